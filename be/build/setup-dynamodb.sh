@@ -7,8 +7,22 @@ aws dynamodb create-table \
   --attribute-definitions \
       AttributeName=PK,AttributeType=S \
       AttributeName=SK,AttributeType=S \
+      AttributeName=user_id,AttributeType=S \
   --key-schema \
       AttributeName=PK,KeyType=HASH \
       AttributeName=SK,KeyType=RANGE \
+  --global-secondary-indexes \
+      "IndexName=user_id-index,KeySchema=[{AttributeName=user_id,KeyType=HASH},{AttributeName=SK,KeyType=RANGE}],Projection={ProjectionType=ALL},ProvisionedThroughput={ReadCapacityUnits=5,WriteCapacityUnits=5}" \
   --provisioned-throughput ReadCapacityUnits=5,WriteCapacityUnits=5 \
   --endpoint-url "$AWS_ENDPOINT"
+
+# tables created before the per-user index existed
+if ! aws dynamodb describe-table --table-name "user_activity_logs" --endpoint-url "$AWS_ENDPOINT" \
+    --query "Table.GlobalSecondaryIndexes[].IndexName" --output text | grep -q "user_id-index"; then
+  aws dynamodb update-table \
+    --table-name "user_activity_logs" \
+    --attribute-definitions AttributeName=user_id,AttributeType=S AttributeName=SK,AttributeType=S \
+    --global-secondary-index-updates \
+        "[{\"Create\":{\"IndexName\":\"user_id-index\",\"KeySchema\":[{\"AttributeName\":\"user_id\",\"KeyType\":\"HASH\"},{\"AttributeName\":\"SK\",\"KeyType\":\"RANGE\"}],\"Projection\":{\"ProjectionType\":\"ALL\"},\"ProvisionedThroughput\":{\"ReadCapacityUnits\":5,\"WriteCapacityUnits\":5}}}]" \
+    --endpoint-url "$AWS_ENDPOINT"
+fi
@@ -95,6 +95,64 @@ func WithInvalidE(err error, code string, ef EvaluateFunc, i18nData ...interface
 	return ie
 }
 
+// IsConflict returns true if err is a Conflict error, when a write was based
+// on a stale version of what it changes.
+func IsConflict(err error) bool {
+	ce, ok := err.(conflict)
+	return ok && ce.Conflict()
+}
+
+// WithConflict annotates err with Conflict behavior.
+func WithConflict(err error, code string) error {
+	if err == nil {
+		return nil
+	}
+	return &withConflict{withCode{cause: err, code: code, stack: callers()}, nil}
+}
+
+// WithConflictE annotates err with Conflict behavior by given custom evaluator.
+func WithConflictE(err error, code string, ef EvaluateFunc) error {
+	if err == nil {
+		return nil
+	}
+
+	ce := &withConflict{withCode{cause: err, stack: callers()}, ef}
+	if ef(err) {
+		ce.code = code
+	}
+
+	return ce
+}
+
+// IsForbidden returns true if err is a Forbidden error, when the caller may
+// not make the change whatever its content.
+func IsForbidden(err error) bool {
+	fe, ok := err.(forbidden)
+	return ok && fe.Forbidden()
+}
+
+// WithForbidden annotates err with Forbidden behavior.
+func WithForbidden(err error, code string) error {
+	if err == nil {
+		return nil
+	}
+	return &withForbidden{withCode{cause: err, code: code, stack: callers()}, nil}
+}
+
+// WithForbiddenE annotates err with Forbidden behavior by given custom evaluator.
+func WithForbiddenE(err error, code string, ef EvaluateFunc) error {
+	if err == nil {
+		return nil
+	}
+
+	fe := &withForbidden{withCode{cause: err, stack: callers()}, ef}
+	if ef(err) {
+		fe.code = code
+	}
+
+	return fe
+}
+
 // IsTemporary returns true if err is temporary, usually used in retry context.
 func IsTemporary(err error) bool {
 	te, ok := err.(temporary)
@@ -170,6 +228,14 @@ type validation interface {
 	Invalid() bool
 }
 
+type conflict interface {
+	Conflict() bool
+}
+
+type forbidden interface {
+	Forbidden() bool
+}
+
 type temporary interface {
 	Temporary() bool
 }
@@ -238,6 +304,24 @@ func (e *withInvalid) Invalid() bool {
 	return e.ef == nil || e.ef(e.cause)
 }
 
+type withConflict struct {
+	withCode
+	ef EvaluateFunc
+}
+
+func (e *withConflict) Conflict() bool {
+	return e.ef == nil || e.ef(e.cause)
+}
+
+type withForbidden struct {
+	withCode
+	ef EvaluateFunc
+}
+
+func (e *withForbidden) Forbidden() bool {
+	return e.ef == nil || e.ef(e.cause)
+}
+
 type withTemporary struct {
 	withCode
 	ef EvaluateFunc
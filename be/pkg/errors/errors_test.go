@@ -20,6 +20,12 @@ func TestAttachErrorCode_OnlyAttach_When_EvaluateFunc_Returns_True(t *testing.T)
 		{"Temporary",
 			WithTemporaryE,
 		},
+		{"Conflict",
+			WithConflictE,
+		},
+		{"Forbidden",
+			WithForbiddenE,
+		},
 	}
 
 	for _, tc := range tcs {
@@ -34,3 +40,25 @@ func TestAttachErrorCode_OnlyAttach_When_EvaluateFunc_Returns_True(t *testing.T)
 	}
 
 }
+
+func TestIsConflict(t *testing.T) {
+	err := WithConflict(New("stale"), "")
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsInvalid(err))
+	assert.Equal(t, "stale", err.Error())
+
+	assert.False(t, IsConflict(WithInvalid(New("bad"), "")))
+	assert.False(t, IsConflict(WithConflictE(New("stale"), "", func(error) bool { return false })))
+	assert.Nil(t, WithConflict(nil, ""))
+}
+
+func TestIsForbidden(t *testing.T) {
+	err := WithForbidden(New("not yours"), "")
+	assert.True(t, IsForbidden(err))
+	assert.False(t, IsInvalid(err))
+	assert.Equal(t, "not yours", err.Error())
+
+	assert.False(t, IsForbidden(WithInvalid(New("bad"), "")))
+	assert.False(t, IsForbidden(WithForbiddenE(New("not yours"), "", func(error) bool { return false })))
+	assert.Nil(t, WithForbidden(nil, ""))
+}
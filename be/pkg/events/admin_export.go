@@ -0,0 +1,7 @@
+package events
+
+// AdminExportRequested asks the worker to build an admin export the API has
+// recorded as pending.
+type AdminExportRequested struct {
+	ExportID string `json:"exportId"`
+}
@@ -0,0 +1,17 @@
+package events
+
+// DataExportRequested asks the worker to build the personal data archive of
+// an export the API has recorded as pending.
+type DataExportRequested struct {
+	ExportID string `json:"exportId"`
+	UserID   string `json:"userId"`
+}
+
+// UserPurgeRequested asks the worker to anonymise the logs of a deleted user.
+type UserPurgeRequested struct {
+	UserID string `json:"userId"`
+}
+
+// AccountDeletedEventType is the user log event of a self-service account
+// deletion. The purge keeps these entries as the record of the erasure.
+const AccountDeletedEventType = "users.deleteAccount"
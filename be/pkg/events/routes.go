@@ -0,0 +1,11 @@
+package events
+
+// Routes the worker dispatches queue messages on, sent in the "route" message
+// attribute.
+const (
+	RouteUserLogs     = "userloggers"
+	RouteDataExports  = "dataexports"
+	RouteUserPurges   = "userpurges"
+	RouteUserImports  = "userimports"
+	RouteAdminExports = "adminexports"
+)
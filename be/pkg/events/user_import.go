@@ -0,0 +1,7 @@
+package events
+
+// UserImportRequested asks the worker to run a bulk user import the API has
+// recorded as pending.
+type UserImportRequested struct {
+	JobID string `json:"jobId"`
+}
@@ -7,4 +7,12 @@ type UserLogsEvent struct {
 	EventType string    `json:"eventType"`
 	EventTime time.Time `json:"eventTime"`
 	Details   string    `json:"details"`
+	// Actor is set when someone else acted on the user, see model.Actor.
+	Actor string `json:"actor,omitempty"`
+	// OrgID is the organization the event happened in, which decides the
+	// partition it is logged to. See model.LogPartition.
+	OrgID string `json:"orgId,omitempty"`
+	// Impersonator is the admin who acted as the user, see package
+	// impersonation.
+	Impersonator string `json:"impersonator,omitempty"`
 }
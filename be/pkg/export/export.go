@@ -0,0 +1,90 @@
+// Package export writes admin exports as CSV or NDJSON, one record at a time,
+// so callers can stream them without holding the whole export in memory.
+package export
+
+import (
+	"be/pkg/errors"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var Formats = []string{FormatCSV, FormatNDJSON}
+
+func IsValidFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// ContentType is the media type of an export in the given format.
+func ContentType(format string) string {
+	if format == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// Record is a row of an export. JSON tags name the NDJSON fields; Header and
+// Fields give the CSV columns in the same order.
+type Record interface {
+	Header() []string
+	Fields() []string
+}
+
+// Writer encodes records of one type. A CSV export starts with the header,
+// which is written even when there are no records.
+type Writer[T Record] struct {
+	csv         *csv.Writer
+	json        *json.Encoder
+	wroteHeader bool
+}
+
+func NewWriter[T Record](w io.Writer, format string) (*Writer[T], error) {
+	switch format {
+	case FormatCSV:
+		return &Writer[T]{csv: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		return &Writer[T]{json: json.NewEncoder(w)}, nil
+	default:
+		return nil, errors.Errorf("Unsupported export format %q", format)
+	}
+}
+
+func (w *Writer[T]) Write(r T) error {
+	if w.json != nil {
+		return errors.WithStack(w.json.Encode(r))
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return errors.WithStack(w.csv.Write(r.Fields()))
+}
+
+// Flush writes out buffered records.
+func (w *Writer[T]) Flush() error {
+	if w.json != nil {
+		return nil
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return errors.WithStack(w.csv.Error())
+}
+
+func (w *Writer[T]) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+
+	var zero T
+	return errors.WithStack(w.csv.Write(zero.Header()))
+}
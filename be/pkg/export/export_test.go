@@ -0,0 +1,70 @@
+package export
+
+import (
+	"be/pkg/model"
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUser = &model.User{
+	ID:        "u1",
+	Email:     "a@example.com",
+	Password:  "hash",
+	Name:      sql.NullString{String: "Ann, \"A\"", Valid: true},
+	Role:      model.RoleUser,
+	Status:    model.StatusActive,
+	CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestWriterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter[UserRecord](&buf, FormatCSV)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(NewUserRecord(testUser)))
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t,
+		"id,email,name,role,status,email_verified_at,suspended_at,deleted_at,created_at\n"+
+			"u1,a@example.com,\"Ann, \"\"A\"\"\",user,active,,,,2024-01-02T03:04:05Z\n",
+		buf.String())
+	assert.NotContains(t, buf.String(), "hash")
+}
+
+func TestWriterCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter[UserLogRecord](&buf, FormatCSV)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "id,user_id,event_type,details,actor,impersonator,created_at\n", buf.String())
+}
+
+func TestWriterNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter[UserLogRecord](&buf, FormatNDJSON)
+	require.NoError(t, err)
+
+	for _, id := range []string{"l1", "l2"} {
+		require.NoError(t, w.Write(NewUserLogRecord(&model.UserLogs{ID: id, UserID: "u1", EventType: "users.signIn"})))
+	}
+	require.NoError(t, w.Flush())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.JSONEq(t,
+		`{"id":"l1","user_id":"u1","event_type":"users.signIn","details":"","actor":"","impersonator":"","created_at":"0001-01-01T00:00:00Z"}`,
+		string(lines[0]))
+}
+
+func TestNewWriterInvalidFormat(t *testing.T) {
+	_, err := NewWriter[UserRecord](&bytes.Buffer{}, "xml")
+	assert.Error(t, err)
+	assert.False(t, IsValidFormat("xml"))
+	assert.True(t, IsValidFormat(FormatNDJSON))
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"be/pkg/model"
+	"database/sql"
+	"time"
+)
+
+// UserRecord is a user as exported. The password hash is left out.
+type UserRecord struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	Role            string     `json:"role"`
+	Status          string     `json:"status"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	SuspendedAt     *time.Time `json:"suspended_at"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func NewUserRecord(u *model.User) UserRecord {
+	return UserRecord{
+		ID:              u.ID,
+		Email:           u.Email,
+		Name:            u.Name.String,
+		Role:            u.Role,
+		Status:          u.Status,
+		EmailVerifiedAt: nullTime(u.EmailVerifiedAt),
+		SuspendedAt:     nullTime(u.SuspendedAt),
+		DeletedAt:       nullTime(u.DeletedAt),
+		CreatedAt:       u.CreatedAt,
+	}
+}
+
+func (UserRecord) Header() []string {
+	return []string{"id", "email", "name", "role", "status", "email_verified_at", "suspended_at", "deleted_at", "created_at"}
+}
+
+func (r UserRecord) Fields() []string {
+	return []string{
+		r.ID, r.Email, r.Name, r.Role, r.Status,
+		formatTime(r.EmailVerifiedAt), formatTime(r.SuspendedAt), formatTime(r.DeletedAt), formatTime(&r.CreatedAt),
+	}
+}
+
+type UserLogRecord struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	EventType    string    `json:"event_type"`
+	Details      string    `json:"details"`
+	Actor        string    `json:"actor"`
+	Impersonator string    `json:"impersonator"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func NewUserLogRecord(l *model.UserLogs) UserLogRecord {
+	return UserLogRecord{
+		ID:           l.ID,
+		UserID:       l.UserID,
+		EventType:    l.EventType,
+		Details:      l.Details,
+		Actor:        l.Actor,
+		Impersonator: l.Impersonator,
+		CreatedAt:    l.CreatedAt,
+	}
+}
+
+func (UserLogRecord) Header() []string {
+	return []string{"id", "user_id", "event_type", "details", "actor", "impersonator", "created_at"}
+}
+
+func (r UserLogRecord) Fields() []string {
+	return []string{r.ID, r.UserID, r.EventType, r.Details, r.Actor, r.Impersonator, formatTime(&r.CreatedAt)}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// formatTime writes times as RFC 3339 in CSV, and nil as an empty cell.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
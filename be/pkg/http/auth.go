@@ -1,6 +1,11 @@
 package http
 
 import (
+	"be/pkg/errors"
+	"be/pkg/impersonation"
+	"be/pkg/jwtkeys"
+	"be/pkg/model"
+	"be/pkg/tenant"
 	"context"
 	"net/http"
 	"strings"
@@ -10,12 +15,94 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey    contextKey = "user_id"
+	RoleKey      contextKey = "role"
+	SessionIDKey contextKey = "session_id"
+
+	EmailVerifiedKey contextKey = "email_verified"
+
+	APIKeyIDKey contextKey = "api_key_id"
+	ScopesKey   contextKey = "scopes"
+)
+
+// SessionChecker tells whether the session a token was issued for has been revoked.
+type SessionChecker interface {
+	IsRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// UserChecker tells whether a user may still use the tokens issued to them in
+// the organization of the context.
+type UserChecker interface {
+	IsActive(ctx context.Context, userID string) (bool, error)
+}
+
+// APIKeyAuthenticator resolves a raw API key to its id, organization and
+// scopes. It returns a not found or invalid error for unknown, revoked or
+// expired keys.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (id, orgID string, scopes []string, err error)
+}
+
+type authOptions struct {
+	sessions SessionChecker
+	users    UserChecker
+	apiKeys  APIKeyAuthenticator
+}
+
+type AuthOption func(*authOptions)
+
+// WithSessionCheck rejects tokens whose "sid" claim is missing or refers to a
+// revoked session.
+func WithSessionCheck(sessions SessionChecker) AuthOption {
+	return func(o *authOptions) {
+		o.sessions = sessions
+	}
+}
+
+// WithUserCheck rejects tokens of users who are no longer active, e.g.
+// suspended or deleted ones.
+func WithUserCheck(users UserChecker) AuthOption {
+	return func(o *authOptions) {
+		o.users = users
+	}
+}
+
+// WithAPIKeys also accepts API keys sent in the X-API-Key header or as
+// "Authorization: ApiKey <key>". Requests made with a key carry its id and
+// scopes in the context instead of a user.
+func WithAPIKeys(apiKeys APIKeyAuthenticator) AuthOption {
+	return func(o *authOptions) {
+		o.apiKeys = apiKeys
+	}
+}
+
+// AuthMiddleware authenticates the request and makes its context act in the
+// organization of the token ("org" claim) or API key, see package tenant.
+//
+// An impersonation token names the admin acting as its user in its "act"
+// claim, {"sub": <admin id>}. The admin is put in the context, see package
+// impersonation, and must be active too when users are checked.
+func AuthMiddleware(keys *jwtkeys.KeySet, opts ...AuthOption) func(next http.Handler) http.Handler {
+	o := authOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-func AuthMiddleware(jwtKey string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			auth := r.Header.Get("Authorization")
+			if o.apiKeys != nil {
+				key := r.Header.Get("X-API-Key")
+				if key == "" && strings.HasPrefix(auth, "ApiKey ") {
+					key = strings.TrimPrefix(auth, "ApiKey ")
+				}
+				if key != "" {
+					serveWithAPIKey(w, r, next, o.apiKeys, key)
+					return
+				}
+			}
+
 			if !strings.HasPrefix(auth, "Bearer ") {
 				JSON(w, http.StatusUnauthorized, map[string]string{"error": "missing token"})
 				return
@@ -23,26 +110,96 @@ func AuthMiddleware(jwtKey string) func(next http.Handler) http.Handler {
 			tokenStr := strings.TrimPrefix(auth, "Bearer ")
 
 			claims := jwt.MapClaims{}
-			_, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-				return []byte(jwtKey), nil
-			})
+			_, err := keys.Parse(tokenStr, claims)
 			if err != nil {
 				JSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
 				return
 			}
 
 			uid, ok := claims["user_id"].(string)
-			if !ok {
+			orgID, _ := claims["org"].(string)
+			if !ok || orgID == "" {
 				JSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token claims"})
 				return
 			}
+			role, _ := claims["role"].(string)
+			sid, _ := claims["sid"].(string)
+			emailVerified, _ := claims["email_verified"].(bool)
+
+			impersonator := ""
+			if act, ok := claims["act"]; ok {
+				actClaims, _ := act.(map[string]any)
+				impersonator, _ = actClaims["sub"].(string)
+				if impersonator == "" {
+					JSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token claims"})
+					return
+				}
+			}
+
+			if o.sessions != nil {
+				if sid == "" {
+					JSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token claims"})
+					return
+				}
 
-			ctx := context.WithValue(r.Context(), UserIDKey, uid)
+				revoked, err := o.sessions.IsRevoked(r.Context(), sid)
+				if err != nil {
+					JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+					return
+				}
+				if revoked {
+					JSON(w, http.StatusUnauthorized, map[string]string{"error": "token revoked"})
+					return
+				}
+			}
+
+			if o.users != nil {
+				for _, id := range []string{uid, impersonator} {
+					if id == "" {
+						continue
+					}
+					active, err := o.users.IsActive(tenant.WithOrg(r.Context(), orgID), id)
+					if err != nil {
+						JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+						return
+					}
+					if !active {
+						JSON(w, http.StatusUnauthorized, map[string]string{"error": "user inactive"})
+						return
+					}
+				}
+			}
+
+			ctx := tenant.WithOrg(r.Context(), orgID)
+			ctx = context.WithValue(ctx, UserIDKey, uid)
+			ctx = context.WithValue(ctx, RoleKey, role)
+			ctx = context.WithValue(ctx, SessionIDKey, sid)
+			ctx = context.WithValue(ctx, EmailVerifiedKey, emailVerified)
+			if impersonator != "" {
+				ctx = impersonation.WithImpersonator(ctx, impersonator)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+func serveWithAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKeys APIKeyAuthenticator, key string) {
+	id, orgID, scopes, err := apiKeys.AuthenticateAPIKey(r.Context(), key)
+	if errors.IsNotFound(err) || errors.IsInvalid(err) {
+		JSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid api key"})
+		return
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx := tenant.WithOrg(r.Context(), orgID)
+	ctx = context.WithValue(ctx, APIKeyIDKey, id)
+	ctx = context.WithValue(ctx, ScopesKey, scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 func GetUserID(w http.ResponseWriter, r *http.Request) string {
 	id, ok := r.Context().Value(UserIDKey).(string)
 	if !ok {
@@ -51,3 +208,17 @@ func GetUserID(w http.ResponseWriter, r *http.Request) string {
 	}
 	return id
 }
+
+// GetActor returns who is making the request, a user or an API key. Like
+// GetUserID it answers 401 and returns false when there is neither.
+func GetActor(w http.ResponseWriter, r *http.Request) (model.Actor, bool) {
+	if id, ok := r.Context().Value(APIKeyIDKey).(string); ok {
+		return model.APIKeyActor(id), true
+	}
+
+	id := GetUserID(w, r)
+	if id == "" {
+		return model.Actor{}, false
+	}
+	return model.UserActor(id), true
+}
@@ -1,6 +1,11 @@
 package http
 
 import (
+	"be/pkg/errors"
+	"be/pkg/impersonation"
+	"be/pkg/jwtkeys"
+	"be/pkg/tenant"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,6 +15,14 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func hmacKeySet(t *testing.T, secret string) *jwtkeys.KeySet {
+	ks, err := jwtkeys.NewKeySet("", jwtkeys.NewHMACKey("", []byte(secret)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
 func TestAuthMiddleware(t *testing.T) {
 	jwtKey := "testsecret"
 	userID := "12345"
@@ -17,18 +30,24 @@ func TestAuthMiddleware(t *testing.T) {
 	createToken := func(uid string) string {
 		claims := jwt.MapClaims{
 			"user_id": uid,
+			"org":     "org-1",
 			"exp":     time.Now().Add(time.Hour).Unix(),
 		}
 		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 		s, _ := token.SignedString([]byte(jwtKey))
 		return s
 	}
+	noOrgToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(jwtKey))
 
 	called := false
 	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		uid := r.Context().Value(UserIDKey).(string)
 		assert.Equal(t, userID, uid)
+		assert.Equal(t, "org-1", tenant.OrgID(r.Context()))
 		w.WriteHeader(http.StatusOK)
 	})
 
@@ -56,6 +75,12 @@ func TestAuthMiddleware(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectNext:     true,
 		},
+		{
+			name:           "token without organization",
+			authHeader:     "Bearer " + noOrgToken,
+			expectedStatus: http.StatusUnauthorized,
+			expectNext:     false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -67,7 +92,7 @@ func TestAuthMiddleware(t *testing.T) {
 			}
 			w := httptest.NewRecorder()
 
-			handler := AuthMiddleware(jwtKey)(next)
+			handler := AuthMiddleware(hmacKeySet(t, jwtKey))(next)
 			handler.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
@@ -75,3 +100,275 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+type sessionCheckerMock map[string]bool
+
+func (m sessionCheckerMock) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	revoked, ok := m[sessionID]
+	return revoked || !ok, nil
+}
+
+func TestAuthMiddlewareSessionCheck(t *testing.T) {
+	jwtKey := "testsecret"
+	sessions := sessionCheckerMock{"active": false, "revoked": true}
+
+	createToken := func(sid string) string {
+		claims := jwt.MapClaims{
+			"user_id": "12345",
+			"org":     "org-1",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+		}
+		if sid != "" {
+			claims["sid"] = sid
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		s, _ := token.SignedString([]byte(jwtKey))
+		return s
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "active", r.Context().Value(SessionIDKey))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		sid            string
+		expectedStatus int
+	}{
+		{
+			name:           "missing session",
+			sid:            "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown session",
+			sid:            "unknown",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "revoked session",
+			sid:            "revoked",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "active session",
+			sid:            "active",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+createToken(tt.sid))
+			w := httptest.NewRecorder()
+
+			handler := AuthMiddleware(hmacKeySet(t, jwtKey), WithSessionCheck(sessions))(next)
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+type userCheckerMock map[string]bool
+
+func (m userCheckerMock) IsActive(ctx context.Context, userID string) (bool, error) {
+	return m[userID], nil
+}
+
+func TestAuthMiddlewareUserCheck(t *testing.T) {
+	jwtKey := "testsecret"
+	users := userCheckerMock{"active": true, "suspended": false}
+
+	createToken := func(uid string) string {
+		claims := jwt.MapClaims{
+			"user_id": uid,
+			"org":     "org-1",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		s, _ := token.SignedString([]byte(jwtKey))
+		return s
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		userID         string
+		expectedStatus int
+	}{
+		{
+			name:           "unknown user",
+			userID:         "unknown",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "suspended user",
+			userID:         "suspended",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "active user",
+			userID:         "active",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+createToken(tt.userID))
+			w := httptest.NewRecorder()
+
+			handler := AuthMiddleware(hmacKeySet(t, jwtKey), WithUserCheck(users))(next)
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestAuthMiddlewareImpersonation(t *testing.T) {
+	jwtKey := "testsecret"
+	users := userCheckerMock{"user": true, "admin": true, "suspended": false}
+
+	createToken := func(act any) string {
+		claims := jwt.MapClaims{
+			"user_id": "user",
+			"org":     "org-1",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+		}
+		if act != nil {
+			claims["act"] = act
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		s, _ := token.SignedString([]byte(jwtKey))
+		return s
+	}
+
+	impersonator := ""
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		impersonator = impersonation.Impersonator(r.Context())
+		assert.Equal(t, "user", r.Context().Value(UserIDKey))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name                 string
+		act                  any
+		expectedStatus       int
+		expectedImpersonator string
+	}{
+		{
+			name:           "own token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:                 "impersonated by an active admin",
+			act:                  map[string]any{"sub": "admin"},
+			expectedStatus:       http.StatusOK,
+			expectedImpersonator: "admin",
+		},
+		{
+			name:           "impersonated by a suspended admin",
+			act:            map[string]any{"sub": "suspended"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "actor without subject",
+			act:            "admin",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impersonator = ""
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+createToken(tt.act))
+			w := httptest.NewRecorder()
+
+			handler := AuthMiddleware(hmacKeySet(t, jwtKey), WithUserCheck(users))(next)
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			assert.Equal(t, tt.expectedImpersonator, impersonator)
+		})
+	}
+}
+
+type apiKeyAuthenticatorMock map[string][]string
+
+func (m apiKeyAuthenticatorMock) AuthenticateAPIKey(ctx context.Context, key string) (string, string, []string, error) {
+	scopes, ok := m[key]
+	if !ok {
+		return "", "", nil, errors.WithNotFound(errors.New("API key not found"), "")
+	}
+	return "key-" + key, "org-" + key, scopes, nil
+}
+
+func TestAuthMiddlewareAPIKeys(t *testing.T) {
+	apiKeys := apiKeyAuthenticatorMock{"valid": {"users:read"}}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key-valid", r.Context().Value(APIKeyIDKey))
+		assert.Equal(t, []string{"users:read"}, r.Context().Value(ScopesKey))
+		assert.Equal(t, "org-valid", tenant.OrgID(r.Context()))
+		assert.Nil(t, r.Context().Value(UserIDKey))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{
+			name:           "x-api-key header",
+			header:         "X-API-Key",
+			value:          "valid",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "authorization header",
+			header:         "Authorization",
+			value:          "ApiKey valid",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown key",
+			header:         "X-API-Key",
+			value:          "unknown",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+
+			handler := AuthMiddleware(hmacKeySet(t, "testsecret"), WithAPIKeys(apiKeys))(next)
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+
+	t.Run("api keys not enabled", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-API-Key", "valid")
+		w := httptest.NewRecorder()
+
+		AuthMiddleware(hmacKeySet(t, "testsecret"))(next).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"be/pkg/impersonation"
+	"be/pkg/tenant"
+	"net/http"
+	"slices"
+)
+
+// RequireRole only lets requests through when the role put in the context by
+// AuthMiddleware is one of the given roles. It must be used after AuthMiddleware.
+func RequireRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(roles, GetRole(r)) {
+				JSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAccess lets users through by role like RequireRole, and requests made
+// with an API key when the key has the given scope. It must be used after
+// AuthMiddleware.
+func RequireAccess(scope string, roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed := slices.Contains(roles, GetRole(r))
+			if scopes, ok := r.Context().Value(ScopesKey).([]string); ok {
+				allowed = slices.Contains(scopes, scope)
+			}
+
+			if !allowed {
+				JSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireOrg only lets requests through when they act in the given
+// organization, for the endpoints that manage the whole deployment rather than
+// one tenant. It must be used after AuthMiddleware.
+func RequireOrg(orgID string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tenant.OrgID(r.Context()) != orgID {
+				JSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyImpersonation refuses the request when an admin impersonates its user,
+// for the actions too destructive to take on someone else's behalf. It must be
+// used after AuthMiddleware.
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if impersonation.Impersonator(r.Context()) != "" {
+			JSON(w, http.StatusForbidden, map[string]string{"error": "not allowed while impersonating"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func GetRole(r *http.Request) string {
+	role, _ := r.Context().Value(RoleKey).(string)
+	return role
+}
+
+// RequireVerifiedEmail only lets requests through when the token says the
+// user's email is verified. API keys have no email and are let through. It
+// must be used after AuthMiddleware.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(APIKeyIDKey).(string); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if verified, _ := r.Context().Value(EmailVerifiedKey).(bool); !verified {
+			JSON(w, http.StatusForbidden, map[string]string{"error": "email not verified"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
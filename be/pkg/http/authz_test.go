@@ -0,0 +1,178 @@
+package http
+
+import (
+	"be/pkg/impersonation"
+	"be/pkg/tenant"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		role           string
+		expectedStatus int
+	}{
+		{
+			name:           "missing role",
+			role:           "",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "role not allowed",
+			role:           "user",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "role allowed",
+			role:           "auditor",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.role != "" {
+				req = req.WithContext(context.WithValue(req.Context(), RoleKey, tt.role))
+			}
+			w := httptest.NewRecorder()
+
+			handler := RequireRole("admin", "auditor")(next)
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestRequireOrg(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RequireOrg("org-1")(next)
+
+	for org, status := range map[string]int{"": http.StatusForbidden, "org-2": http.StatusForbidden, "org-1": http.StatusOK} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(tenant.WithOrg(req.Context(), org))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Result().StatusCode, "org %q", org)
+	}
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := DenyImpersonation(next)
+
+	for impersonator, status := range map[string]int{"": http.StatusOK, "admin-1": http.StatusForbidden} {
+		req := httptest.NewRequest("POST", "/", nil)
+		req = req.WithContext(impersonation.WithImpersonator(req.Context(), impersonator))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Result().StatusCode, "impersonator %q", impersonator)
+	}
+}
+
+func TestRequireAccess(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	withKey := func(scopes ...string) context.Context {
+		ctx := context.WithValue(context.Background(), APIKeyIDKey, "key")
+		return context.WithValue(ctx, ScopesKey, scopes)
+	}
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		expectedStatus int
+	}{
+		{
+			name:           "role allowed",
+			ctx:            context.WithValue(context.Background(), RoleKey, "admin"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "role not allowed",
+			ctx:            context.WithValue(context.Background(), RoleKey, "user"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "key with scope",
+			ctx:            withKey("userlogs:read", "users:read"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "key without scope",
+			ctx:            withKey("userlogs:read"),
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil).WithContext(tt.ctx)
+			w := httptest.NewRecorder()
+
+			RequireAccess("users:read", "admin")(next).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		expectedStatus int
+	}{
+		{
+			name:           "missing claim",
+			ctx:            context.Background(),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unverified",
+			ctx:            context.WithValue(context.Background(), EmailVerifiedKey, false),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "verified",
+			ctx:            context.WithValue(context.Background(), EmailVerifiedKey, true),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "api key",
+			ctx:            context.WithValue(context.Background(), APIKeyIDKey, "key"),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil).WithContext(tt.ctx)
+			w := httptest.NewRecorder()
+
+			RequireVerifiedEmail(next).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
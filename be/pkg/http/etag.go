@@ -0,0 +1,39 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag formats the version of a resource as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// AnyVersion is the version IfMatch returns for `If-Match: *`, which matches
+// whatever version the resource is at.
+const AnyVersion = 0
+
+// IfMatch reads the version a client based its write on from the If-Match
+// header, as sent by ETag. ok is false when the header is missing.
+func IfMatch(r *http.Request) (version int, ok bool, err error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" {
+		return 0, false, nil
+	}
+	if h == "*" {
+		return AnyVersion, true, nil
+	}
+
+	unquoted, err := strconv.Unquote(h)
+	if err != nil || !strings.HasPrefix(h, `"`) {
+		return 0, true, errors.New("If-Match must be a single strong ETag")
+	}
+	version, err = strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, true, errors.New("If-Match does not match any version")
+	}
+	return version, true, nil
+}
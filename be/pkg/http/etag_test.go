@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int
+		ok      bool
+		err     bool
+	}{
+		{name: "missing"},
+		{name: "etag", header: ETag(3), version: 3, ok: true},
+		{name: "weak", header: `W/"3"`, ok: true, err: true},
+		{name: "wildcard", header: "*", version: AnyVersion, ok: true},
+		{name: "list", header: `"3", "4"`, ok: true, err: true},
+		{name: "not a version", header: `"abc"`, ok: true, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			version, ok, err := IfMatch(r)
+			assert.Equal(t, tt.version, version)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.err, err != nil)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package http
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the IP address of the client from r.RemoteAddr. Forwarded
+// headers are not trusted here; deployments behind a proxy should rewrite
+// RemoteAddr with a middleware that knows which proxies to trust.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		expected   string
+	}{
+		{
+			name:       "ipv4 with port",
+			remoteAddr: "10.0.0.1:51234",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "ipv6 with port",
+			remoteAddr: "[::1]:51234",
+			expected:   "::1",
+		},
+		{
+			name:       "without port",
+			remoteAddr: "10.0.0.1",
+			expected:   "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+			assert.Equal(t, tt.expected, ClientIP(req))
+		})
+	}
+}
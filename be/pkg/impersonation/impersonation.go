@@ -0,0 +1,20 @@
+// Package impersonation carries who is impersonating the user a request is
+// made as, from the auth middleware down to the user logs.
+package impersonation
+
+import "context"
+
+type contextKey struct{}
+
+// WithImpersonator returns ctx acting as its user on behalf of the given
+// admin. An empty id means nobody is impersonating.
+func WithImpersonator(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// Impersonator returns the id of the admin impersonating the user of ctx,
+// empty when the user acts for themselves.
+func Impersonator(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
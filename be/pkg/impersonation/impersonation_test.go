@@ -0,0 +1,16 @@
+package impersonation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonator(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, Impersonator(ctx))
+
+	ctx = WithImpersonator(ctx, "admin-1")
+	assert.Equal(t, "admin-1", Impersonator(ctx))
+}
@@ -0,0 +1,153 @@
+// Package jwtkeys holds the keys access tokens are signed and verified with.
+// Several keys can be active at once, told apart by the "kid" header, so a new
+// signing key can be rolled out while tokens signed by the previous one keep
+// verifying until it is retired.
+package jwtkeys
+
+import (
+	"be/pkg/errors"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSABits = 2048
+
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   any
+	verifyKey any
+}
+
+// NewHMACKey returns an HS256 key. It is never published in the JWKS, since
+// anyone able to verify with it could also sign. An empty id matches tokens
+// issued without a "kid" header.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+// ParsePEM reads an RSA or Ed25519 key. A private key (PKCS#8, or PKCS#1 for
+// RSA) can sign and verify; a public key (PKIX) only verifies, which is how a
+// key is kept around after rotation until the tokens it signed expire.
+func ParsePEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("Key %q: no PEM block found", id)
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("Key %q: unsupported PEM block %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Key %q", id)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, errors.Errorf("Key %q: RSA keys must be at least %d bits", id, minRSABits)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, errors.Errorf("Key %q: RSA keys must be at least %d bits", id, minRSABits)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	default:
+		return nil, errors.Errorf("Key %q: unsupported key type %T", id, parsed)
+	}
+}
+
+func LoadPEMFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParsePEM(id, data)
+}
+
+// CanSign reports whether the private part of the key is known.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	methods []string
+}
+
+// NewKeySet returns a set signing with the key identified by signingID and
+// verifying with all of keys.
+func NewKeySet(signingID string, keys ...*Key) (*KeySet, error) {
+	s := &KeySet{keys: map[string]*Key{}}
+	for _, k := range keys {
+		if _, ok := s.keys[k.ID]; ok {
+			return nil, errors.Errorf("Duplicate key %q", k.ID)
+		}
+		s.keys[k.ID] = k
+		if !slices.Contains(s.methods, k.Method.Alg()) {
+			s.methods = append(s.methods, k.Method.Alg())
+		}
+	}
+
+	s.signing = s.keys[signingID]
+	if s.signing == nil {
+		return nil, errors.Errorf("Signing key %q not found", signingID)
+	}
+	if !s.signing.CanSign() {
+		return nil, errors.Errorf("Signing key %q has no private key", signingID)
+	}
+	return s, nil
+}
+
+// Sign returns the token for claims signed with the signing key.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.ID != "" {
+		token.Header["kid"] = s.signing.ID
+	}
+
+	ss, err := token.SignedString(s.signing.signKey)
+	return ss, errors.WithStack(err)
+}
+
+// Parse verifies tokenStr with the key named by its "kid" header and decodes
+// its claims. The algorithm must be the one of that key.
+func (s *KeySet) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, s.keyfunc, jwt.WithValidMethods(s.methods))
+}
+
+func (s *KeySet) keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, errors.Errorf("Unknown key %q", kid)
+	}
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, errors.Errorf("Unexpected signing method %q for key %q", t.Method.Alg(), kid)
+	}
+	return k.verifyKey, nil
+}
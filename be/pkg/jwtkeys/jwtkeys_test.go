@@ -0,0 +1,197 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rsaPEM(t *testing.T, bits int) (private, public []byte) {
+	k, err := rsa.GenerateKey(rand.Reader, bits)
+	require.NoError(t, err)
+	return encodePEM(t, k, &k.PublicKey)
+}
+
+func ed25519PEM(t *testing.T) (private, public []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return encodePEM(t, priv, pub)
+}
+
+func encodePEM(t *testing.T, priv, pub any) ([]byte, []byte) {
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": "12345", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestSignAndParse(t *testing.T) {
+	rsaPriv, _ := rsaPEM(t, 2048)
+	edPriv, _ := ed25519PEM(t)
+
+	rsaKey, err := ParsePEM("rsa", rsaPriv)
+	require.NoError(t, err)
+	edKey, err := ParsePEM("ed", edPriv)
+	require.NoError(t, err)
+	hmacKey := NewHMACKey("", []byte("secret"))
+
+	tests := []struct {
+		name      string
+		signingID string
+		alg       string
+	}{
+		{name: "RS256", signingID: "rsa", alg: "RS256"},
+		{name: "EdDSA", signingID: "ed", alg: "EdDSA"},
+		{name: "HS256 without kid", signingID: "", alg: "HS256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := NewKeySet(tt.signingID, rsaKey, edKey, hmacKey)
+			require.NoError(t, err)
+
+			ss, err := ks.Sign(claims())
+			require.NoError(t, err)
+
+			parsed := jwt.MapClaims{}
+			token, err := ks.Parse(ss, parsed)
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, token.Method.Alg())
+			assert.Equal(t, "12345", parsed["user_id"])
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldPriv, oldPub := ed25519PEM(t)
+	newPriv, _ := ed25519PEM(t)
+
+	oldKey, err := ParsePEM("old", oldPriv)
+	require.NoError(t, err)
+	newKey, err := ParsePEM("new", newPriv)
+	require.NoError(t, err)
+
+	before, err := NewKeySet("old", oldKey)
+	require.NoError(t, err)
+	oldToken, err := before.Sign(claims())
+	require.NoError(t, err)
+
+	// the old key is retired to its public part and keeps verifying
+	retired, err := ParsePEM("old", oldPub)
+	require.NoError(t, err)
+	assert.False(t, retired.CanSign())
+
+	after, err := NewKeySet("new", newKey, retired)
+	require.NoError(t, err)
+	_, err = after.Parse(oldToken, jwt.MapClaims{})
+	assert.NoError(t, err)
+
+	newToken, err := after.Sign(claims())
+	require.NoError(t, err)
+	_, err = after.Parse(newToken, jwt.MapClaims{})
+	assert.NoError(t, err)
+
+	// once removed, tokens it signed are rejected
+	removed, err := NewKeySet("new", newKey)
+	require.NoError(t, err)
+	_, err = removed.Parse(oldToken, jwt.MapClaims{})
+	assert.Error(t, err)
+}
+
+func TestParseRejectsAlgorithmMismatch(t *testing.T) {
+	rsaPriv, rsaPub := rsaPEM(t, 2048)
+	rsaKey, err := ParsePEM("rsa", rsaPriv)
+	require.NoError(t, err)
+	ks, err := NewKeySet("rsa", rsaKey)
+	require.NoError(t, err)
+
+	// an HS256 token using the public key as secret must not verify
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	token.Header["kid"] = "rsa"
+	ss, err := token.SignedString(rsaPub)
+	require.NoError(t, err)
+
+	_, err = ks.Parse(ss, jwt.MapClaims{})
+	assert.Error(t, err)
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	_, pub := ed25519PEM(t)
+	verifyOnly, err := ParsePEM("ed", pub)
+	require.NoError(t, err)
+
+	_, err = NewKeySet("missing", verifyOnly)
+	assert.Error(t, err)
+
+	_, err = NewKeySet("ed", verifyOnly)
+	assert.Error(t, err)
+
+	_, err = NewKeySet("", NewHMACKey("", []byte("a")), NewHMACKey("", []byte("b")))
+	assert.Error(t, err)
+}
+
+func TestParsePEMErrors(t *testing.T) {
+	weak, _ := rsaPEM(t, 1024)
+	_, err := ParsePEM("weak", weak)
+	assert.Error(t, err)
+
+	_, err = ParsePEM("garbage", []byte("not a pem"))
+	assert.Error(t, err)
+}
+
+func TestLoadPEMFile(t *testing.T) {
+	priv, _ := ed25519PEM(t)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, priv, 0o600))
+
+	k, err := LoadPEMFile("ed", path)
+	require.NoError(t, err)
+	assert.True(t, k.CanSign())
+	assert.Equal(t, "EdDSA", k.Method.Alg())
+
+	_, err = LoadPEMFile("ed", filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPriv, _ := rsaPEM(t, 2048)
+	_, edPub := ed25519PEM(t)
+
+	rsaKey, err := ParsePEM("rsa", rsaPriv)
+	require.NoError(t, err)
+	edKey, err := ParsePEM("ed", edPub)
+	require.NoError(t, err)
+
+	ks, err := NewKeySet("rsa", rsaKey, edKey, NewHMACKey("", []byte("secret")))
+	require.NoError(t, err)
+
+	jwks := ks.JWKS()
+	require.Len(t, jwks.Keys, 2)
+
+	assert.Equal(t, "ed", jwks.Keys[0].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+	assert.NotEmpty(t, jwks.Keys[0].X)
+
+	assert.Equal(t, "rsa", jwks.Keys[1].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "RS256", jwks.Keys[1].Alg)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	assert.NotEmpty(t, jwks.Keys[1].N)
+}
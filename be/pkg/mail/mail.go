@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"be/pkg/errors"
+	"context"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	// Driver is one of smtp, file or memory. Defaults to memory.
+	Driver string
+	From   string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Dir is where the file driver writes messages.
+	Dir string
+}
+
+// New returns the Mailer selected by cfg.Driver.
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case DriverMemory, "":
+		return NewMemoryMailer(), nil
+	default:
+		return nil, errors.Errorf("Unsupported mail driver: %s", cfg.Driver)
+	}
+}
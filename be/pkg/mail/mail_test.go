@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    string
+		expectErr bool
+	}{
+		{"default", "", false},
+		{"memory", DriverMemory, false},
+		{"smtp", DriverSMTP, false},
+		{"file", DriverFile, false},
+		{"unknown", "pigeon", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(Config{Driver: tt.driver, Dir: t.TempDir()})
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, m)
+		})
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "noreply@example.com")
+	assert.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "line1\nline2"})
+	assert.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), "-user@example.com.eml"))
+
+	bts, err := os.ReadFile(dir + "/" + entries[0].Name())
+	assert.NoError(t, err)
+	assert.Contains(t, string(bts), "To: user@example.com\r\n")
+	assert.Contains(t, string(bts), "Subject: Hello\r\n")
+	assert.Contains(t, string(bts), "\r\n\r\nline1\r\nline2")
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+	msg := Message{To: "user@example.com", Subject: "Hello", Body: "body"}
+
+	err := m.Send(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, []Message{msg}, m.Messages())
+}
@@ -0,0 +1,230 @@
+// Package migrate reads versioned SQL migrations and works out which of them
+// to apply or revert, given the ones a database has applied already. Running
+// them is left to the service owning the database.
+//
+// A migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, the version being a number such as the
+// 20060102150405 timestamp Create uses. The down file is optional, a
+// migration without one cannot be reverted.
+package migrate
+
+import (
+	"be/pkg/errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration states, see Status.
+const (
+	StateApplied = "applied"
+	StatePending = "pending"
+	// StateChanged is an applied migration whose up file was edited since.
+	StateChanged = "changed"
+	// StateMissing is an applied migration that has no file anymore.
+	StateMissing = "missing"
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifies the up file the migration was applied with.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// Applied is a migration as recorded by the database.
+type Applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+var (
+	fileRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	nameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// Load reads the migrations at the root of fsys, sorted by version. Other
+// files are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	hasUp := map[int64]bool{}
+	for _, e := range entries {
+		match := fileRegex.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+			hasUp[version] = true
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !hasUp[m.Version] {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Statuses lists the migrations with their state, followed by the applied
+// ones that are missing.
+func Statuses(migrations []Migration, applied []Applied) []Status {
+	byVersion := appliedByVersion(applied)
+	known := map[int64]bool{}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		s := Status{Version: m.Version, Name: m.Name, State: StatePending}
+		if a, ok := byVersion[m.Version]; ok {
+			s.State = StateApplied
+			if a.Checksum != m.Checksum() {
+				s.State = StateChanged
+			}
+			s.AppliedAt = &a.AppliedAt
+		}
+		statuses = append(statuses, s)
+	}
+
+	for _, a := range applied {
+		if !known[a.Version] {
+			statuses = append(statuses, Status{Version: a.Version, Name: a.Name, State: StateMissing, AppliedAt: &a.AppliedAt})
+		}
+	}
+	return statuses
+}
+
+// Pending returns the migrations to apply, in order. It fails when an
+// applied migration was changed or is missing, as the schema no longer
+// matches the files then.
+func Pending(migrations []Migration, applied []Applied) ([]Migration, error) {
+	if err := verify(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	byVersion := appliedByVersion(applied)
+	pending := []Migration{}
+	for _, m := range migrations {
+		if _, ok := byVersion[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Rollback returns the last n applied migrations to revert, latest first.
+// Each of them must have a down file.
+func Rollback(migrations []Migration, applied []Applied, n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, errors.New("Number of migrations to revert must be positive")
+	}
+	if err := verify(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	byVersion := appliedByVersion(applied)
+	revert := []Migration{}
+	for i := len(migrations) - 1; i >= 0 && len(revert) < n; i-- {
+		m := migrations[i]
+		if _, ok := byVersion[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+		revert = append(revert, m)
+	}
+	return revert, nil
+}
+
+// Create writes an empty pair of migration files to dir, versioned with now,
+// and returns their paths.
+func Create(dir, name string, now time.Time) ([]string, error) {
+	if !nameRegex.MatchString(name) {
+		return nil, errors.New("Migration name must only have lowercase letters, digits and underscores")
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%s_%s", now.UTC().Format("20060102150405"), name))
+	paths := []string{base + ".up.sql", base + ".down.sql"}
+	for _, p := range paths {
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := f.Close(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return paths, nil
+}
+
+func verify(migrations []Migration, applied []Applied) error {
+	for _, s := range Statuses(migrations, applied) {
+		switch s.State {
+		case StateChanged:
+			return fmt.Errorf("migration %d_%s was changed after it was applied", s.Version, s.Name)
+		case StateMissing:
+			return fmt.Errorf("migration %d_%s was applied but its file is missing", s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+func appliedByVersion(applied []Applied) map[int64]Applied {
+	byVersion := make(map[int64]Applied, len(applied))
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+	return byVersion
+}
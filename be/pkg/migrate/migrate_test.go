@@ -0,0 +1,126 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMigrations(t *testing.T) []Migration {
+	migrations, err := Load(fstest.MapFS{
+		"2_add_name.up.sql":   {Data: []byte("ALTER TABLE users ADD name TEXT;")},
+		"2_add_name.down.sql": {Data: []byte("ALTER TABLE users DROP name;")},
+		"1_users.up.sql":      {Data: []byte("CREATE TABLE users (id INT);")},
+		"1_users.down.sql":    {Data: []byte("DROP TABLE users;")},
+		"3_seed.up.sql":       {Data: []byte("INSERT INTO users VALUES (1);")},
+		"README.md":           {Data: []byte("ignored")},
+	})
+	require.NoError(t, err)
+	return migrations
+}
+
+func applied(m Migration) Applied {
+	return Applied{Version: m.Version, Name: m.Name, Checksum: m.Checksum(), AppliedAt: time.Now()}
+}
+
+func TestLoad(t *testing.T) {
+	migrations := testMigrations(t)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, Migration{Version: 1, Name: "users", Up: "CREATE TABLE users (id INT);", Down: "DROP TABLE users;"}, migrations[0])
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "seed", migrations[2].Name)
+	assert.Empty(t, migrations[2].Down)
+
+	_, err := Load(fstest.MapFS{"1_users.down.sql": {Data: []byte("DROP TABLE users;")}})
+	assert.ErrorContains(t, err, "no up file")
+
+	_, err = Load(fstest.MapFS{
+		"1_users.up.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+		"1_accounts.up.sql": {Data: []byte("CREATE TABLE accounts (id INT);")},
+	})
+	assert.ErrorContains(t, err, "named both")
+}
+
+func TestPending(t *testing.T) {
+	migrations := testMigrations(t)
+
+	pending, err := Pending(migrations, nil)
+	require.NoError(t, err)
+	assert.Len(t, pending, 3)
+
+	// a migration merged after a later one was applied still runs
+	pending, err = Pending(migrations, []Applied{applied(migrations[1])})
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, int64(1), pending[0].Version)
+	assert.Equal(t, int64(3), pending[1].Version)
+
+	changed := applied(migrations[0])
+	changed.Checksum = "edited"
+	_, err = Pending(migrations, []Applied{changed})
+	assert.ErrorContains(t, err, "changed after it was applied")
+
+	_, err = Pending(migrations, []Applied{{Version: 9, Name: "gone"}})
+	assert.ErrorContains(t, err, "file is missing")
+}
+
+func TestRollback(t *testing.T) {
+	migrations := testMigrations(t)
+	all := []Applied{applied(migrations[0]), applied(migrations[1]), applied(migrations[2])}
+
+	// the last migration has no down file
+	_, err := Rollback(migrations, all, 1)
+	assert.ErrorContains(t, err, "no down file")
+
+	revert, err := Rollback(migrations, all[:2], 5)
+	require.NoError(t, err)
+	require.Len(t, revert, 2)
+	assert.Equal(t, int64(2), revert[0].Version)
+	assert.Equal(t, int64(1), revert[1].Version)
+
+	_, err = Rollback(migrations, all[:2], 0)
+	assert.Error(t, err)
+}
+
+func TestStatuses(t *testing.T) {
+	migrations := testMigrations(t)
+	changed := applied(migrations[1])
+	changed.Checksum = "edited"
+
+	statuses := Statuses(migrations, []Applied{applied(migrations[0]), changed, {Version: 9, Name: "gone"}})
+	require.Len(t, statuses, 4)
+	assert.Equal(t, StateApplied, statuses[0].State)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Equal(t, StateChanged, statuses[1].State)
+	assert.Equal(t, StatePending, statuses[2].State)
+	assert.Nil(t, statuses[2].AppliedAt)
+	assert.Equal(t, Status{Version: 9, Name: "gone", State: StateMissing, AppliedAt: statuses[3].AppliedAt}, statuses[3])
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	paths, err := Create(dir, "add_user_locale", now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "20261018093000_add_user_locale.up.sql"),
+		filepath.Join(dir, "20261018093000_add_user_locale.down.sql"),
+	}, paths)
+
+	// an empty up file is still a migration, to be filled in
+	migrations, err := Load(os.DirFS(dir))
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, int64(20261018093000), migrations[0].Version)
+
+	_, err = Create(dir, "add_user_locale", now)
+	assert.Error(t, err)
+	_, err = Create(dir, "Add user locale", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package model
+
+const (
+	ActorUser   = "user"
+	ActorAPIKey = "apikey"
+	ActorSystem = "system"
+)
+
+// Actor is who performed an admin action: a signed-in user, an API key or a
+// background job of the system itself.
+type Actor struct {
+	Kind string
+	ID   string
+}
+
+func UserActor(id string) Actor {
+	return Actor{Kind: ActorUser, ID: id}
+}
+
+func APIKeyActor(id string) Actor {
+	return Actor{Kind: ActorAPIKey, ID: id}
+}
+
+// SystemActor names a background job, e.g. "purge".
+func SystemActor(job string) Actor {
+	return Actor{Kind: ActorSystem, ID: job}
+}
+
+// IsUser reports whether the actor is the user with the given id.
+func (a Actor) IsUser(id string) bool {
+	return a.Kind == ActorUser && a.ID == id
+}
+
+func (a Actor) String() string {
+	return a.Kind + ":" + a.ID
+}
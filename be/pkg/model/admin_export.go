@@ -0,0 +1,42 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Kinds of admin exports.
+const (
+	AdminExportUsers    = "users"
+	AdminExportUserLogs = "userlogs"
+)
+
+// UserFilter selects the users of an export, the same way as the admin user
+// list filters and sorts them.
+type UserFilter struct {
+	Search      string    `json:"search,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	CreatedFrom time.Time `json:"createdFrom,omitzero"`
+	CreatedTo   time.Time `json:"createdTo,omitzero"`
+	SortBy      string    `json:"sortBy"`
+	Desc        bool      `json:"desc,omitempty"`
+}
+
+// AdminExport is an export of users or user logs built in the background by
+// the worker. It goes through the DataExport statuses and File holds the
+// export once ready.
+type AdminExport struct {
+	ID        string
+	Kind      string
+	Format    string
+	Status    string
+	CreatedBy string
+	// OrgID is the organization whose users or logs are exported.
+	OrgID  string
+	Filter UserFilter
+	File   []byte
+	Error  sql.NullString
+
+	CreatedAt   time.Time
+	CompletedAt sql.NullTime
+}
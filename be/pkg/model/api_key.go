@@ -0,0 +1,42 @@
+package model
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+)
+
+// API key scopes. Each admin route requires one of them when called with an
+// API key instead of a user token.
+const (
+	ScopeUsersRead    = "users:read"
+	ScopeUsersWrite   = "users:write"
+	ScopeUserLogsRead = "userlogs:read"
+)
+
+var Scopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeUserLogsRead}
+
+func IsValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+type APIKey struct {
+	ID   string
+	Name string
+	// Prefix is the start of the key, kept in clear so it can be recognised
+	// in listings.
+	Prefix string
+	Scopes []string
+	// OrgID is the organization the key acts in.
+	OrgID      string
+	CreatedBy  sql.NullString
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
+
+// Active reports whether the key can still be used to authenticate.
+func (k *APIKey) Active(now time.Time) bool {
+	return !k.RevokedAt.Valid && (!k.ExpiresAt.Valid || now.Before(k.ExpiresAt.Time))
+}
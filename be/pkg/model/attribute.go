@@ -0,0 +1,174 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of custom user attributes, named after the JSON types they hold.
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+)
+
+var AttributeTypes = []string{AttributeString, AttributeNumber, AttributeBoolean}
+
+// MaxAttributeLength caps string attributes whose definition sets no
+// max length.
+const MaxAttributeLength = 255
+
+var attributeNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// IsValidAttributeName reports whether name can name a custom attribute:
+// lower case letters, digits and underscores, starting with a letter.
+func IsValidAttributeName(name string) bool {
+	return attributeNameRe.MatchString(name)
+}
+
+// Attributes are the custom attributes of a user, by name. Values are
+// strings, float64 numbers or booleans, as they decode from JSON.
+type Attributes map[string]any
+
+// AttributeDefinition is one entry of the attribute schema admins manage.
+// Users can only hold the attributes it defines.
+type AttributeDefinition struct {
+	Name     string
+	Type     string
+	Required bool
+	// Enum lists the values a string attribute may take, empty for any.
+	Enum []string
+	// MaxLength caps a string attribute, zero for MaxAttributeLength.
+	MaxLength int
+
+	UpdatedAt time.Time
+	CreatedAt time.Time
+}
+
+// Check validates the definition itself.
+func (d *AttributeDefinition) Check() error {
+	if !IsValidAttributeName(d.Name) {
+		return fmt.Errorf("invalid attribute name %q", d.Name)
+	}
+	if !slices.Contains(AttributeTypes, d.Type) {
+		return fmt.Errorf("invalid attribute type %q", d.Type)
+	}
+	if d.Type != AttributeString && (len(d.Enum) > 0 || d.MaxLength != 0) {
+		return errors.New("enum and max length only apply to string attributes")
+	}
+	if d.MaxLength < 0 || d.MaxLength > MaxAttributeLength {
+		return fmt.Errorf("max length must be between 0 and %d", MaxAttributeLength)
+	}
+	for _, v := range d.Enum {
+		if err := d.checkLength(v); err != nil {
+			return fmt.Errorf("enum value %q is %w", v, err)
+		}
+	}
+	return nil
+}
+
+func (d *AttributeDefinition) maxLength() int {
+	if d.MaxLength == 0 {
+		return MaxAttributeLength
+	}
+	return d.MaxLength
+}
+
+func (d *AttributeDefinition) checkLength(s string) error {
+	if len(s) > d.maxLength() {
+		return fmt.Errorf("longer than %d characters", d.maxLength())
+	}
+	return nil
+}
+
+// CheckValue reports what is wrong with v as a value of the attribute.
+func (d *AttributeDefinition) CheckValue(v any) error {
+	switch d.Type {
+	case AttributeString:
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		if err := d.checkLength(s); err != nil {
+			return err
+		}
+		if len(d.Enum) > 0 && !slices.Contains(d.Enum, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(d.Enum, ", "))
+		}
+	case AttributeNumber:
+		if _, ok := v.(float64); !ok {
+			return errors.New("must be a number")
+		}
+	case AttributeBoolean:
+		if _, ok := v.(bool); !ok {
+			return errors.New("must be a boolean")
+		}
+	}
+	return nil
+}
+
+// ParseValue reads a value of the attribute from text, such as a query
+// parameter or a CSV cell, and checks it.
+func (d *AttributeDefinition) ParseValue(s string) (any, error) {
+	var v any = s
+	switch d.Type {
+	case AttributeNumber:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		v = f
+	case AttributeBoolean:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be a boolean")
+		}
+		v = b
+	}
+	return v, d.CheckValue(v)
+}
+
+// AttributeErrors maps the invalid attributes of a user to what is wrong
+// with them.
+type AttributeErrors map[string]string
+
+func (e AttributeErrors) Error() string {
+	names := slices.Sorted(maps.Keys(e))
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, name+": "+e[name])
+	}
+	return "invalid attributes: " + strings.Join(msgs, "; ")
+}
+
+// CheckAttributes validates attrs against the schema: each attribute must be
+// defined and hold a valid value, and the required ones must be present.
+func CheckAttributes(schema []AttributeDefinition, attrs Attributes) error {
+	invalid := AttributeErrors{}
+	for name, v := range attrs {
+		i := slices.IndexFunc(schema, func(d AttributeDefinition) bool { return d.Name == name })
+		if i < 0 {
+			invalid[name] = "unknown attribute"
+			continue
+		}
+		if err := schema[i].CheckValue(v); err != nil {
+			invalid[name] = err.Error()
+		}
+	}
+	for _, d := range schema {
+		if _, ok := attrs[d.Name]; d.Required && !ok {
+			invalid[d.Name] = "required"
+		}
+	}
+
+	if len(invalid) > 0 {
+		return invalid
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = []AttributeDefinition{
+	{Name: "department", Type: AttributeString, Required: true, Enum: []string{"eng", "sales"}},
+	{Name: "external_id", Type: AttributeString, MaxLength: 5},
+	{Name: "level", Type: AttributeNumber},
+	{Name: "contractor", Type: AttributeBoolean},
+}
+
+func TestAttributeDefinitionCheck(t *testing.T) {
+	cases := []struct {
+		name  string
+		def   AttributeDefinition
+		valid bool
+	}{
+		{"string", AttributeDefinition{Name: "locale", Type: AttributeString, MaxLength: 10}, true},
+		{"number", AttributeDefinition{Name: "level", Type: AttributeNumber, Required: true}, true},
+		{"bad name", AttributeDefinition{Name: "Locale", Type: AttributeString}, false},
+		{"bad type", AttributeDefinition{Name: "locale", Type: "date"}, false},
+		{"enum on number", AttributeDefinition{Name: "level", Type: AttributeNumber, Enum: []string{"1"}}, false},
+		{"max length too big", AttributeDefinition{Name: "locale", Type: AttributeString, MaxLength: 1000}, false},
+		{"enum longer than max", AttributeDefinition{Name: "locale", Type: AttributeString, MaxLength: 2, Enum: []string{"en-GB"}}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.def.Check()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestAttributeDefinitionParseValue(t *testing.T) {
+	v, err := testSchema[2].ParseValue("3.5")
+	require.NoError(t, err)
+	assert.Equal(t, 3.5, v)
+
+	v, err = testSchema[3].ParseValue("true")
+	require.NoError(t, err)
+	assert.Equal(t, true, v)
+
+	_, err = testSchema[0].ParseValue("hr")
+	assert.Error(t, err)
+	_, err = testSchema[2].ParseValue("three")
+	assert.Error(t, err)
+}
+
+func TestCheckAttributes(t *testing.T) {
+	assert.NoError(t, CheckAttributes(testSchema, Attributes{
+		"department": "eng",
+		"level":      float64(2),
+		"contractor": false,
+	}))
+
+	err := CheckAttributes(testSchema, Attributes{
+		"external_id": "123456",
+		"level":       "2",
+		"team":        "core",
+	})
+	var invalid AttributeErrors
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, AttributeErrors{
+		"department":  "required",
+		"external_id": "longer than 5 characters",
+		"level":       "must be a number",
+		"team":        "unknown attribute",
+	}, invalid)
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is a user's request for a copy of their personal data. The
+// worker builds the archive and stores it on the export.
+type DataExport struct {
+	ID          string
+	UserID      string
+	Status      string
+	Archive     []byte
+	Error       sql.NullString
+	CreatedAt   time.Time
+	CompletedAt sql.NullTime
+}
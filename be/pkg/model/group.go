@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// MaxGroupNameLength matches user_groups.name.
+const MaxGroupNameLength = 100
+
+// Group is a named set of users of an organization, for admins to select
+// them together. Names are unique within the organization, ignoring case.
+type Group struct {
+	ID          string
+	OrgID       string
+	Name        string
+	Description string
+	// MemberCount is how many users are in the group.
+	MemberCount int
+
+	UpdatedAt time.Time
+	CreatedAt time.Time
+}
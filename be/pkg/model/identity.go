@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Identity links a user to an account at an external OpenID Connect provider.
+type Identity struct {
+	Provider      string
+	Subject       string
+	UserID        string
+	Email         string
+	EmailVerified bool
+	CreatedAt     time.Time
+}
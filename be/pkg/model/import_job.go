@@ -0,0 +1,49 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// Formats of user import files.
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+)
+
+// ImportJob is a bulk user import, run by the worker. Data holds the uploaded
+// file and is only loaded by the worker.
+type ImportJob struct {
+	ID        string
+	Format    string
+	Status    string
+	CreatedBy string
+	// OrgID is the organization the imported users join.
+	OrgID string
+	Data  []byte
+
+	TotalRows     int
+	ProcessedRows int
+	CreatedRows   int
+	FailedRows    int
+	Error         sql.NullString
+
+	CreatedAt   time.Time
+	StartedAt   sql.NullTime
+	CompletedAt sql.NullTime
+}
+
+// ImportRowError is why a row of an import was not created. Row counts data
+// rows from 1, not counting a CSV header.
+type ImportRowError struct {
+	Row     int
+	Email   string
+	Message string
+}
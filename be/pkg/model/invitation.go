@@ -0,0 +1,33 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Invitation statuses. An invitation is pending until it is accepted by
+// signing up, revoked by an admin or expired by the sweep.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation lets whoever holds its token sign up with Email and join OrgID
+// with Role. Only a hash of the token is stored.
+type Invitation struct {
+	ID     string
+	OrgID  string
+	Email  string
+	Role   string
+	Status string
+	// InvitedBy is the id of the user or API key that created the invitation.
+	InvitedBy string
+	// UserID is the user who signed up with the invitation.
+	UserID     sql.NullString
+	ExpiresAt  time.Time
+	AcceptedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// MFA is a user's TOTP enrollment. Secret is the decrypted base32 seed and
+// RecoveryCodes the hashes of the recovery codes not used yet.
+type MFA struct {
+	UserID        string
+	Secret        string
+	RecoveryCodes []string
+	ConfirmedAt   sql.NullTime
+	CreatedAt     time.Time
+}
+
+// Enabled reports whether the enrollment was confirmed with a valid code.
+func (m *MFA) Enabled() bool {
+	return m.ConfirmedAt.Valid
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// DefaultOrgID is the organization created with the database. New users who
+// sign up join it, and its admins may create other organizations.
+const DefaultOrgID = "00000000-0000-0000-0000-000000000001"
+
+// Organization is a tenant. Users belong to one or more of them and the admin
+// endpoints only see the users and logs of the one a request acts in.
+type Organization struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+// Membership is a user's place in an organization. Roles are held per
+// organization, a user can be admin of one and a plain user of another.
+type Membership struct {
+	OrgID     string
+	OrgName   string
+	UserID    string
+	Role      string
+	CreatedAt time.Time
+}
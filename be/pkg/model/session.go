@@ -0,0 +1,21 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Session struct {
+	ID     string
+	UserID string
+	// OrgID is the organization the session acts in, until switched.
+	OrgID     string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
+
+// Active reports whether the session can still be used to authenticate.
+func (s *Session) Active(now time.Time) bool {
+	return !s.RevokedAt.Valid && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SignInAttempts counts failed sign-ins for a key, such as an account or a
+// client IP, within the current window.
+type SignInAttempts struct {
+	Key          string
+	Failures     int
+	BlockedUntil sql.NullTime
+}
+
+// Blocked reports whether sign-ins for the key are refused at now.
+func (a *SignInAttempts) Blocked(now time.Time) bool {
+	return a.BlockedUntil.Valid && now.Before(a.BlockedUntil.Time)
+}
@@ -2,13 +2,74 @@ package model
 
 import (
 	"database/sql"
+	"net/mail"
+	"slices"
 	"time"
 )
 
+const (
+	RoleUser    = "user"
+	RoleAdmin   = "admin"
+	RoleAuditor = "auditor"
+)
+
+var Roles = []string{RoleUser, RoleAdmin, RoleAuditor}
+
+// User statuses. Only active users can sign in or use their tokens; deleted
+// users are kept for a retention window so they can be restored, then purged.
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusDeleted   = "deleted"
+)
+
+var Statuses = []string{StatusActive, StatusSuspended, StatusDeleted}
+
+func IsValidStatus(status string) bool {
+	return slices.Contains(Statuses, status)
+}
+
+func IsValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// MaxEmailLength and MaxNameLength are the sizes of the users columns.
+const (
+	MaxEmailLength = 50
+	MaxNameLength  = 50
+)
+
+// IsValidEmail reports whether email is an address users can sign up with.
+func IsValidEmail(email string) bool {
+	_, err := mail.ParseAddress(email)
+	return err == nil && len(email) <= MaxEmailLength
+}
+
 type User struct {
 	ID        string
 	Email     string
 	Password  string
 	Name      sql.NullString
+	Role      string
 	CreatedAt time.Time
+
+	EmailVerifiedAt sql.NullTime
+
+	Status      string
+	SuspendedAt sql.NullTime
+	DeletedAt   sql.NullTime
+
+	// Version counts the writes to the user, for optimistic concurrency.
+	Version int
+
+	// Attributes hold the custom fields defined by the attribute schema.
+	Attributes Attributes
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt.Valid
+}
+
+func (u *User) Active() bool {
+	return u.Status == StatusActive
 }
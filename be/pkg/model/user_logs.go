@@ -1,12 +1,46 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
+const logPartitionPrefix = "logs#"
+
+// AnonymisedUserID replaces the user id of log entries whose user deleted
+// their account.
+const AnonymisedUserID = "anonymised"
+
+// LogPartition is the DynamoDB partition holding the logs of an
+// organization. The default organization, and events outside of any, keep
+// the partition all logs lived in before organizations.
+func LogPartition(orgID string) string {
+	if orgID == "" || orgID == DefaultOrgID {
+		return "logs"
+	}
+	return logPartitionPrefix + orgID
+}
+
+// LogPartitionOrg returns the organization of a partition made by
+// LogPartition, empty for the default one.
+func LogPartitionOrg(pk string) string {
+	orgID, ok := strings.CutPrefix(pk, logPartitionPrefix)
+	if !ok {
+		return ""
+	}
+	return orgID
+}
+
 type UserLogs struct {
+	ID        string
 	UserID    string
 	EventType string
 	Details   string
+	Actor     string
+	// Impersonator is the admin who acted as the user, if any.
+	Impersonator string
+	// OrgID is the organization the entry is logged in, empty for the
+	// default one.
+	OrgID     string
 	CreatedAt time.Time
 }
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogPartition(t *testing.T) {
+	assert.Equal(t, "logs", LogPartition(""))
+	assert.Equal(t, "logs", LogPartition(DefaultOrgID))
+	assert.Equal(t, "logs#org-1", LogPartition("org-1"))
+}
+
+func TestLogPartitionOrg(t *testing.T) {
+	assert.Equal(t, "", LogPartitionOrg("logs"))
+	assert.Equal(t, "org-1", LogPartitionOrg(LogPartition("org-1")))
+}
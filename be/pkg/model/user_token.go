@@ -0,0 +1,8 @@
+package model
+
+// Kinds of single-use tokens stored in the user_tokens table.
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+	TokenMFAChallenge      = "mfa_challenge"
+)
@@ -0,0 +1,70 @@
+// Package secretbox encrypts small secrets (e.g. TOTP seeds) before they are
+// stored, using AES-256-GCM.
+package secretbox
+
+import (
+	"be/pkg/errors"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+)
+
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a Box using a 32 bytes key.
+func New(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, errors.New("Encryption key must be 32 bytes")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// NewFromBase64 returns a Box using a base64 (std encoding) 32 bytes key.
+func NewFromBase64(key string) (*Box, error) {
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return New(b)
+}
+
+// Seal encrypts plaintext and returns it base64 encoded with its nonce.
+func (b *Box) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value returned by Seal.
+func (b *Box) Open(ciphertext string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	n := b.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("Ciphertext too short")
+	}
+
+	plaintext, err := b.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	return plaintext, errors.WithStack(err)
+}
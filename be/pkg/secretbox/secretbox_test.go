@@ -0,0 +1,34 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{1}, 32))
+	assert.NoError(t, err)
+
+	sealed, err := box.Seal([]byte("secret"))
+	assert.NoError(t, err)
+	assert.NotContains(t, sealed, "secret")
+
+	opened, err := box.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(opened))
+
+	other, err := New(bytes.Repeat([]byte{2}, 32))
+	assert.NoError(t, err)
+	_, err = other.Open(sealed)
+	assert.Error(t, err)
+}
+
+func TestNewInvalidKey(t *testing.T) {
+	_, err := New([]byte("short"))
+	assert.Error(t, err)
+
+	_, err = NewFromBase64("not base64!")
+	assert.Error(t, err)
+}
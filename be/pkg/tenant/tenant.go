@@ -0,0 +1,19 @@
+// Package tenant carries the organization a request acts in through its
+// context, from the auth middleware down to the stores.
+package tenant
+
+import "context"
+
+type contextKey struct{}
+
+// WithOrg returns ctx acting in the given organization. An empty id makes it
+// act outside of any organization, as background jobs do.
+func WithOrg(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, orgID)
+}
+
+// OrgID returns the organization ctx acts in, empty when there is none.
+func OrgID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,19 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgID(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, OrgID(ctx))
+
+	ctx = WithOrg(ctx, "org-1")
+	assert.Equal(t, "org-1", OrgID(ctx))
+
+	// a job started from a request can leave its organization
+	assert.Empty(t, OrgID(WithOrg(ctx, "")))
+}
@@ -0,0 +1,88 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		c, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.code, c)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name   string
+		code   string
+		expect bool
+	}{
+		{"current period", "050471", true},
+		{"previous period", "081804", true},
+		{"wrong code", "000000", false},
+		{"wrong length", "50471", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, Validate(rfcSecret, tt.code, now))
+		})
+	}
+
+	assert.False(t, Validate("not base32!", "050471", now))
+}
+
+func TestValidateStep(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok := ValidateStep(rfcSecret, "050471", now)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111111/Period), step)
+
+	// a code of the previous period is reported with its own step
+	step, ok = ValidateStep(rfcSecret, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111109/Period), step)
+
+	_, ok = ValidateStep(rfcSecret, "000000", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	s, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, s, 32)
+
+	c, err := Code(s, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, Validate(s, c, time.Now()))
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("QaasT", "user@example.com", "SECRET")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/QaasT:user@example.com?"))
+	assert.Contains(t, uri, "secret=SECRET")
+	assert.Contains(t, uri, "issuer=QaasT")
+}
@@ -5,10 +5,18 @@ import (
 	"api/store"
 	"api/transport"
 	"be/pkg/config"
+	"be/pkg/errors"
+	pkghttp "be/pkg/http"
+	"be/pkg/jwtkeys"
+	"be/pkg/mail"
+	"be/pkg/secretbox"
 	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
@@ -23,6 +31,89 @@ type envConfig struct {
 	PgApiConnURI string `mapstructure:"PG_API_CONN_URI"`
 	JwtSecret    string `mapstructure:"JWT_SECRET"`
 
+	// MigrateOnStart applies the pending migrations before serving, otherwise
+	// they are applied with the `migrate up` subcommand.
+	MigrateOnStart bool `mapstructure:"MIGRATE_ON_START"`
+
+	// JwtKeys lists PEM key files as "kid=path" separated by ";", and
+	// JwtSigningKID picks the one new tokens are signed with. JwtSecret is the
+	// HS256 key for tokens without a kid, only used without JwtKeys.
+	JwtKeys       []string `mapstructure:"JWT_KEYS"`
+	JwtSigningKID string   `mapstructure:"JWT_SIGNING_KID"`
+
+	AccessTokenTTL  string `mapstructure:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL string `mapstructure:"REFRESH_TOKEN_TTL"`
+	// SessionCacheTTL is how long each replica caches sessions, so how long a
+	// session revoked through another replica stays usable there.
+	SessionCacheTTL string `mapstructure:"SESSION_CACHE_TTL"`
+	// ImpersonationTTL is how long the tokens of admins impersonating users
+	// last.
+	ImpersonationTTL string `mapstructure:"IMPERSONATION_TTL"`
+
+	// CursorSecret signs pagination cursors so clients cannot forge them. It
+	// has no default, the api refuses to start without it.
+	CursorSecret string `mapstructure:"CURSOR_SECRET"`
+
+	// UserStatusCacheTTL is how long each replica caches user statuses, so how
+	// long a user deactivated through another replica keeps access there.
+	UserStatusCacheTTL string `mapstructure:"USER_STATUS_CACHE_TTL"`
+	// DeletedUserRetention is how long deleted users can be restored before
+	// the purge, run every UserPurgeInterval, removes them.
+	DeletedUserRetention string `mapstructure:"DELETED_USER_RETENTION"`
+	UserPurgeInterval    string `mapstructure:"USER_PURGE_INTERVAL"`
+
+	AppURL           string `mapstructure:"APP_URL"`
+	PasswordResetTTL string `mapstructure:"PASSWORD_RESET_TTL"`
+
+	EmailVerificationTTL    string `mapstructure:"EMAIL_VERIFICATION_TTL"`
+	EmailVerificationPolicy string `mapstructure:"EMAIL_VERIFICATION_POLICY"`
+
+	// SignUpPolicy is "open" (default) or "invite". Invitations expire after
+	// InvitationTTL unless the admin sets their expiry, and are marked expired
+	// every InvitationExpiryInterval.
+	SignUpPolicy             string `mapstructure:"SIGNUP_POLICY"`
+	InvitationTTL            string `mapstructure:"INVITATION_TTL"`
+	InvitationExpiryInterval string `mapstructure:"INVITATION_EXPIRY_INTERVAL"`
+
+	MFAIssuer       string `mapstructure:"MFA_ISSUER"`
+	MFAChallengeTTL string `mapstructure:"MFA_CHALLENGE_TTL"`
+	// MFAEncryptionKey encrypts the TOTP secrets at rest. It has no default,
+	// the api refuses to start without it.
+	MFAEncryptionKey string `mapstructure:"MFA_ENCRYPTION_KEY"`
+
+	// OIDCProviders lists identity providers separated by ";", see store.ParseOIDCProviders.
+	OIDCProviders []string `mapstructure:"OIDC_PROVIDERS"`
+	OIDCStateTTL  string   `mapstructure:"OIDC_STATE_TTL"`
+
+	// SignInAttemptsStore is "postgres" (default) or "memory".
+	SignInAttemptsStore      string `mapstructure:"SIGNIN_ATTEMPTS_STORE"`
+	SignInMaxAccountFailures int    `mapstructure:"SIGNIN_MAX_ACCOUNT_FAILURES"`
+	SignInMaxIPFailures      int    `mapstructure:"SIGNIN_MAX_IP_FAILURES"`
+	SignInFailureWindow      string `mapstructure:"SIGNIN_FAILURE_WINDOW"`
+	SignInLockout            string `mapstructure:"SIGNIN_LOCKOUT"`
+	SignInDelayAfter         int    `mapstructure:"SIGNIN_DELAY_AFTER"`
+	SignInDelay              string `mapstructure:"SIGNIN_DELAY"`
+
+	DataExportTTL     string `mapstructure:"DATA_EXPORT_TTL"`
+	DataExportTimeout string `mapstructure:"DATA_EXPORT_TIMEOUT"`
+	// UserPurgeResweepDelay is when the logs of a deleted account are
+	// anonymised a second time, at most 15m.
+	UserPurgeResweepDelay string `mapstructure:"USER_PURGE_RESWEEP_DELAY"`
+
+	MailDriver   string `mapstructure:"MAIL_DRIVER"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	MailDir      string `mapstructure:"MAIL_DIR"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+
+	// AdminEmail and AdminPassword create the first admin of the default
+	// organization. The password has no default, the api refuses to start
+	// without it while AdminEmail is set.
+	AdminEmail    string `mapstructure:"ADMIN_EMAIL"`
+	AdminPassword string `mapstructure:"ADMIN_PASSWORD"`
+
 	AwsRegion           string `mapstructure:"AWS_REGION"`
 	AWSEndpoint         string `mapstructure:"AWS_ENDPOINT"`
 	AwsAccessKey        string `mapstructure:"AWS_ACCESS_KEY"`
@@ -45,12 +136,29 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	pgPool, err := pgxpool.New(ctx, env.PgApiConnURI)
+	pgConfig, err := pgxpool.ParseConfig(env.PgApiConnURI)
+	if err != nil {
+		panic(err)
+	}
+	store.ScopeToTenant(pgConfig)
+	pgPool, err := pgxpool.NewWithConfig(ctx, pgConfig)
 	if err != nil {
 		panic(err)
 	}
 	defer pgPool.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, pgPool, os.Args[2:]); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if env.MigrateOnStart {
+		if err := migrateOnStart(ctx, pgPool); err != nil {
+			panic(err)
+		}
+	}
+
 	sqsAWSConfig, err := awsconfig.LoadDefaultConfig(ctx,
 		awsconfig.WithRegion(env.AwsRegion),
 		awsconfig.WithBaseEndpoint(env.AWSEndpoint),
@@ -70,24 +178,178 @@ func main() {
 	r := chi.NewRouter()
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"http://localhost:3000", "http://frontend:3000"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-API-Key"},
 		ExposedHeaders: []string{"Link"},
 	}))
 
+	mailer, err := mail.New(mail.Config{
+		Driver:       env.MailDriver,
+		From:         env.MailFrom,
+		Dir:          env.MailDir,
+		SMTPHost:     env.SMTPHost,
+		SMTPPort:     env.SMTPPort,
+		SMTPUsername: env.SMTPUsername,
+		SMTPPassword: env.SMTPPassword,
+	})
+	if err != nil {
+		panic(err)
+	}
+
 	userLogsSQS := store.NewUserLogsSQS(sqsAWSConfig, env.SQSUserLogsQueueURL)
-	userRepo := store.NewUserRepo(pgPool)
-	userSvc := service.NewUserService(userRepo, env.JwtSecret)
+	userRepo := store.NewUserRepoWithStatusCache(store.NewUserRepo(pgPool), parseDuration(env.UserStatusCacheTTL, 5*time.Second))
+	orgRepo := store.NewOrganizationRepo(pgPool)
+	if env.AdminEmail != "" {
+		if env.AdminPassword == "" {
+			panic("ADMIN_PASSWORD is required with ADMIN_EMAIL, e.g. `openssl rand -hex 16`")
+		}
+		err := service.EnsureAdmin(ctx, userRepo, orgRepo, env.AdminEmail, env.AdminPassword)
+		if errors.IsInvalid(err) {
+			log.Println("bootstrap admin not created: " + err.Error())
+		} else if err != nil {
+			panic(err)
+		}
+	}
+
+	jwtKeys, err := loadJWTKeys(env)
+	if err != nil {
+		panic(err)
+	}
+
+	sessionRepo := store.NewSessionRepoWithCache(store.NewSessionRepo(pgPool), parseDuration(env.SessionCacheTTL, 5*time.Second))
+	authMiddleware := pkghttp.AuthMiddleware(jwtKeys, pkghttp.WithSessionCheck(sessionRepo), pkghttp.WithUserCheck(userRepo))
+
+	apiKeySvc := service.NewAPIKeyService(store.NewAPIKeyRepo(pgPool))
+	apiKeySvc = service.NewAPIKeyServiceWithQueue(apiKeySvc, userLogsSQS)
+
+	adminAuthMiddleware := pkghttp.AuthMiddleware(jwtKeys,
+		pkghttp.WithSessionCheck(sessionRepo), pkghttp.WithUserCheck(userRepo), pkghttp.WithAPIKeys(apiKeySvc))
+	if env.EmailVerificationPolicy == service.VerifyEmailAdmin {
+		withAPIKeys := adminAuthMiddleware
+		adminAuthMiddleware = func(next http.Handler) http.Handler {
+			return withAPIKeys(pkghttp.RequireVerifiedEmail(next))
+		}
+	}
+
+	userTokenRepo := store.NewUserTokenRepo(pgPool)
+	emailVerifier := service.NewEmailVerifier(userRepo, userTokenRepo, mailer, parseDuration(env.EmailVerificationTTL, 48*time.Hour), env.AppURL)
+	if env.MFAEncryptionKey == "" {
+		panic("MFA_ENCRYPTION_KEY is required, e.g. `openssl rand -base64 32`")
+	}
+	mfaBox, err := secretbox.NewFromBase64(env.MFAEncryptionKey)
+	if err != nil {
+		panic(err)
+	}
+	mfaRepo := store.NewMFARepo(pgPool, mfaBox)
+
+	oidcConfigs, err := store.ParseOIDCProviders(env.OIDCProviders)
+	if err != nil {
+		panic(err)
+	}
+	oidcProviders := store.NewOIDCProviders(ctx, oidcConfigs)
+	identityRepo := store.NewIdentityRepo(pgPool)
+
+	signInAttempts := store.NewSignInAttemptRepo(pgPool)
+	if env.SignInAttemptsStore == "memory" {
+		signInAttempts = store.NewSignInAttemptMemory()
+	}
+
+	invitationRepo := store.NewInvitationRepo(pgPool)
+	userSvc := service.NewUserService(userRepo, orgRepo, invitationRepo, sessionRepo, userTokenRepo, mailer, emailVerifier, mfaRepo, identityRepo, oidcProviders, signInAttempts, service.UserConfig{
+		JWTKeys:          jwtKeys,
+		AccessTTL:        parseDuration(env.AccessTokenTTL, 15*time.Minute),
+		RefreshTTL:       parseDuration(env.RefreshTokenTTL, 7*24*time.Hour),
+		PasswordResetTTL: parseDuration(env.PasswordResetTTL, time.Hour),
+		AppURL:           env.AppURL,
+
+		EmailVerificationPolicy: env.EmailVerificationPolicy,
+		SignUpPolicy:            env.SignUpPolicy,
+
+		MFAIssuer:       env.MFAIssuer,
+		MFAChallengeTTL: parseDuration(env.MFAChallengeTTL, 5*time.Minute),
+
+		OIDCStateTTL: parseDuration(env.OIDCStateTTL, 10*time.Minute),
+
+		SignInGuard: service.SignInGuardConfig{
+			MaxAccountFailures: intOr(env.SignInMaxAccountFailures, 5),
+			MaxIPFailures:      intOr(env.SignInMaxIPFailures, 100),
+			Window:             parseDuration(env.SignInFailureWindow, 15*time.Minute),
+			Lockout:            parseDuration(env.SignInLockout, 15*time.Minute),
+			DelayAfter:         intOr(env.SignInDelayAfter, 3),
+			Delay:              parseDuration(env.SignInDelay, time.Second),
+		},
+	})
 	userSvc = service.NewUserServiceWithQueue(userSvc, userLogsSQS)
-	userController := transport.NewUserController(r, userSvc)
+	userController := transport.NewUserController(r, userSvc, authMiddleware)
 	userController.RegisterRoutes()
 
+	// export and purge jobs share the queue with the user logs; the worker routes them
+	privacySQS := store.NewPrivacySQS(sqsAWSConfig, env.SQSUserLogsQueueURL)
+	privacySvc := service.NewPrivacyService(userRepo, sessionRepo, store.NewDataExportRepo(pgPool), privacySQS, service.PrivacyConfig{
+		ExportTTL:         parseDuration(env.DataExportTTL, 24*time.Hour),
+		ExportTimeout:     parseDuration(env.DataExportTimeout, 15*time.Minute),
+		PurgeResweepDelay: parseDuration(env.UserPurgeResweepDelay, 10*time.Minute),
+	})
+	privacySvc = service.NewPrivacyServiceWithQueue(privacySvc, userLogsSQS)
+	privacyController := transport.NewPrivacyController(r, privacySvc, authMiddleware)
+	privacyController.RegisterRoutes()
+
 	userLogRepo := store.NewLogRepo(dynamodbAWSConfig, env.DynamoTable)
-	adminSvc := service.NewAdminService(userRepo, userLogRepo)
+	if env.CursorSecret == "" {
+		panic("CURSOR_SECRET is required, e.g. `openssl rand -base64 32`")
+	}
+	userAttributeRepo := store.NewUserAttributeRepo(pgPool)
+	adminSvc := service.NewAdminService(userRepo, userAttributeRepo, userLogRepo, sessionRepo, emailVerifier, service.AdminConfig{
+		CursorSecret:     []byte(env.CursorSecret),
+		JWTKeys:          jwtKeys,
+		ImpersonationTTL: parseDuration(env.ImpersonationTTL, 15*time.Minute),
+	})
 	adminSvc = service.NewAdminServiceWithQueue(adminSvc, userLogsSQS)
-	adminControler := transport.NewAdminController(r, userSvc, adminSvc, env.JwtSecret)
+	adminControler := transport.NewAdminController(r, userSvc, adminSvc, adminAuthMiddleware)
 	adminControler.RegisterRoutes()
 
+	attributeSvc := service.NewUserAttributeService(userAttributeRepo)
+	attributeSvc = service.NewUserAttributeServiceWithQueue(attributeSvc, userLogsSQS)
+	attributeController := transport.NewUserAttributeController(r, attributeSvc, adminAuthMiddleware)
+	attributeController.RegisterRoutes()
+
+	groupSvc := service.NewGroupServiceWithQueue(service.NewGroupService(store.NewGroupRepo(pgPool)), userLogsSQS)
+	groupController := transport.NewGroupController(r, groupSvc, adminSvc, adminAuthMiddleware)
+	groupController.RegisterRoutes()
+
+	go purgeDeletedUsers(ctx, adminSvc,
+		parseDuration(env.DeletedUserRetention, 30*24*time.Hour), parseDuration(env.UserPurgeInterval, time.Hour))
+
+	importSvc := service.NewUserImportService(store.NewImportJobRepo(pgPool), store.NewUserImportSQS(sqsAWSConfig, env.SQSUserLogsQueueURL))
+	importSvc = service.NewUserImportServiceWithQueue(importSvc, userLogsSQS)
+	importController := transport.NewUserImportController(r, importSvc, adminAuthMiddleware)
+	importController.RegisterRoutes()
+
+	exportSvc := service.NewAdminExportService(userRepo, userLogRepo, store.NewAdminExportRepo(pgPool), store.NewAdminExportSQS(sqsAWSConfig, env.SQSUserLogsQueueURL))
+	exportSvc = service.NewAdminExportServiceWithQueue(exportSvc, userLogsSQS)
+	exportController := transport.NewAdminExportController(r, exportSvc, adminAuthMiddleware)
+	exportController.RegisterRoutes()
+
+	apiKeyController := transport.NewAPIKeyController(r, apiKeySvc, adminAuthMiddleware)
+	apiKeyController.RegisterRoutes()
+
+	orgSvc := service.NewOrganizationServiceWithQueue(service.NewOrganizationService(orgRepo), userLogsSQS)
+	orgController := transport.NewOrganizationController(r, orgSvc, adminAuthMiddleware)
+	orgController.RegisterRoutes()
+
+	invitationSvc := service.NewInvitationService(invitationRepo, userRepo, mailer, service.InvitationConfig{
+		TTL:    parseDuration(env.InvitationTTL, 7*24*time.Hour),
+		AppURL: env.AppURL,
+	})
+	invitationSvc = service.NewInvitationServiceWithQueue(invitationSvc, userLogsSQS)
+	invitationController := transport.NewInvitationController(r, invitationSvc, authMiddleware, adminAuthMiddleware)
+	invitationController.RegisterRoutes()
+
+	go expireInvitations(ctx, invitationSvc, parseDuration(env.InvitationExpiryInterval, 10*time.Minute))
+
+	jwksController := transport.NewJWKSController(r, jwtKeys)
+	jwksController.RegisterRoutes()
+
 	port := fmt.Sprintf(":%d", env.Port)
 	srv := &http.Server{Addr: port, Handler: r}
 	log.Println("API listening on " + port)
@@ -95,3 +357,100 @@ func main() {
 		panic(err)
 	}
 }
+
+// purgeDeletedUsers removes users deleted longer than retention ago, every
+// interval. Replicas running it at the same time each purge different rows.
+func purgeDeletedUsers(ctx context.Context, adminSvc service.AdminService, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		memberships, err := adminSvc.PurgeDeletedUsers(ctx, time.Now().UTC().Add(-retention))
+		if err != nil {
+			log.Println("purging deleted users: " + err.Error())
+		} else if len(memberships) > 0 {
+			log.Printf("purged %d deleted users", len(memberships))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireInvitations marks the invitations past their expiry every interval.
+// Each one is expired by a single replica.
+func expireInvitations(ctx context.Context, invitationSvc service.InvitationService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		invitations, err := invitationSvc.ExpireInvitations(ctx, time.Now().UTC())
+		if err != nil {
+			log.Println("expiring invitations: " + err.Error())
+		} else if len(invitations) > 0 {
+			log.Printf("expired %d invitations", len(invitations))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseDuration parses a Go duration string such as "15m", falling back to def
+// when the variable is not set.
+func parseDuration(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// intOr returns v, or def when the variable is not set.
+func intOr(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// loadJWTKeys loads the keys access tokens are signed and verified with. Once
+// asymmetric keys are configured, the HS256 secret is no longer accepted.
+func loadJWTKeys(env envConfig) (*jwtkeys.KeySet, error) {
+	keys := []*jwtkeys.Key{}
+	for _, spec := range env.JwtKeys {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(spec, "=")
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid=path", spec)
+		}
+
+		k, err := jwtkeys.LoadPEMFile(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if len(keys) == 0 {
+		if env.JwtSecret == "" {
+			return nil, fmt.Errorf("no JWT key configured, set JWT_KEYS")
+		}
+		keys = append(keys, jwtkeys.NewHMACKey("", []byte(env.JwtSecret)))
+	}
+
+	return jwtkeys.NewKeySet(env.JwtSigningKID, keys...)
+}
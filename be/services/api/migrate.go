@@ -0,0 +1,111 @@
+package main
+
+import (
+	"api/store"
+	"be/pkg/migrate"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const migrateUsage = `usage: main migrate <action>
+
+actions:
+  status       list the migrations and whether they are applied
+  up           apply the pending migrations
+  down N       revert the last N applied migrations
+  baseline     record the first migration as applied, once, on a database
+               whose schema was loaded before migrations
+  create NAME  add an empty migration to ` + store.MigrationsDir
+
+// runMigrate runs the `migrate` subcommand with its arguments.
+func runMigrate(ctx context.Context, pool *pgxpool.Pool, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing action\n\n%s", migrateUsage)
+	}
+
+	// creating a migration needs no database, and changes the files the
+	// next build embeds
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return fmt.Errorf("create takes the migration name\n\n%s", migrateUsage)
+		}
+		paths, err := migrate.Create(store.MigrationsDir, args[1], time.Now())
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			fmt.Println("created " + p)
+		}
+		return nil
+	}
+
+	migrator, err := store.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "status" && len(args) == 1:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := ""
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+		}
+		return w.Flush()
+	case args[0] == "up" && len(args) == 1:
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case args[0] == "baseline" && len(args) == 1:
+		m, err := migrator.Baseline(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("recorded %d_%s\n", m.Version, m.Name)
+		return nil
+	case args[0] == "down" && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid number of migrations %q", args[1])
+		}
+		reverted, err := migrator.Down(ctx, n)
+		for _, m := range reverted {
+			fmt.Printf("reverted %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	}
+	return fmt.Errorf("invalid action\n\n%s", migrateUsage)
+}
+
+// migrateOnStart applies the pending migrations before the api serves.
+// Replicas starting together apply them once, the others wait for them.
+func migrateOnStart(ctx context.Context, pool *pgxpool.Pool) error {
+	migrator, err := store.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		log.Printf("applied migration %d_%s", m.Version, m.Name)
+	}
+	return err
+}
@@ -3,41 +3,425 @@ package service
 import (
 	"api/store"
 	"be/pkg/errors"
+	"be/pkg/jwtkeys"
 	"be/pkg/model"
+	"be/pkg/tenant"
 	"context"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type AdminService interface {
-	ListUsers(ctx context.Context, limit int, cursor string) ([]model.User, error)
+	ListUsers(ctx context.Context, opts ListUsersOptions) (*UserPage, error)
 	ListUserLogs(ctx context.Context, limit int, cursor string) ([]model.UserLogs, string, error)
-	UpdateUser(ctx context.Context, adminID, userID, email, name string) (*model.User, error)
-	DeleteUser(ctx context.Context, adminID, userID string) error
+	GetUser(ctx context.Context, userID string) (*model.User, error)
+	// PatchUser only applies when the user is still at the given version, or
+	// at any version when it is zero. On a conflict the current user is
+	// returned with the error. It returns the old and new value of each field
+	// it changed. Users who also belong to other organizations cannot be
+	// changed, see checkOwnUser.
+	PatchUser(ctx context.Context, actor model.Actor, userID string, patch UserPatch, version int) (*model.User, map[string][2]string, error)
+	// DeleteUser marks the user deleted in the organization the context acts
+	// in. The membership is kept until PurgeDeletedUsers removes it, so the
+	// deletion can be undone meanwhile.
+	DeleteUser(ctx context.Context, actor model.Actor, userID string) error
+	// SuspendUser suspends the user in the organization the context acts in.
+	// Like deletions, it leaves their other organizations alone.
+	SuspendUser(ctx context.Context, actor model.Actor, userID string) (*model.User, error)
+	// RestoreUser makes a suspended or deleted user active again and returns
+	// the status they had.
+	RestoreUser(ctx context.Context, actor model.Actor, userID string) (*model.User, string, error)
+	// PurgeDeletedUsers removes the memberships deleted before the given
+	// time for good, and the users left in no organization.
+	PurgeDeletedUsers(ctx context.Context, before time.Time) ([]model.Membership, error)
+	// UpdateUserRole changes the role of the user and returns the role they
+	// had.
+	UpdateUserRole(ctx context.Context, actor model.Actor, userID, role string) (*model.User, string, error)
+	ResendVerification(ctx context.Context, actor model.Actor, userID string) error
+	// BulkUpdateUsers changes the status, name or role of many users in one
+	// transaction, or reports what it would change.
+	BulkUpdateUsers(ctx context.Context, actor model.Actor, op BulkUserOperation) (*BulkUserReport, error)
+	// Impersonate returns a short-lived access token acting as the user on
+	// behalf of the admin signed in with sessionID.
+	Impersonate(ctx context.Context, actor model.Actor, sessionID, userID string) (*Tokens, error)
+}
+
+// ListUsersOptions filters, sorts and pages the admin user list.
+type ListUsersOptions struct {
+	Search      string
+	Status      string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// SortBy is one of the store.UserSort* fields, created_at by default.
+	SortBy string
+	Desc   bool
+	// Limit is between 1 and MaxListUsersLimit.
+	Limit int
+	// Cursor is the NextCursor or PrevCursor of an earlier page.
+	Cursor string
+	// WithTotal also counts every user matching the filters.
+	WithTotal bool
+	// Attributes filters on attribute values, given as text and read as the
+	// type of the attribute.
+	Attributes map[string]string
+	// GroupID filters on the members of the group.
+	GroupID string
+}
+
+// UserPatch changes the fields of a user that are not nil. A name pointing to
+// an empty string clears it, the email cannot be cleared. A new email must be
+// verified again, so a verification link is sent to it.
+type UserPatch struct {
+	Email *string
+	Name  *string
+	// Attributes is merged into the user's attributes, a nil value removes
+	// the attribute.
+	Attributes model.Attributes
+}
+
+// MaxListUsersLimit caps the page size of ListUsers.
+const MaxListUsersLimit = 100
+
+// UserPage is a page of users. The cursors are empty when there is no page
+// after or before this one.
+type UserPage struct {
+	Users      []model.User
+	NextCursor string
+	PrevCursor string
+	// Total is only set when asked for with WithTotal.
+	Total *int
+}
+
+type AdminConfig struct {
+	// CursorSecret signs the user list cursors.
+	CursorSecret []byte
+
+	// JWTKeys sign the impersonation tokens, which expire after
+	// ImpersonationTTL.
+	JWTKeys          *jwtkeys.KeySet
+	ImpersonationTTL time.Duration
 }
 
 type adminService struct {
-	users    store.UserRepository
-	userLogs store.LogRepository
+	users      store.UserRepository
+	attributes store.UserAttributeRepository
+	userLogs   store.LogRepository
+	sessions   store.SessionRepository
+	verifier   EmailVerifier
+	cursors    userCursorCodec
+
+	jwtKeys          *jwtkeys.KeySet
+	impersonationTTL time.Duration
 }
 
-func NewAdminService(u store.UserRepository, userLogs store.LogRepository) AdminService {
-	return &adminService{users: u, userLogs: userLogs}
+func NewAdminService(u store.UserRepository, attrs store.UserAttributeRepository, userLogs store.LogRepository, sessions store.SessionRepository, v EmailVerifier, cfg AdminConfig) AdminService {
+	return &adminService{
+		users: u, attributes: attrs, userLogs: userLogs, sessions: sessions, verifier: v, cursors: userCursorCodec{secret: cfg.CursorSecret},
+		jwtKeys: cfg.JWTKeys, impersonationTTL: cfg.ImpersonationTTL,
+	}
 }
 
-func (svc *adminService) ListUsers(ctx context.Context, limit int, cursor string) ([]model.User, error) {
-	return svc.users.List(ctx, limit, cursor)
+func (svc *adminService) ListUsers(ctx context.Context, opts ListUsersOptions) (*UserPage, error) {
+	if err := checkUserFilter(opts.Status, &opts.SortBy); err != nil {
+		return nil, err
+	}
+
+	if opts.Limit < 1 || opts.Limit > MaxListUsersLimit {
+		return nil, errors.WithInvalid(errors.Errorf("Limit must be between 1 and %d", MaxListUsersLimit), "")
+	}
+
+	q := store.UserListQuery{
+		Search:      opts.Search,
+		Status:      opts.Status,
+		CreatedFrom: opts.CreatedFrom,
+		CreatedTo:   opts.CreatedTo,
+		SortBy:      opts.SortBy,
+		Desc:        opts.Desc,
+		GroupID:     opts.GroupID,
+		// one more than asked tells whether there is another page
+		Limit: opts.Limit + 1,
+	}
+	if opts.GroupID != "" {
+		if err := checkGroupID(opts.GroupID); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Attributes) > 0 {
+		schema, err := svc.attributes.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if q.Attributes, err = attributeFilter(schema, opts.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	// a previous page is read backwards from the cursor, then flipped
+	backwards := false
+	if opts.Cursor != "" {
+		cur, err := svc.cursors.decode(opts.Cursor, opts.SortBy, opts.Desc)
+		if err != nil {
+			return nil, err
+		}
+		backwards = cur.Prev
+		q.After = cur.key()
+		q.Desc = opts.Desc != backwards
+	}
+
+	users, err := svc.users.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(users) > opts.Limit
+	if more {
+		users = users[:opts.Limit]
+	}
+	hasNext, hasPrev := more, opts.Cursor != ""
+	if backwards {
+		slices.Reverse(users)
+		hasNext, hasPrev = true, more
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > 0 {
+		cursor := func(u *model.User, prev bool) string {
+			key := store.UserListKeyOf(u, opts.SortBy)
+			return svc.cursors.encode(userCursor{SortBy: opts.SortBy, Desc: opts.Desc, Prev: prev, Value: key.Value, ID: key.ID})
+		}
+		if hasNext {
+			page.NextCursor = cursor(&users[len(users)-1], false)
+		}
+		if hasPrev {
+			page.PrevCursor = cursor(&users[0], true)
+		}
+	}
+
+	if opts.WithTotal {
+		total, err := svc.users.Count(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// checkUserFilter validates the filters shared by the user list and exports,
+// defaulting the sort to the creation time.
+func checkUserFilter(status string, sortBy *string) error {
+	if status != "" && !model.IsValidStatus(status) {
+		return errors.WithInvalid(errors.Errorf("Invalid status %q", status), "")
+	}
+	if *sortBy == "" {
+		*sortBy = store.UserSortCreatedAt
+	}
+	if !store.IsValidUserSort(*sortBy) {
+		return errors.WithInvalid(errors.Errorf("Invalid sort %q", *sortBy), "")
+	}
+	return nil
 }
 
 func (svc *adminService) ListUserLogs(ctx context.Context, limit int, cursor string) ([]model.UserLogs, string, error) {
 	return svc.userLogs.List(ctx, limit, cursor)
 }
 
-func (svc *adminService) UpdateUser(ctx context.Context, adminID, userID, email, name string) (*model.User, error) {
-	return svc.users.UpdateUser(ctx, userID, email, name)
+func (svc *adminService) GetUser(ctx context.Context, userID string) (*model.User, error) {
+	return svc.users.FindByID(ctx, userID)
 }
 
-func (svc *adminService) DeleteUser(ctx context.Context, adminID, userID string) error {
-	if adminID == userID {
+func (svc *adminService) PatchUser(ctx context.Context, actor model.Actor, userID string, patch UserPatch, version int) (*model.User, map[string][2]string, error) {
+	before, err := svc.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == 0 {
+		version = before.Version
+	}
+	if before.Version != version {
+		return before, nil, errors.WithConflict(errors.New("User was changed by someone else"), "")
+	}
+
+	changes := map[string][2]string{}
+	if patch.Email != nil && *patch.Email != before.Email {
+		changes["email"] = [2]string{before.Email, *patch.Email}
+	}
+	if patch.Name != nil && *patch.Name != before.Name.String {
+		changes["name"] = [2]string{before.Name.String, *patch.Name}
+	}
+	var attrs model.Attributes
+	if patch.Attributes != nil {
+		schema, err := svc.attributes.List(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		attrs, err = mergeAttributes(schema, before.Attributes, patch.Attributes, changes)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(changes) == 0 {
+		return before, changes, nil
+	}
+	if err := svc.checkOwnUser(ctx, userID); err != nil {
+		return nil, nil, err
+	}
+
+	u, err := svc.users.PatchUser(ctx, userID, patch.Email, patch.Name, attrs, version)
+	if err != nil {
+		return u, nil, err
+	}
+
+	if u.Email != before.Email {
+		if err := svc.verifier.Send(ctx, u); err != nil {
+			return nil, nil, err
+		}
+	}
+	return u, changes, nil
+}
+
+// checkOwnUser refuses changes to the users row of a user who also belongs to
+// other organizations. The row is shared by all of them, and an admin of one
+// could otherwise take over the account elsewhere, e.g. by pointing its email
+// at a mailbox of theirs and resetting the password. Roles and statuses are
+// held per organization and stay open to every admin.
+func (svc *adminService) checkOwnUser(ctx context.Context, userID string) error {
+	shared, err := svc.users.InOtherOrgs(ctx, []string{userID})
+	if err != nil {
+		return err
+	}
+	if shared[userID] {
+		return errors.WithForbidden(errors.New("User belongs to other organizations, only their role and status can be changed"), "")
+	}
+	return nil
+}
+
+func (svc *adminService) DeleteUser(ctx context.Context, actor model.Actor, userID string) error {
+	if actor.IsUser(userID) {
 		return errors.WithInvalid(errors.New("Could not delete yourself"), "")
 	}
-	return svc.users.DeleteUser(ctx, userID)
+
+	u, err := svc.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.Status == model.StatusDeleted {
+		return errors.WithInvalid(errors.New("User already deleted"), "")
+	}
+
+	return svc.deactivate(ctx, userID, model.StatusDeleted)
+}
+
+func (svc *adminService) SuspendUser(ctx context.Context, actor model.Actor, userID string) (*model.User, error) {
+	if actor.IsUser(userID) {
+		return nil, errors.WithInvalid(errors.New("Could not suspend yourself"), "")
+	}
+
+	u, err := svc.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Active() {
+		return nil, errors.WithInvalid(errors.Errorf("Could not suspend a %s user", u.Status), "")
+	}
+
+	if err := svc.deactivate(ctx, userID, model.StatusSuspended); err != nil {
+		return nil, err
+	}
+	return svc.users.FindByID(ctx, userID)
+}
+
+// deactivate moves the user out of the active status in the organization and
+// signs out their sessions acting there. Sessions are revoked through the
+// repository so cached ones are dropped and the user's tokens stop working
+// right away.
+func (svc *adminService) deactivate(ctx context.Context, userID, status string) error {
+	if _, err := svc.users.SetStatus(ctx, userID, status); err != nil {
+		return err
+	}
+	return svc.sessions.RevokeInOrg(ctx, userID, tenant.OrgID(ctx))
+}
+
+func (svc *adminService) RestoreUser(ctx context.Context, actor model.Actor, userID string) (*model.User, string, error) {
+	u, err := svc.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Active() {
+		return nil, "", errors.WithInvalid(errors.New("User is already active"), "")
+	}
+
+	from := u.Status
+	u, err = svc.users.SetStatus(ctx, userID, model.StatusActive)
+	return u, from, err
+}
+
+func (svc *adminService) PurgeDeletedUsers(ctx context.Context, before time.Time) ([]model.Membership, error) {
+	return svc.users.PurgeDeleted(ctx, before)
+}
+
+func (svc *adminService) UpdateUserRole(ctx context.Context, actor model.Actor, userID, role string) (*model.User, string, error) {
+	if actor.IsUser(userID) {
+		return nil, "", errors.WithInvalid(errors.New("Could not change your own role"), "")
+	}
+	if !model.IsValidRole(role) {
+		return nil, "", errors.WithInvalid(errors.Errorf("Invalid role %q", role), "")
+	}
+
+	u, err := svc.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	from := u.Role
+	u, err = svc.users.UpdateRole(ctx, userID, role)
+	return u, from, err
+}
+
+func (svc *adminService) ResendVerification(ctx context.Context, actor model.Actor, userID string) error {
+	u, err := svc.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return svc.verifier.Send(ctx, u)
+}
+
+// EnsureAdmin creates a verified admin of the default organization with the
+// given email unless the account exists, so a fresh deployment has someone
+// able to manage roles and create organizations. An existing account is never
+// promoted, or anyone signing up with the email first would become admin: it
+// fails with an invalid error when that account is not an admin already.
+func EnsureAdmin(ctx context.Context, users store.UserRepository, orgs store.OrganizationRepository, email, password string) error {
+	u, err := users.FindByEmail(ctx, email)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if u != nil {
+		m, err := orgs.FindMembership(ctx, model.DefaultOrgID, u.ID)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if m == nil || m.Role != model.RoleAdmin {
+			return errors.WithInvalid(errors.Errorf("%s is taken by an account that is not an admin", email), "")
+		}
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	id, err := users.Create(ctx, email, string(hash))
+	if err != nil {
+		return err
+	}
+	if err := users.MarkEmailVerified(ctx, id); err != nil {
+		return err
+	}
+
+	_, err = orgs.AddMember(ctx, model.DefaultOrgID, id, model.RoleAdmin)
+	return err
 }
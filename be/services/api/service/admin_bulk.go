@@ -0,0 +1,238 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxBulkUsers caps how many users one bulk operation may change. A filter
+// matching more fails rather than changing only some of them.
+const MaxBulkUsers = 500
+
+// BulkUserOperation changes many users at once.
+type BulkUserOperation struct {
+	// IDs lists the users to change. When empty, Filter selects them.
+	IDs []string
+	// Filter uses the search, status, creation bounds and group of the user
+	// list.
+	Filter *ListUsersOptions
+
+	// Status is active, suspended or deleted. Empty fields are left as is.
+	Status string
+	Name   string
+	Role   string
+
+	// DryRun reports what would change without writing anything.
+	DryRun bool
+}
+
+// BulkUserChange is what a bulk operation did, or would do, to one user.
+type BulkUserChange struct {
+	UserID string
+	Email  string
+	// Changes holds the old and new value of each changed field.
+	Changes map[string][2]string
+	// Skipped is why the user was left unchanged.
+	Skipped string
+}
+
+type BulkUserReport struct {
+	DryRun bool
+	// Users has an entry per selected user and per unknown id.
+	Users   []BulkUserChange
+	Matched int
+	Changed int
+}
+
+func (svc *adminService) BulkUpdateUsers(ctx context.Context, actor model.Actor, op BulkUserOperation) (*BulkUserReport, error) {
+	sel, err := checkBulkUserOperation(&op)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BulkUserReport{DryRun: op.DryRun}
+	plan := func(users []model.User) ([]model.User, error) {
+		report.Users, report.Matched, report.Changed = nil, len(users), 0
+		updated := []model.User{}
+		now := time.Now().UTC()
+
+		// names are only changed for users of this organization alone, see
+		// checkOwnUser
+		shared := map[string]bool{}
+		if op.Name != "" {
+			ids := make([]string, 0, len(users))
+			for _, u := range users {
+				ids = append(ids, u.ID)
+			}
+			var err error
+			if shared, err = svc.users.InOtherOrgs(ctx, ids); err != nil {
+				return nil, err
+			}
+		}
+
+		found := map[string]bool{}
+		for _, u := range users {
+			found[u.ID] = true
+			after, change := planUserChange(actor, u, op, shared[u.ID], now)
+			if change.Skipped == "" {
+				updated = append(updated, after)
+				report.Changed++
+			}
+			report.Users = append(report.Users, change)
+		}
+		for _, id := range op.IDs {
+			if !found[id] {
+				report.Users = append(report.Users, BulkUserChange{UserID: id, Skipped: "User not found"})
+			}
+		}
+		return updated, nil
+	}
+
+	if op.DryRun {
+		users, err := svc.users.Select(ctx, sel, MaxBulkUsers)
+		if err != nil {
+			return nil, err
+		}
+		_, err = plan(users)
+		return report, err
+	}
+
+	updated, err := svc.users.BulkUpdate(ctx, sel, MaxBulkUsers, plan)
+	if err != nil {
+		return nil, err
+	}
+	// signed-in users that were suspended or deleted are signed out of the
+	// organization, as by deactivate
+	for _, u := range updated {
+		if !u.Active() {
+			if err := svc.sessions.RevokeInOrg(ctx, u.ID, tenant.OrgID(ctx)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// checkBulkUserOperation validates op, drops duplicate ids and returns the
+// users it selects.
+func checkBulkUserOperation(op *BulkUserOperation) (store.UserSelection, error) {
+	sel := store.UserSelection{}
+	if (len(op.IDs) == 0) == (op.Filter == nil) {
+		return sel, errors.WithInvalid(errors.New("Give either ids or a filter"), "")
+	}
+	if len(op.IDs) > MaxBulkUsers {
+		return sel, errors.WithInvalid(errors.Errorf("At most %d ids are allowed", MaxBulkUsers), "")
+	}
+	if op.Status == "" && op.Name == "" && op.Role == "" {
+		return sel, errors.WithInvalid(errors.New("Nothing to change"), "")
+	}
+	if op.Status != "" && !model.IsValidStatus(op.Status) {
+		return sel, errors.WithInvalid(errors.Errorf("Invalid status %q", op.Status), "")
+	}
+	if op.Role != "" && !model.IsValidRole(op.Role) {
+		return sel, errors.WithInvalid(errors.Errorf("Invalid role %q", op.Role), "")
+	}
+	if len(op.Name) > model.MaxNameLength {
+		return sel, errors.WithInvalid(errors.Errorf("Name longer than %d characters", model.MaxNameLength), "")
+	}
+
+	if op.Filter != nil {
+		f := op.Filter
+		if err := checkUserFilter(f.Status, &f.SortBy); err != nil {
+			return sel, err
+		}
+		if f.GroupID != "" {
+			if err := checkGroupID(f.GroupID); err != nil {
+				return sel, err
+			}
+		}
+		sel.Filter = store.UserListQuery{
+			Search: f.Search, Status: f.Status, CreatedFrom: f.CreatedFrom, CreatedTo: f.CreatedTo, GroupID: f.GroupID,
+		}
+		return sel, nil
+	}
+
+	for _, id := range op.IDs {
+		if err := uuid.Validate(id); err != nil {
+			return sel, errors.WithInvalid(errors.Errorf("Invalid id %q", id), "")
+		}
+	}
+	slices.Sort(op.IDs)
+	op.IDs = slices.Compact(op.IDs)
+	sel.IDs = op.IDs
+	return sel, nil
+}
+
+// planUserChange applies op to u under the rules of the single-user admin
+// endpoints, shared telling whether u also belongs to other organizations. A
+// user any rule refuses is skipped as a whole.
+//
+// The status moves as DeleteUser, SuspendUser and RestoreUser do, except that
+// deleted users are left alone: they are restored one at a time, and only
+// restored users may be changed.
+func planUserChange(actor model.Actor, u model.User, op BulkUserOperation, shared bool, now time.Time) (model.User, BulkUserChange) {
+	change := BulkUserChange{UserID: u.ID, Email: u.Email, Changes: map[string][2]string{}}
+	skip := func(reason string) (model.User, BulkUserChange) {
+		change.Changes, change.Skipped = nil, reason
+		return u, change
+	}
+
+	if u.Status == model.StatusDeleted {
+		if op.Status == model.StatusDeleted {
+			return skip("User already deleted")
+		}
+		return skip("Could not change a deleted user, restore it first")
+	}
+
+	after := u
+	if op.Status != "" && op.Status != u.Status {
+		switch {
+		case op.Status == model.StatusDeleted && actor.IsUser(u.ID):
+			return skip("Could not delete yourself")
+		case op.Status == model.StatusSuspended && actor.IsUser(u.ID):
+			return skip("Could not suspend yourself")
+		case op.Status == model.StatusSuspended && !u.Active():
+			return skip("Could not suspend a " + u.Status + " user")
+		}
+
+		after.Status = op.Status
+		after.SuspendedAt, after.DeletedAt = sql.NullTime{}, sql.NullTime{}
+		switch op.Status {
+		case model.StatusSuspended:
+			after.SuspendedAt = sql.NullTime{Time: now, Valid: true}
+		case model.StatusDeleted:
+			after.DeletedAt = sql.NullTime{Time: now, Valid: true}
+		}
+		change.Changes["status"] = [2]string{u.Status, op.Status}
+	}
+
+	if op.Role != "" && op.Role != u.Role {
+		if actor.IsUser(u.ID) {
+			return skip("Could not change your own role")
+		}
+		after.Role = op.Role
+		change.Changes["role"] = [2]string{u.Role, op.Role}
+	}
+
+	if op.Name != "" && op.Name != u.Name.String {
+		if shared {
+			return skip("Could not rename a user of other organizations")
+		}
+		after.Name = sql.NullString{String: op.Name, Valid: true}
+		change.Changes["name"] = [2]string{u.Name.String, op.Name}
+	}
+
+	if len(change.Changes) == 0 {
+		return skip("Nothing to change")
+	}
+	after.Version++
+	return after, change
+}
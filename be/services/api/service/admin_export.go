@@ -0,0 +1,103 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/events"
+	"be/pkg/export"
+	"be/pkg/model"
+	"context"
+)
+
+// AdminExportService dumps users and user logs for compliance. Exports are
+// either streamed straight to the caller or built in the background by the
+// worker and downloaded later.
+type AdminExportService interface {
+	StreamUsers(ctx context.Context, actor model.Actor, format string, filter model.UserFilter, fn func(*model.User) error) error
+	StreamUserLogs(ctx context.Context, actor model.Actor, format string, fn func(*model.UserLogs) error) error
+	// Start records a background export of the given kind and queues it.
+	Start(ctx context.Context, actor model.Actor, kind, format string, filter model.UserFilter) (*model.AdminExport, error)
+	// Get returns an export of the given kind, with its file when withFile.
+	Get(ctx context.Context, kind, id string, withFile bool) (*model.AdminExport, error)
+}
+
+type adminExportService struct {
+	users    store.UserRepository
+	userLogs store.LogRepository
+	exports  store.AdminExportRepository
+	queue    store.AdminExportQueue
+}
+
+func NewAdminExportService(u store.UserRepository, l store.LogRepository, e store.AdminExportRepository, q store.AdminExportQueue) AdminExportService {
+	return &adminExportService{users: u, userLogs: l, exports: e, queue: q}
+}
+
+func (s *adminExportService) StreamUsers(ctx context.Context, actor model.Actor, format string, filter model.UserFilter, fn func(*model.User) error) error {
+	if err := checkExport(model.AdminExportUsers, format, &filter); err != nil {
+		return err
+	}
+
+	return s.users.Stream(ctx, store.UserListQuery{
+		Search:      filter.Search,
+		Status:      filter.Status,
+		CreatedFrom: filter.CreatedFrom,
+		CreatedTo:   filter.CreatedTo,
+		SortBy:      filter.SortBy,
+		Desc:        filter.Desc,
+	}, fn)
+}
+
+func (s *adminExportService) StreamUserLogs(ctx context.Context, actor model.Actor, format string, fn func(*model.UserLogs) error) error {
+	if err := checkExport(model.AdminExportUserLogs, format, nil); err != nil {
+		return err
+	}
+
+	return s.userLogs.Stream(ctx, fn)
+}
+
+func (s *adminExportService) Start(ctx context.Context, actor model.Actor, kind, format string, filter model.UserFilter) (*model.AdminExport, error) {
+	if kind == model.AdminExportUserLogs {
+		filter = model.UserFilter{}
+	}
+	if err := checkExport(kind, format, &filter); err != nil {
+		return nil, err
+	}
+
+	e, err := s.exports.Create(ctx, kind, format, actor.String(), filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.queue.EnqueueAdminExport(ctx, events.AdminExportRequested{ExportID: e.ID}); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *adminExportService) Get(ctx context.Context, kind, id string, withFile bool) (*model.AdminExport, error) {
+	find := s.exports.Find
+	if withFile {
+		find = s.exports.FindFile
+	}
+
+	e, err := find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// an export is only reachable under the endpoint of its kind, which is
+	// what the caller's access was checked against
+	if e.Kind != kind {
+		return nil, errors.WithNotFound(errors.New("Export not found"), "")
+	}
+	return e, nil
+}
+
+// checkExport validates the format and, for user exports, the filter.
+func checkExport(kind, format string, filter *model.UserFilter) error {
+	if !export.IsValidFormat(format) {
+		return errors.WithInvalid(errors.Errorf("Unsupported export format %q", format), "")
+	}
+	if kind == model.AdminExportUsers {
+		return checkUserFilter(filter.Status, &filter.SortBy)
+	}
+	return nil
+}
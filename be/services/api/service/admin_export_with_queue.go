@@ -0,0 +1,73 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type adminExportServiceWithQueue struct {
+	svc          AdminExportService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewAdminExportServiceWithQueue(svc AdminExportService, userLogQueue store.UserLogsQueue) AdminExportService {
+	return &adminExportServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *adminExportServiceWithQueue) StreamUsers(ctx context.Context, actor model.Actor, format string, filter model.UserFilter, fn func(*model.User) error) error {
+	n := 0
+	err := s.svc.StreamUsers(ctx, actor, format, filter, func(u *model.User) error {
+		n++
+		return fn(u)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.logExport(ctx, actor, model.AdminExportUsers, fmt.Sprintf("Admin %s exported %d users as %s", actor, n, format))
+}
+
+func (s *adminExportServiceWithQueue) StreamUserLogs(ctx context.Context, actor model.Actor, format string, fn func(*model.UserLogs) error) error {
+	n := 0
+	err := s.svc.StreamUserLogs(ctx, actor, format, func(l *model.UserLogs) error {
+		n++
+		return fn(l)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.logExport(ctx, actor, model.AdminExportUserLogs, fmt.Sprintf("Admin %s exported %d user logs as %s", actor, n, format))
+}
+
+func (s *adminExportServiceWithQueue) Start(ctx context.Context, actor model.Actor, kind, format string, filter model.UserFilter) (*model.AdminExport, error) {
+	e, err := s.svc.Start(ctx, actor, kind, format, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, s.logExport(ctx, actor, kind, fmt.Sprintf("Admin %s started %s export %s as %s", actor, kind, e.ID, format))
+}
+
+func (s *adminExportServiceWithQueue) Get(ctx context.Context, kind, id string, withFile bool) (*model.AdminExport, error) {
+	return s.svc.Get(ctx, kind, id, withFile)
+}
+
+func (s *adminExportServiceWithQueue) logExport(ctx context.Context, actor model.Actor, kind, details string) error {
+	eventType := "admin.exportUsers"
+	if kind == model.AdminExportUserLogs {
+		eventType = "admin.exportUserLogs"
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: eventType,
+		EventTime: time.Now().UTC(),
+		Details:   details,
+		Actor:     actor.String(),
+	})
+}
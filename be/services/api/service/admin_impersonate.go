@@ -0,0 +1,62 @@
+package service
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Impersonate returns an access token to act as the user in the organization
+// the admin acts in, for support staff to see the app as the user sees it.
+// The token names the admin in its "act" claim and is tied to the admin's
+// session, so signing out ends the impersonation too. It cannot be refreshed.
+//
+// Admins cannot be impersonated, so impersonating never grants more than the
+// admin has already.
+func (svc *adminService) Impersonate(ctx context.Context, actor model.Actor, sessionID, userID string) (*Tokens, error) {
+	if actor.Kind != model.ActorUser || sessionID == "" {
+		return nil, errors.WithInvalid(errors.New("Only signed-in users can impersonate"), "")
+	}
+	if actor.IsUser(userID) {
+		return nil, errors.WithInvalid(errors.New("Cannot impersonate yourself"), "")
+	}
+	if uuid.Validate(userID) != nil {
+		return nil, errors.WithNotFound(errors.New("User not found"), "")
+	}
+
+	u, err := svc.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Active() {
+		return nil, errors.WithInvalid(errors.New("User is not active"), "")
+	}
+	if u.Role == model.RoleAdmin {
+		return nil, errors.WithInvalid(errors.New("Cannot impersonate an admin"), "")
+	}
+
+	orgID := tenant.OrgID(ctx)
+	ss, err := svc.jwtKeys.Sign(jwt.MapClaims{
+		"user_id":        u.ID,
+		"org":            orgID,
+		"role":           u.Role,
+		"sid":            sessionID,
+		"email_verified": u.EmailVerified(),
+		"act":            map[string]any{"sub": actor.ID},
+		"exp":            time.Now().Add(svc.impersonationTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken: ss,
+		ExpiresIn:   int(svc.impersonationTTL.Seconds()),
+		OrgID:       orgID,
+	}, nil
+}
@@ -6,6 +6,9 @@ import (
 	"be/pkg/model"
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -18,34 +21,36 @@ func NewAdminServiceWithQueue(adminSvc AdminService, userLogQueue store.UserLogs
 	return &adminServiceWithQueue{adminSvc: adminSvc, userLogQueue: userLogQueue}
 }
 
-func (svc *adminServiceWithQueue) ListUsers(ctx context.Context, limit int, cursor string) ([]model.User, error) {
-	return svc.adminSvc.ListUsers(ctx, limit, cursor)
+func (svc *adminServiceWithQueue) ListUsers(ctx context.Context, opts ListUsersOptions) (*UserPage, error) {
+	return svc.adminSvc.ListUsers(ctx, opts)
 }
 
 func (svc *adminServiceWithQueue) ListUserLogs(ctx context.Context, limit int, cursor string) ([]model.UserLogs, string, error) {
 	return svc.adminSvc.ListUserLogs(ctx, limit, cursor)
 }
 
-func (svc *adminServiceWithQueue) UpdateUser(ctx context.Context, adminID, userID, email, name string) (*model.User, error) {
-	u, err := svc.adminSvc.UpdateUser(ctx, adminID, userID, email, name)
-	if err != nil {
-		return nil, err
+func (svc *adminServiceWithQueue) GetUser(ctx context.Context, userID string) (*model.User, error) {
+	return svc.adminSvc.GetUser(ctx, userID)
+}
+
+func (svc *adminServiceWithQueue) PatchUser(ctx context.Context, actor model.Actor, userID string, patch UserPatch, version int) (*model.User, map[string][2]string, error) {
+	u, changes, err := svc.adminSvc.PatchUser(ctx, actor, userID, patch, version)
+	if err != nil || len(changes) == 0 {
+		return u, changes, err
 	}
 
 	err = svc.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
 		UserID:    u.ID,
 		EventType: "admin.updateUser",
 		EventTime: time.Now().UTC(),
-		Details: fmt.Sprintf(
-			"Admin %s updated user.\nOld values: email=%s, name=%s; New values: email=%s, name=%s",
-			adminID, email, name, u.Email, u.Name.String,
-		),
+		Details:   fmt.Sprintf("Admin %s updated user %s: %s", actor, u.ID, describeChanges(changes)),
+		Actor:     actor.String(),
 	})
-	return u, err
+	return u, changes, err
 }
 
-func (svc *adminServiceWithQueue) DeleteUser(ctx context.Context, adminID, userID string) error {
-	err := svc.adminSvc.DeleteUser(ctx, adminID, userID)
+func (svc *adminServiceWithQueue) DeleteUser(ctx context.Context, actor model.Actor, userID string) error {
+	err := svc.adminSvc.DeleteUser(ctx, actor, userID)
 	if err != nil {
 		return err
 	}
@@ -56,8 +61,175 @@ func (svc *adminServiceWithQueue) DeleteUser(ctx context.Context, adminID, userI
 		EventTime: time.Now().UTC(),
 		Details: fmt.Sprintf(
 			"Admin %s deleted user %s",
-			adminID, userID,
+			actor, userID,
 		),
+		Actor: actor.String(),
 	})
 	return err
 }
+
+func (svc *adminServiceWithQueue) SuspendUser(ctx context.Context, actor model.Actor, userID string) (*model.User, error) {
+	u, err := svc.adminSvc.SuspendUser(ctx, actor, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "admin.suspendUser",
+		EventTime: time.Now().UTC(),
+		Details: fmt.Sprintf(
+			"Admin %s suspended user %s",
+			actor, userID,
+		),
+		Actor: actor.String(),
+	})
+	return u, err
+}
+
+func (svc *adminServiceWithQueue) RestoreUser(ctx context.Context, actor model.Actor, userID string) (*model.User, string, error) {
+	u, from, err := svc.adminSvc.RestoreUser(ctx, actor, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// lifting a suspension and undoing a deletion are told apart
+	eventType := "admin.unsuspendUser"
+	if from == model.StatusDeleted {
+		eventType = "admin.restoreUser"
+	}
+
+	err = svc.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: eventType,
+		EventTime: time.Now().UTC(),
+		Details: fmt.Sprintf(
+			"Admin %s restored user %s from %s",
+			actor, userID, from,
+		),
+		Actor: actor.String(),
+	})
+	return u, from, err
+}
+
+func (svc *adminServiceWithQueue) PurgeDeletedUsers(ctx context.Context, before time.Time) ([]model.Membership, error) {
+	memberships, err := svc.adminSvc.PurgeDeletedUsers(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	// logged in the organization the user was purged from
+	actor := model.SystemActor("purge")
+	for _, m := range memberships {
+		err := svc.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+			UserID:    m.UserID,
+			OrgID:     m.OrgID,
+			EventType: "admin.purgeUser",
+			EventTime: time.Now().UTC(),
+			Details: fmt.Sprintf(
+				"User %s deleted before %s was purged",
+				m.UserID, before.Format(time.RFC3339),
+			),
+			Actor: actor.String(),
+		})
+		if err != nil {
+			return memberships, err
+		}
+	}
+	return memberships, nil
+}
+
+func (svc *adminServiceWithQueue) UpdateUserRole(ctx context.Context, actor model.Actor, userID, role string) (*model.User, string, error) {
+	u, from, err := svc.adminSvc.UpdateUserRole(ctx, actor, userID, role)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = svc.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    u.ID,
+		EventType: "admin.updateUserRole",
+		EventTime: time.Now().UTC(),
+		Details: fmt.Sprintf(
+			"Admin %s changed role of user %s from %s to %s",
+			actor, u.ID, from, u.Role,
+		),
+		Actor: actor.String(),
+	})
+	return u, from, err
+}
+
+func (svc *adminServiceWithQueue) ResendVerification(ctx context.Context, actor model.Actor, userID string) error {
+	err := svc.adminSvc.ResendVerification(ctx, actor, userID)
+	if err != nil {
+		return err
+	}
+
+	return svc.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "admin.resendVerification",
+		EventTime: time.Now().UTC(),
+		Details: fmt.Sprintf(
+			"Admin %s resent verification email to user %s",
+			actor, userID,
+		),
+		Actor: actor.String(),
+	})
+}
+
+// BulkUpdateUsers logs one event per changed user, sent in batches.
+func (svc *adminServiceWithQueue) BulkUpdateUsers(ctx context.Context, actor model.Actor, op BulkUserOperation) (*BulkUserReport, error) {
+	report, err := svc.adminSvc.BulkUpdateUsers(ctx, actor, op)
+	if err != nil || report.DryRun {
+		return report, err
+	}
+
+	eventType := "admin.bulkUpdateUser"
+	if op.Status == model.StatusDeleted {
+		eventType = "admin.bulkDeleteUser"
+	}
+
+	now := time.Now().UTC()
+	evs := make([]events.UserLogsEvent, 0, report.Changed)
+	for _, c := range report.Users {
+		if c.Skipped != "" {
+			continue
+		}
+
+		evs = append(evs, events.UserLogsEvent{
+			UserID:    c.UserID,
+			EventType: eventType,
+			EventTime: now,
+			Details:   fmt.Sprintf("Admin %s bulk updated user %s: %s", actor, c.UserID, describeChanges(c.Changes)),
+			Actor:     actor.String(),
+		})
+	}
+
+	return report, svc.userLogQueue.EnqueueBatch(ctx, evs)
+}
+
+// describeChanges lists the old and new value of each changed field, quoted
+// so that a cleared value reads as "".
+func describeChanges(changes map[string][2]string) string {
+	fields := slices.Sorted(maps.Keys(changes))
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, fmt.Sprintf("%s=%q->%q", f, changes[f][0], changes[f][1]))
+	}
+	return strings.Join(out, ", ")
+}
+
+func (svc *adminServiceWithQueue) Impersonate(ctx context.Context, actor model.Actor, sessionID, userID string) (*Tokens, error) {
+	tokens, err := svc.adminSvc.Impersonate(ctx, actor, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "admin.impersonateUser",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s started impersonating user %s for %ds", actor, userID, tokens.ExpiresIn),
+		Actor:     actor.String(),
+	})
+	return tokens, err
+}
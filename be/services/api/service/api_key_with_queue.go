@@ -0,0 +1,59 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type apiKeyServiceWithQueue struct {
+	svc          APIKeyService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewAPIKeyServiceWithQueue(svc APIKeyService, userLogQueue store.UserLogsQueue) APIKeyService {
+	return &apiKeyServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *apiKeyServiceWithQueue) Create(ctx context.Context, actor model.Actor, name string, scopes []string, expiresAt *time.Time) (*model.APIKey, string, error) {
+	k, key, err := s.svc.Create(ctx, actor, name, scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.createAPIKey",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s created API key %s (%s) with scopes %v", actor, k.ID, k.Name, k.Scopes),
+		Actor:     actor.String(),
+	})
+	return k, key, err
+}
+
+func (s *apiKeyServiceWithQueue) List(ctx context.Context) ([]model.APIKey, error) {
+	return s.svc.List(ctx)
+}
+
+func (s *apiKeyServiceWithQueue) Revoke(ctx context.Context, actor model.Actor, id string) (*model.APIKey, error) {
+	k, err := s.svc.Revoke(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.revokeAPIKey",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s revoked API key %s (%s)", actor, k.ID, k.Name),
+		Actor:     actor.String(),
+	})
+	return k, err
+}
+
+func (s *apiKeyServiceWithQueue) AuthenticateAPIKey(ctx context.Context, key string) (string, string, []string, error) {
+	return s.svc.AuthenticateAPIKey(ctx, key)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxGroupMembersAdded caps how many users one call adds to a group.
+const MaxGroupMembersAdded = MaxBulkUsers
+
+// GroupService manages the user groups of the organization the context acts
+// in. Listing the members of a group goes through the user list, filtered on
+// the group.
+type GroupService interface {
+	List(ctx context.Context) ([]model.Group, error)
+	Get(ctx context.Context, id string) (*model.Group, error)
+	// ListUserGroups returns the groups the user is in.
+	ListUserGroups(ctx context.Context, userID string) ([]model.Group, error)
+	Create(ctx context.Context, actor model.Actor, name, description string) (*model.Group, error)
+	Update(ctx context.Context, actor model.Actor, id, name, description string) (*model.Group, error)
+	Delete(ctx context.Context, actor model.Actor, id string) (*model.Group, error)
+	// AddMembers returns the ids of the users who were not in the group yet.
+	AddMembers(ctx context.Context, actor model.Actor, id string, userIDs []string) ([]string, error)
+	RemoveMember(ctx context.Context, actor model.Actor, id, userID string) error
+}
+
+type groupService struct {
+	groups store.GroupRepository
+}
+
+func NewGroupService(g store.GroupRepository) GroupService {
+	return &groupService{groups: g}
+}
+
+func (s *groupService) List(ctx context.Context) ([]model.Group, error) {
+	return s.groups.List(ctx)
+}
+
+func (s *groupService) Get(ctx context.Context, id string) (*model.Group, error) {
+	if err := checkGroupID(id); err != nil {
+		return nil, errors.WithNotFound(errors.New("Group not found"), "")
+	}
+	return s.groups.Find(ctx, id)
+}
+
+func (s *groupService) ListUserGroups(ctx context.Context, userID string) ([]model.Group, error) {
+	if uuid.Validate(userID) != nil {
+		return nil, errors.WithNotFound(errors.New("User not found"), "")
+	}
+	return s.groups.ListForUser(ctx, userID)
+}
+
+func (s *groupService) Create(ctx context.Context, actor model.Actor, name, description string) (*model.Group, error) {
+	name, err := checkGroupName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.groups.Create(ctx, name, strings.TrimSpace(description))
+}
+
+func (s *groupService) Update(ctx context.Context, actor model.Actor, id, name, description string) (*model.Group, error) {
+	if err := checkGroupID(id); err != nil {
+		return nil, errors.WithNotFound(errors.New("Group not found"), "")
+	}
+	name, err := checkGroupName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.groups.Update(ctx, id, name, strings.TrimSpace(description))
+}
+
+func (s *groupService) Delete(ctx context.Context, actor model.Actor, id string) (*model.Group, error) {
+	if err := checkGroupID(id); err != nil {
+		return nil, errors.WithNotFound(errors.New("Group not found"), "")
+	}
+	return s.groups.Delete(ctx, id)
+}
+
+func (s *groupService) AddMembers(ctx context.Context, actor model.Actor, id string, userIDs []string) ([]string, error) {
+	if err := checkGroupID(id); err != nil {
+		return nil, errors.WithNotFound(errors.New("Group not found"), "")
+	}
+	if len(userIDs) == 0 {
+		return nil, errors.WithInvalid(errors.New("Give the ids of the users to add"), "")
+	}
+	if len(userIDs) > MaxGroupMembersAdded {
+		return nil, errors.WithInvalid(errors.Errorf("At most %d ids are allowed", MaxGroupMembersAdded), "")
+	}
+	for _, userID := range userIDs {
+		if uuid.Validate(userID) != nil {
+			return nil, errors.WithInvalid(errors.Errorf("Invalid id %q", userID), "")
+		}
+	}
+
+	userIDs = slices.Clone(userIDs)
+	slices.Sort(userIDs)
+	return s.groups.AddMembers(ctx, id, slices.Compact(userIDs))
+}
+
+func (s *groupService) RemoveMember(ctx context.Context, actor model.Actor, id, userID string) error {
+	if checkGroupID(id) != nil || uuid.Validate(userID) != nil {
+		return errors.WithNotFound(errors.New("Member not found"), "")
+	}
+	return s.groups.RemoveMember(ctx, id, userID)
+}
+
+func checkGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > model.MaxGroupNameLength {
+		return "", errors.WithInvalid(errors.Errorf("Name must be 1 to %d characters", model.MaxGroupNameLength), "")
+	}
+	return name, nil
+}
+
+// checkGroupID refuses ids that cannot name a group, before they reach
+// Postgres as uuids.
+func checkGroupID(id string) error {
+	if uuid.Validate(id) != nil {
+		return errors.WithInvalid(errors.Errorf("Invalid group %q", id), "")
+	}
+	return nil
+}
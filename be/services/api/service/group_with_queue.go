@@ -0,0 +1,115 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type groupServiceWithQueue struct {
+	svc          GroupService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewGroupServiceWithQueue(svc GroupService, userLogQueue store.UserLogsQueue) GroupService {
+	return &groupServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *groupServiceWithQueue) List(ctx context.Context) ([]model.Group, error) {
+	return s.svc.List(ctx)
+}
+
+func (s *groupServiceWithQueue) Get(ctx context.Context, id string) (*model.Group, error) {
+	return s.svc.Get(ctx, id)
+}
+
+func (s *groupServiceWithQueue) ListUserGroups(ctx context.Context, userID string) ([]model.Group, error) {
+	return s.svc.ListUserGroups(ctx, userID)
+}
+
+func (s *groupServiceWithQueue) Create(ctx context.Context, actor model.Actor, name, description string) (*model.Group, error) {
+	g, err := s.svc.Create(ctx, actor, name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.createGroup",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s created group %s (%s)", actor, g.ID, g.Name),
+		Actor:     actor.String(),
+	})
+	return g, err
+}
+
+func (s *groupServiceWithQueue) Update(ctx context.Context, actor model.Actor, id, name, description string) (*model.Group, error) {
+	g, err := s.svc.Update(ctx, actor, id, name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.updateGroup",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s updated group %s: name=%q description=%q", actor, g.ID, g.Name, g.Description),
+		Actor:     actor.String(),
+	})
+	return g, err
+}
+
+func (s *groupServiceWithQueue) Delete(ctx context.Context, actor model.Actor, id string) (*model.Group, error) {
+	g, err := s.svc.Delete(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.deleteGroup",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s deleted group %s (%s) with %d members", actor, g.ID, g.Name, g.MemberCount),
+		Actor:     actor.String(),
+	})
+	return g, err
+}
+
+// AddMembers logs one admin.addGroupMember event per user added, in the
+// user's logs.
+func (s *groupServiceWithQueue) AddMembers(ctx context.Context, actor model.Actor, id string, userIDs []string) ([]string, error) {
+	added, err := s.svc.AddMembers(ctx, actor, id, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	evs := make([]events.UserLogsEvent, 0, len(added))
+	for _, userID := range added {
+		evs = append(evs, events.UserLogsEvent{
+			UserID:    userID,
+			EventType: "admin.addGroupMember",
+			EventTime: now,
+			Details:   fmt.Sprintf("Admin %s added user %s to group %s", actor, userID, id),
+			Actor:     actor.String(),
+		})
+	}
+	return added, s.userLogQueue.EnqueueBatch(ctx, evs)
+}
+
+func (s *groupServiceWithQueue) RemoveMember(ctx context.Context, actor model.Actor, id, userID string) error {
+	if err := s.svc.RemoveMember(ctx, actor, id, userID); err != nil {
+		return err
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "admin.removeGroupMember",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s removed user %s from group %s", actor, userID, id),
+		Actor:     actor.String(),
+	})
+}
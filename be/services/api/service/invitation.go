@@ -0,0 +1,150 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/mail"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sign-up policies, see UserConfig.SignUpPolicy.
+const (
+	// SignUpOpen lets anyone sign up. An invitation token is still accepted.
+	SignUpOpen = "open"
+	// SignUpInvite only lets users sign up with an invitation.
+	SignUpInvite = "invite"
+)
+
+// InvitationService lets admins invite people to join the organization the
+// context acts in. Only invited users become members of an organization they
+// did not create.
+type InvitationService interface {
+	List(ctx context.Context) ([]model.Invitation, error)
+	// Create mails the invitation link to email: to sign up, or for existing
+	// users, to accept it once signed in. A nil expiresAt uses the configured
+	// TTL.
+	Create(ctx context.Context, actor model.Actor, email, role string, expiresAt *time.Time) (*model.Invitation, error)
+	// Accept makes the signed in user a member of the organization of the
+	// invitation sent to their email.
+	Accept(ctx context.Context, userID, token string) (*model.Invitation, error)
+	Revoke(ctx context.Context, actor model.Actor, id string) (*model.Invitation, error)
+	// ExpireInvitations marks the pending invitations that expired before now
+	// and returns them.
+	ExpireInvitations(ctx context.Context, now time.Time) ([]model.Invitation, error)
+}
+
+type InvitationConfig struct {
+	TTL time.Duration
+	// AppURL is the frontend base URL used to build the invitation link.
+	AppURL string
+}
+
+type invitationService struct {
+	invitations store.InvitationRepository
+	users       store.UserRepository
+	mailer      mail.Mailer
+	cfg         InvitationConfig
+}
+
+func NewInvitationService(i store.InvitationRepository, u store.UserRepository, m mail.Mailer, cfg InvitationConfig) InvitationService {
+	return &invitationService{invitations: i, users: u, mailer: m, cfg: cfg}
+}
+
+func (s *invitationService) List(ctx context.Context) ([]model.Invitation, error) {
+	return s.invitations.List(ctx, tenant.OrgID(ctx))
+}
+
+func (s *invitationService) Create(ctx context.Context, actor model.Actor, email, role string, expiresAt *time.Time) (*model.Invitation, error) {
+	if !model.IsValidEmail(email) {
+		return nil, errors.WithInvalid(errors.New("Invalid email format"), "")
+	}
+	if !model.IsValidRole(role) {
+		return nil, errors.WithInvalid(errors.New("Invalid role"), "")
+	}
+
+	now := time.Now().UTC()
+	expiry := now.Add(s.cfg.TTL)
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, errors.WithInvalid(errors.New("Expiry must be in the future"), "")
+		}
+		expiry = expiresAt.UTC()
+	}
+
+	_, err := s.users.FindByEmail(ctx, email)
+	if err == nil {
+		return nil, errors.WithInvalid(errors.New("User is already a member"), "")
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	// users of other organizations are hidden in the tenant, and accept the
+	// invitation once signed in instead of signing up again
+	_, err = s.users.FindByEmail(tenant.WithOrg(ctx, ""), email)
+	existing := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := s.invitations.Create(ctx, model.Invitation{
+		OrgID:     tenant.OrgID(ctx),
+		Email:     email,
+		Role:      role,
+		InvitedBy: actor.ID,
+		ExpiresAt: expiry,
+	}, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf(
+		"You have been invited to create an account. Sign up with this email address by opening the link below. It expires on %s.\n\n%s/signup?invitation=%s&email=%s",
+		inv.ExpiresAt.Format(time.RFC1123), s.cfg.AppURL, token, url.QueryEscape(email),
+	)
+	if existing {
+		body = fmt.Sprintf(
+			"You have been invited to join an organization. Sign in with this email address and open the link below to accept. It expires on %s.\n\n%s/invitations/accept?invitation=%s",
+			inv.ExpiresAt.Format(time.RFC1123), s.cfg.AppURL, token,
+		)
+	}
+	return inv, s.mailer.Send(ctx, mail.Message{To: email, Subject: "You are invited", Body: body})
+}
+
+func (s *invitationService) Accept(ctx context.Context, userID, token string) (*model.Invitation, error) {
+	if token == "" {
+		return nil, errors.WithInvalid(errors.New("Invalid or expired invitation"), "")
+	}
+
+	// the user is not a member of the organization they join yet, so it is
+	// done outside of the one they are signed in to
+	ctx = tenant.WithOrg(ctx, "")
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.invitations.Join(ctx, hashToken(token), u.ID, u.Email)
+}
+
+func (s *invitationService) Revoke(ctx context.Context, actor model.Actor, id string) (*model.Invitation, error) {
+	if uuid.Validate(id) != nil {
+		return nil, errors.WithNotFound(errors.New("Invitation not found"), "")
+	}
+	return s.invitations.Revoke(ctx, tenant.OrgID(ctx), id)
+}
+
+func (s *invitationService) ExpireInvitations(ctx context.Context, now time.Time) ([]model.Invitation, error) {
+	return s.invitations.Expire(ctx, now)
+}
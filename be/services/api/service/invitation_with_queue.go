@@ -0,0 +1,98 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type invitationServiceWithQueue struct {
+	svc          InvitationService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewInvitationServiceWithQueue(svc InvitationService, userLogQueue store.UserLogsQueue) InvitationService {
+	return &invitationServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *invitationServiceWithQueue) List(ctx context.Context) ([]model.Invitation, error) {
+	return s.svc.List(ctx)
+}
+
+func (s *invitationServiceWithQueue) Create(ctx context.Context, actor model.Actor, email, role string, expiresAt *time.Time) (*model.Invitation, error) {
+	inv, err := s.svc.Create(ctx, actor, email, role, expiresAt)
+	if inv == nil {
+		return nil, err
+	}
+
+	// the invitation exists even when mailing it failed
+	if qErr := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.createInvitation",
+		EventTime: time.Now().UTC(),
+		Details: fmt.Sprintf("Admin %s invited %s as %s: invitation=%s expires_at=%s",
+			actor, inv.Email, inv.Role, inv.ID, inv.ExpiresAt.Format(time.RFC3339)),
+		Actor: actor.String(),
+	}); qErr != nil {
+		return nil, qErr
+	}
+	return inv, err
+}
+
+func (s *invitationServiceWithQueue) Accept(ctx context.Context, userID, token string) (*model.Invitation, error) {
+	inv, err := s.svc.Accept(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// logged in the organization joined, where the invitation was sent from
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		OrgID:     inv.OrgID,
+		EventType: "users.acceptInvitation",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User %s joined as %s: invitation=%s", inv.Email, inv.Role, inv.ID),
+		Actor:     model.UserActor(userID).String(),
+	})
+	return inv, err
+}
+
+func (s *invitationServiceWithQueue) Revoke(ctx context.Context, actor model.Actor, id string) (*model.Invitation, error) {
+	inv, err := s.svc.Revoke(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.revokeInvitation",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s revoked the invitation of %s: invitation=%s", actor, inv.Email, inv.ID),
+		Actor:     actor.String(),
+	})
+	return inv, err
+}
+
+func (s *invitationServiceWithQueue) ExpireInvitations(ctx context.Context, now time.Time) ([]model.Invitation, error) {
+	invitations, err := s.svc.ExpireInvitations(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// logged with whoever sent the invitation, in its organization
+	evts := make([]events.UserLogsEvent, 0, len(invitations))
+	for _, inv := range invitations {
+		evts = append(evts, events.UserLogsEvent{
+			UserID:    inv.InvitedBy,
+			OrgID:     inv.OrgID,
+			EventType: "admin.expireInvitation",
+			EventTime: time.Now().UTC(),
+			Details:   fmt.Sprintf("Invitation of %s expired: invitation=%s", inv.Email, inv.ID),
+			Actor:     model.SystemActor("expire").String(),
+		})
+	}
+	return invitations, s.userLogQueue.EnqueueBatch(ctx, evts)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/totp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount = 10
+
+	codeInvalidMFACode = "invalid_mfa_code"
+)
+
+type TOTPEnrollment struct {
+	Secret        string
+	URI           string
+	RecoveryCodes []string
+}
+
+// EnrollTOTP starts a TOTP enrollment. It only takes effect once confirmed
+// with a code from the authenticator app through ConfirmTOTP.
+func (s *userService) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(c)))
+	}
+
+	if err := s.mfa.Save(ctx, u.ID, secret, hashes); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(s.cfg.MFAIssuer, u.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (s *userService) ConfirmTOTP(ctx context.Context, userID, code string) error {
+	m, err := s.mfa.Find(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if m.Enabled() {
+		return errors.WithInvalid(errors.New("Two-factor authentication already enabled"), "")
+	}
+
+	step, ok := totp.ValidateStep(m.Secret, code, time.Now())
+	if !ok {
+		return errInvalidMFACode()
+	}
+	// the code confirming the enrollment cannot sign in afterwards
+	err = s.mfa.UseTOTPStep(ctx, userID, step)
+	if errors.IsNotFound(err) {
+		return errInvalidMFACode()
+	}
+	if err != nil {
+		return err
+	}
+	return s.mfa.Confirm(ctx, userID)
+}
+
+func (s *userService) DisableTOTP(ctx context.Context, userID, code string) error {
+	m, err := s.mfa.Find(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkMFACode(ctx, m, code); err != nil {
+		return err
+	}
+	return s.mfa.Delete(ctx, userID)
+}
+
+// SignInMFA completes a sign-in started by SignIn for a user with two-factor
+// authentication enabled. The challenge token is single use, so a wrong code
+// means signing in with the password again.
+func (s *userService) SignInMFA(ctx context.Context, mfaToken, code string) (*Tokens, string, error) {
+	userID, err := s.userTokens.Consume(ctx, model.TokenMFAChallenge, hashToken(mfaToken))
+	if errors.IsNotFound(err) {
+		return nil, "", errors.WithNotFound(errors.New("Invalid or expired MFA token"), "")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, err := s.mfa.Find(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.checkMFACode(ctx, m, code); err != nil {
+		return nil, userID, err
+	}
+
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tokens, err := s.startSession(ctx, u)
+	return tokens, u.ID, err
+}
+
+// checkMFACode accepts either a TOTP code not used before or one of the
+// unused recovery codes. Wrong codes count towards locking the user out of
+// the second factor, see signInGuard.failMFA.
+func (s *userService) checkMFACode(ctx context.Context, m *model.MFA, code string) error {
+	if err := s.guard.checkMFA(ctx, m.UserID); err != nil {
+		return err
+	}
+
+	var err error
+	if step, ok := totp.ValidateStep(m.Secret, code, time.Now()); ok {
+		err = s.mfa.UseTOTPStep(ctx, m.UserID, step)
+	} else {
+		err = s.mfa.UseRecoveryCode(ctx, m.UserID, hashToken(normalizeRecoveryCode(code)))
+	}
+	if errors.IsNotFound(err) {
+		return s.guard.failMFA(ctx, m.UserID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.guard.succeedMFA(ctx, m.UserID)
+}
+
+func (s *userService) newMFAChallenge(ctx context.Context, userID string) (*Tokens, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userTokens.Create(ctx, model.TokenMFAChallenge, userID, hash, time.Now().UTC().Add(s.cfg.MFAChallengeTTL))
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{MFAToken: token}, nil
+}
+
+func errInvalidMFACode() error {
+	return errors.WithInvalid(errors.New("Invalid two-factor code"), codeInvalidMFACode)
+}
+
+// newRecoveryCode returns a code formatted as xxxxx-xxxxx.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	c := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return c[:5] + "-" + c[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// unusablePassword is stored for users created through an identity provider.
+// It is not a bcrypt hash, so password sign-in always fails until they set one
+// through the password reset flow.
+const unusablePassword = "!"
+
+// OIDCLogin describes how an OIDC callback was resolved to a user.
+type OIDCLogin struct {
+	UserID   string
+	Provider string
+	Email    string
+	// Created is set when the user did not exist and was signed up.
+	Created bool
+	// Linked is set when the identity was attached to a user for the first time.
+	Linked bool
+}
+
+func (s *userService) OIDCProviders() []string {
+	return s.providers.Names()
+}
+
+// OIDCAuthURL starts an authorization code flow with PKCE and returns the
+// provider URL to redirect the user to.
+func (s *userService) OIDCAuthURL(ctx context.Context, provider string) (string, error) {
+	p, ok := s.providers.Get(provider)
+	if !ok {
+		return "", errors.WithNotFound(errors.New("Unknown OIDC provider"), "")
+	}
+
+	state, stateHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, _, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier := oauth2.GenerateVerifier()
+
+	err = s.identities.CreateAuthRequest(ctx, stateHash, provider, codeVerifier, nonce, time.Now().UTC().Add(s.cfg.OIDCStateTTL))
+	if err != nil {
+		return "", err
+	}
+
+	return p.AuthCodeURL(ctx, state, nonce, codeVerifier)
+}
+
+// OIDCSignIn handles the provider callback. The external identity is resolved
+// to a user by a previous link, or else by its verified email, signing the user
+// up when no account exists yet.
+func (s *userService) OIDCSignIn(ctx context.Context, provider, code, state string) (*Tokens, *OIDCLogin, error) {
+	p, ok := s.providers.Get(provider)
+	if !ok {
+		return nil, nil, errors.WithNotFound(errors.New("Unknown OIDC provider"), "")
+	}
+
+	codeVerifier, nonce, err := s.identities.ConsumeAuthRequest(ctx, hashToken(state), provider)
+	if errors.IsNotFound(err) {
+		return nil, nil, errors.WithInvalid(errors.New("Invalid or expired OIDC state"), "")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity, err := p.Exchange(ctx, code, codeVerifier, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	login := &OIDCLogin{Provider: provider, Email: identity.Email}
+	u, err := s.resolveIdentity(ctx, identity, login)
+	if err != nil {
+		return nil, nil, err
+	}
+	login.UserID = u.ID
+
+	tokens, err := s.completeSignIn(ctx, u)
+	return tokens, login, err
+}
+
+func (s *userService) resolveIdentity(ctx context.Context, identity *model.Identity, login *OIDCLogin) (*model.User, error) {
+	linked, err := s.identities.Find(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return s.users.FindByID(ctx, linked.UserID)
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	// only an email the provider vouches for may be linked to an account
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errors.WithInvalid(errors.New("OIDC provider did not return a verified email"), "")
+	}
+
+	u, err := s.users.FindByEmail(ctx, identity.Email)
+	switch {
+	case errors.IsNotFound(err):
+		if s.cfg.SignUpPolicy == SignUpInvite {
+			return nil, errors.WithInvalid(errors.New("Sign-up is by invitation only"), "")
+		}
+		id, err := s.users.Create(ctx, identity.Email, unusablePassword)
+		if err != nil {
+			return nil, err
+		}
+		login.Created = true
+		u = &model.User{ID: id, Email: identity.Email}
+	case err != nil:
+		return nil, err
+	case !u.EmailVerified():
+		// nobody proved owning this email before, so whoever picked the
+		// password may not be the person signing in now
+		if err := s.users.UpdatePassword(ctx, u.ID, unusablePassword); err != nil {
+			return nil, err
+		}
+		if err := s.sessions.RevokeAll(ctx, u.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if !u.EmailVerified() {
+		if err := s.users.MarkEmailVerified(ctx, u.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	identity.UserID = u.ID
+	if err := s.identities.Create(ctx, *identity); err != nil {
+		return nil, err
+	}
+	login.Linked = true
+
+	// re-read so the issued token carries the verified email and current role
+	return s.users.FindByID(ctx, u.ID)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"strings"
+)
+
+// OrganizationService creates organizations and manages the members of the
+// one the context acts in. Users join through invitations, see
+// InvitationService.
+type OrganizationService interface {
+	// Create adds an organization with the actor as its first admin.
+	Create(ctx context.Context, actor model.Actor, name string) (*model.Organization, error)
+	// RemoveMember takes the user out of the organization. Admins cannot
+	// remove themselves, so an organization keeps at least one admin.
+	RemoveMember(ctx context.Context, actor model.Actor, userID string) error
+}
+
+// maxOrganizationName matches organizations.name.
+const maxOrganizationName = 100
+
+type organizationService struct {
+	orgs store.OrganizationRepository
+}
+
+func NewOrganizationService(o store.OrganizationRepository) OrganizationService {
+	return &organizationService{orgs: o}
+}
+
+func (s *organizationService) Create(ctx context.Context, actor model.Actor, name string) (*model.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxOrganizationName {
+		return nil, errors.WithInvalid(errors.Errorf("Name must be 1 to %d characters", maxOrganizationName), "")
+	}
+	if actor.Kind != model.ActorUser {
+		return nil, errors.WithInvalid(errors.New("API keys cannot create organizations"), "")
+	}
+	return s.orgs.Create(ctx, name, actor.ID)
+}
+
+func (s *organizationService) RemoveMember(ctx context.Context, actor model.Actor, userID string) error {
+	if actor.IsUser(userID) {
+		return errors.WithInvalid(errors.New("Cannot remove yourself"), "")
+	}
+	return s.orgs.RemoveMember(ctx, tenant.OrgID(ctx), userID)
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type organizationServiceWithQueue struct {
+	svc          OrganizationService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewOrganizationServiceWithQueue(svc OrganizationService, userLogQueue store.UserLogsQueue) OrganizationService {
+	return &organizationServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *organizationServiceWithQueue) Create(ctx context.Context, actor model.Actor, name string) (*model.Organization, error) {
+	o, err := s.svc.Create(ctx, actor, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// logged in the new organization, whose admins it concerns
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		OrgID:     o.ID,
+		EventType: "admin.createOrg",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s created organization %s (%s)", actor, o.ID, o.Name),
+		Actor:     actor.String(),
+	})
+	return o, err
+}
+
+func (s *organizationServiceWithQueue) RemoveMember(ctx context.Context, actor model.Actor, userID string) error {
+	if err := s.svc.RemoveMember(ctx, actor, userID); err != nil {
+		return err
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "admin.removeMember",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s removed user %s from the organization", actor, userID),
+		Actor:     actor.String(),
+	})
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PrivacyService lets users exercise their data rights: getting a copy of
+// their personal data and erasing their account.
+type PrivacyService interface {
+	// Export returns the user's latest data export, starting a new one when
+	// there is none still usable. started reports whether it did.
+	Export(ctx context.Context, userID string) (e *model.DataExport, started bool, err error)
+	// DeleteAccount removes the user after checking their password and has
+	// the worker anonymise their logs.
+	DeleteAccount(ctx context.Context, userID, password string) error
+}
+
+type PrivacyConfig struct {
+	// ExportTTL is how long a built archive can be downloaded.
+	ExportTTL time.Duration
+	// ExportTimeout is how long an export may stay pending before a new one
+	// is started in its place.
+	ExportTimeout time.Duration
+	// PurgeResweepDelay is when the logs of a deleted account are anonymised
+	// again, catching the events that were still queued at the first purge.
+	PurgeResweepDelay time.Duration
+}
+
+type privacyService struct {
+	users    store.UserRepository
+	sessions store.SessionRepository
+	exports  store.DataExportRepository
+	queue    store.PrivacyQueue
+	cfg      PrivacyConfig
+}
+
+func NewPrivacyService(u store.UserRepository, s store.SessionRepository, e store.DataExportRepository, q store.PrivacyQueue, cfg PrivacyConfig) PrivacyService {
+	return &privacyService{users: u, sessions: s, exports: e, queue: q, cfg: cfg}
+}
+
+func (s *privacyService) Export(ctx context.Context, userID string) (*model.DataExport, bool, error) {
+	e, err := s.exports.FindLatest(ctx, userID)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, false, err
+	}
+
+	now := time.Now().UTC()
+	if e != nil {
+		switch e.Status {
+		case model.DataExportReady:
+			if now.Before(e.CompletedAt.Time.Add(s.cfg.ExportTTL)) {
+				return e, false, nil
+			}
+		case model.DataExportPending:
+			if now.Before(e.CreatedAt.Add(s.cfg.ExportTimeout)) {
+				return e, false, nil
+			}
+		}
+	}
+
+	// none yet, or the last one failed, expired or got lost on the way
+	if e, err = s.exports.Create(ctx, userID); err != nil {
+		return nil, false, err
+	}
+	if err := s.queue.EnqueueDataExport(ctx, events.DataExportRequested{ExportID: e.ID, UserID: userID}); err != nil {
+		return nil, false, err
+	}
+	return e, true, nil
+}
+
+func (s *privacyService) DeleteAccount(ctx context.Context, userID, password string) error {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// users signed up through an identity provider set a password first
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+		return errors.WithInvalid(errors.New("Invalid password"), "")
+	}
+
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.users.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	// the queue is not ordered, so log events sent before or along with the
+	// deletion may land after the first purge; the second one clears them
+	ev := events.UserPurgeRequested{UserID: userID}
+	if err := s.queue.EnqueueUserPurge(ctx, ev, 0); err != nil {
+		return err
+	}
+	return s.queue.EnqueueUserPurge(ctx, ev, s.cfg.PurgeResweepDelay)
+}
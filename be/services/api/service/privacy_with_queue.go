@@ -0,0 +1,47 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type privacyServiceWithQueue struct {
+	svc          PrivacyService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewPrivacyServiceWithQueue(svc PrivacyService, userLogQueue store.UserLogsQueue) PrivacyService {
+	return &privacyServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *privacyServiceWithQueue) Export(ctx context.Context, userID string) (*model.DataExport, bool, error) {
+	e, started, err := s.svc.Export(ctx, userID)
+	if err != nil || !started {
+		return e, started, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "users.requestDataExport",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User requested a data export: id=%s export=%s", userID, e.ID),
+	})
+	return e, started, err
+}
+
+func (s *privacyServiceWithQueue) DeleteAccount(ctx context.Context, userID, password string) error {
+	if err := s.svc.DeleteAccount(ctx, userID, password); err != nil {
+		return err
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: events.AccountDeletedEventType,
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User deleted their account: id=%s", userID),
+	})
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ProfileUpdate holds the fields a user changes on their own record. Nil
+// fields are left as they are. Version is the version of the user the
+// changes were based on, or zero to apply them to any version.
+type ProfileUpdate struct {
+	Name    *string
+	Email   *string
+	Version int
+}
+
+func (s *userService) GetProfile(ctx context.Context, userID string) (*model.User, error) {
+	return s.users.FindByID(ctx, userID)
+}
+
+// UpdateProfile changes the user's name and email. A new email must be
+// verified again, so a verification link is sent to it. When the user has
+// changed since p.Version, nothing is written and the current user is
+// returned with a conflict error.
+func (s *userService) UpdateProfile(ctx context.Context, userID string, p ProfileUpdate) (*model.User, error) {
+	if p.Name != nil {
+		name := strings.TrimSpace(*p.Name)
+		if name == "" {
+			return nil, errors.WithInvalid(errors.New("Name cannot be empty"), "")
+		}
+		p.Name = &name
+	}
+
+	before, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Version == 0 {
+		p.Version = before.Version
+	}
+
+	u, err := s.users.UpdateProfile(ctx, userID, p.Name, p.Email, p.Version)
+	if err != nil {
+		return u, err
+	}
+
+	if u.Email != before.Email {
+		if err := s.verifier.Send(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// ChangePassword sets a new password after checking the current one. Other
+// sessions are signed out; the one making the change stays signed in.
+func (s *userService) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(currentPassword)); err != nil {
+		return errors.WithInvalid(errors.New("Invalid current password"), "")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := s.users.UpdatePassword(ctx, userID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.userTokens.InvalidateAll(ctx, model.TokenPasswordReset, userID); err != nil {
+		return err
+	}
+	return s.sessions.RevokeOthers(ctx, userID, sessionID)
+}
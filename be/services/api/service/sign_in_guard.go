@@ -0,0 +1,165 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	codeInvalidCredentials = "invalid_credentials"
+	codeSignInBlocked      = "sign_in_blocked"
+	codeAccountLocked      = "account_locked"
+)
+
+type SignInGuardConfig struct {
+	// MaxAccountFailures failures on an account within Window lock it for
+	// Lockout.
+	MaxAccountFailures int
+	// MaxIPFailures failures from a client IP within Window block the IP for
+	// Lockout.
+	MaxIPFailures int
+	Window        time.Duration
+	Lockout       time.Duration
+	// From the DelayAfter-th failure on, the account is blocked for Delay,
+	// doubling with each further failure until it is locked.
+	DelayAfter int
+	Delay      time.Duration
+}
+
+// signInGuard throttles password guessing per account and per client IP.
+type signInGuard struct {
+	attempts store.SignInAttemptStore
+	cfg      SignInGuardConfig
+}
+
+func errInvalidCredentials() error {
+	return errors.WithInvalid(errors.New("Invalid credentials"), codeInvalidCredentials)
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// mfaKey counts the wrong second factor codes of a user. Unlike the account
+// counters, a correct password does not reset it.
+func mfaKey(userID string) string {
+	return "mfa:" + userID
+}
+
+// check refuses the attempt while the account or the IP is blocked.
+func (g *signInGuard) check(ctx context.Context, email, ip string) error {
+	now := time.Now().UTC()
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		a, err := g.attempts.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if a.Blocked(now) {
+			return errors.WithTemporary(errors.New("Too many failed sign-in attempts, try again later"), codeSignInBlocked)
+		}
+	}
+	return nil
+}
+
+// fail records a failed attempt and returns the error to answer with: invalid
+// credentials, or account locked when this failure crossed the limit.
+func (g *signInGuard) fail(ctx context.Context, email, ip string) error {
+	now := time.Now().UTC()
+
+	failures, err := g.attempts.RecordFailure(ctx, ipKey(ip), g.cfg.Window)
+	if err != nil {
+		return err
+	}
+	if failures >= g.cfg.MaxIPFailures {
+		if err := g.attempts.Block(ctx, ipKey(ip), now.Add(g.cfg.Lockout)); err != nil {
+			return err
+		}
+	}
+
+	failures, err = g.attempts.RecordFailure(ctx, accountKey(email), g.cfg.Window)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case failures >= g.cfg.MaxAccountFailures:
+		if err := g.attempts.Block(ctx, accountKey(email), now.Add(g.cfg.Lockout)); err != nil {
+			return err
+		}
+		return errors.WithTemporary(errors.New("Too many failed sign-in attempts, account locked"), codeAccountLocked)
+	case failures >= g.cfg.DelayAfter:
+		if err := g.attempts.Block(ctx, accountKey(email), now.Add(g.delay(failures))); err != nil {
+			return err
+		}
+	}
+	return errInvalidCredentials()
+}
+
+// delay returns how long the account is blocked after its failures-th failure.
+// It doubles Delay step by step rather than shifting it, so a large count
+// cannot overflow, and stops at Lockout.
+func (g *signInGuard) delay(failures int) time.Duration {
+	delay := g.cfg.Delay
+	for i := g.cfg.DelayAfter; i < failures && delay < g.cfg.Lockout; i++ {
+		delay *= 2
+	}
+	return min(delay, g.cfg.Lockout)
+}
+
+// checkMFA refuses second factor codes while the user is locked out of it.
+func (g *signInGuard) checkMFA(ctx context.Context, userID string) error {
+	a, err := g.attempts.Get(ctx, mfaKey(userID))
+	if err != nil {
+		return err
+	}
+	if a.Blocked(time.Now().UTC()) {
+		return errors.WithTemporary(errors.New("Too many invalid two-factor codes, try again later"), codeSignInBlocked)
+	}
+	return nil
+}
+
+// failMFA records a wrong second factor code and returns the error to answer
+// with: invalid code, or account locked when this failure crossed the limit
+// of the account.
+func (g *signInGuard) failMFA(ctx context.Context, userID string) error {
+	failures, err := g.attempts.RecordFailure(ctx, mfaKey(userID), g.cfg.Window)
+	if err != nil {
+		return err
+	}
+	if failures < g.cfg.MaxAccountFailures {
+		return errInvalidMFACode()
+	}
+
+	if err := g.attempts.Block(ctx, mfaKey(userID), time.Now().UTC().Add(g.cfg.Lockout)); err != nil {
+		return err
+	}
+	return errors.WithTemporary(errors.New("Too many invalid two-factor codes, account locked"), codeAccountLocked)
+}
+
+// succeedMFA clears the second factor counters of the user.
+func (g *signInGuard) succeedMFA(ctx context.Context, userID string) error {
+	return g.attempts.Reset(ctx, mfaKey(userID))
+}
+
+// succeed clears the account counters. The IP ones are left to expire, or a
+// single valid account would let an attacker reset them at will.
+func (g *signInGuard) succeed(ctx context.Context, email string) error {
+	return g.attempts.Reset(ctx, accountKey(email))
+}
+
+// dummyPasswordHash is compared against when the email is unknown, so both
+// cases take as long and cannot be told apart by timing.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"be/pkg/errors"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Tokens are returned on sign-in. When the user has two-factor authentication
+// enabled, only MFAToken is set and must be exchanged through SignInMFA.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+	MFAToken     string
+	// OrgID is the organization the session acts in.
+	OrgID string
+}
+
+// newOpaqueToken returns a random token to hand out to the client and the hash
+// to persist for it. Only the hash is ever stored.
+func newOpaqueToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,7 +3,11 @@ package service
 import (
 	"api/store"
 	"be/pkg/errors"
+	"be/pkg/jwtkeys"
+	"be/pkg/mail"
+	"be/pkg/model"
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,50 +15,378 @@ import (
 )
 
 type UserService interface {
-	SignUp(ctx context.Context, email, password string) (string, error)
-	SignIn(ctx context.Context, email, password string) (string, string, error)
+	// SignUp creates the user, accepting the invitation when a token is
+	// given. The accepted invitation is returned, nil without a token.
+	SignUp(ctx context.Context, email, password, invitationToken string) (string, *model.Invitation, error)
+	SignIn(ctx context.Context, email, password, ip string) (*Tokens, string, error)
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, string, error)
+	// SignOut revokes the session of the refresh token and returns it.
+	SignOut(ctx context.Context, refreshToken string) (*model.Session, error)
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) (string, error)
+	VerifyEmail(ctx context.Context, token string) (string, error)
+
+	// ListOrgs returns the organizations the user belongs to.
+	ListOrgs(ctx context.Context, userID string) ([]model.Membership, error)
+	// SwitchOrg moves the session to another organization of the user and
+	// returns an access token acting there. The refresh token is unchanged.
+	SwitchOrg(ctx context.Context, userID, sessionID, orgID string) (*Tokens, error)
+
+	GetProfile(ctx context.Context, userID string) (*model.User, error)
+	UpdateProfile(ctx context.Context, userID string, p ProfileUpdate) (*model.User, error)
+	ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error
+
+	EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) error
+	DisableTOTP(ctx context.Context, userID, code string) error
+	SignInMFA(ctx context.Context, mfaToken, code string) (*Tokens, string, error)
+
+	OIDCProviders() []string
+	OIDCAuthURL(ctx context.Context, provider string) (string, error)
+	OIDCSignIn(ctx context.Context, provider, code, state string) (*Tokens, *OIDCLogin, error)
+}
+
+type UserConfig struct {
+	JWTKeys          *jwtkeys.KeySet
+	AccessTTL        time.Duration
+	RefreshTTL       time.Duration
+	PasswordResetTTL time.Duration
+	// AppURL is the frontend base URL used to build links sent by email.
+	AppURL string
+	// EmailVerificationPolicy is one of the VerifyEmail* constants.
+	EmailVerificationPolicy string
+	// SignUpPolicy is one of the SignUp* constants.
+	SignUpPolicy string
+
+	MFAIssuer       string
+	MFAChallengeTTL time.Duration
+
+	OIDCStateTTL time.Duration
+
+	SignInGuard SignInGuardConfig
 }
 
 type userService struct {
-	users  store.UserRepository
-	jwtKey []byte
+	users       store.UserRepository
+	orgs        store.OrganizationRepository
+	invitations store.InvitationRepository
+	sessions    store.SessionRepository
+	userTokens  store.UserTokenRepository
+	mailer      mail.Mailer
+	verifier    EmailVerifier
+	mfa         store.MFARepository
+	identities  store.IdentityRepository
+	providers   store.OIDCProviders
+	guard       *signInGuard
+	cfg         UserConfig
 }
 
-func NewUserService(u store.UserRepository, secret string) UserService {
-	return &userService{users: u, jwtKey: []byte(secret)}
+func NewUserService(
+	u store.UserRepository, o store.OrganizationRepository, inv store.InvitationRepository, s store.SessionRepository, t store.UserTokenRepository, m mail.Mailer, v EmailVerifier,
+	mfa store.MFARepository, i store.IdentityRepository, p store.OIDCProviders, a store.SignInAttemptStore, cfg UserConfig,
+) UserService {
+	return &userService{
+		users: u, orgs: o, invitations: inv, sessions: s, userTokens: t, mailer: m, verifier: v, mfa: mfa, identities: i, providers: p,
+		guard: &signInGuard{attempts: a, cfg: cfg.SignInGuard},
+		cfg:   cfg,
+	}
 }
 
-func (s *userService) SignUp(ctx context.Context, email, password string) (string, error) {
+func (s *userService) SignUp(ctx context.Context, email, password, invitationToken string) (string, *model.Invitation, error) {
+	if invitationToken == "" && s.cfg.SignUpPolicy == SignUpInvite {
+		return "", nil, errors.WithInvalid(errors.New("Sign-up is by invitation only"), "")
+	}
+
 	_, err := s.users.FindByEmail(ctx, email)
 	if !errors.IsNotFound(err) {
 		if err == nil {
 			err = errors.WithInvalid(errors.New("Email existed"), "")
 		}
+		return "", nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the invitation was mailed to the address, so it needs no verification
+	if invitationToken != "" {
+		inv, err := s.invitations.Accept(ctx, hashToken(invitationToken), email, string(hash))
+		if err != nil {
+			return "", nil, err
+		}
+		return inv.UserID.String, inv, nil
+	}
+
+	id, err := s.users.Create(ctx, email, string(hash))
+	if err != nil {
+		return "", nil, err
+	}
+	return id, nil, s.verifier.Send(ctx, &model.User{ID: id, Email: email})
+}
+
+// SignIn checks the password of the account, throttled per account and per
+// client IP. Unknown emails and wrong passwords fail the same way so they
+// cannot be used to probe for users. The user ID is returned with the error
+// when the account exists.
+func (s *userService) SignIn(ctx context.Context, email, password, ip string) (*Tokens, string, error) {
+	u, err := s.users.FindByEmail(ctx, email)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, "", err
+	}
+
+	userID := ""
+	if u != nil {
+		userID = u.ID
+	}
+
+	if err := s.guard.check(ctx, email, ip); err != nil {
+		return nil, userID, err
+	}
+
+	if u == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, "", s.guard.fail(ctx, email, ip)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+		return nil, userID, s.guard.fail(ctx, email, ip)
+	}
+
+	if err := s.guard.succeed(ctx, email); err != nil {
+		return nil, "", err
+	}
+
+	tokens, err := s.completeSignIn(ctx, u)
+	return tokens, u.ID, err
+}
+
+// completeSignIn runs the checks shared by every way of signing in once the
+// user is authenticated, then either starts a session or an MFA challenge.
+func (s *userService) completeSignIn(ctx context.Context, u *model.User) (*Tokens, error) {
+	if err := checkActive(u); err != nil {
+		return nil, err
+	}
+	if s.cfg.EmailVerificationPolicy == VerifyEmailSignIn && !u.EmailVerified() {
+		return nil, errors.WithInvalid(errors.New("Email not verified"), "")
+	}
+
+	m, err := s.mfa.Find(ctx, u.ID)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if m != nil && m.Enabled() {
+		return s.newMFAChallenge(ctx, u.ID)
+	}
+
+	return s.startSession(ctx, u)
+}
+
+func (s *userService) Refresh(ctx context.Context, refreshToken string) (*Tokens, string, error) {
+	newRefreshToken, newRefreshHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.sessions.Rotate(ctx, hashToken(refreshToken), newRefreshHash)
+	if errors.IsNotFound(err) {
+		return nil, "", errors.WithNotFound(errors.New("Invalid refresh token"), "")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// re-read the user so role and status changes apply from the next refresh on
+	u, err := s.users.FindByID(ctx, session.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkActive(u); err != nil {
+		return nil, "", err
+	}
+
+	m, err := s.orgs.FindMembership(ctx, session.OrgID, u.ID)
+	if errors.IsNotFound(err) {
+		// removed from the organization since, go back to the first one left
+		if m, err = s.firstMembership(ctx, u.ID); err == nil {
+			err = s.sessions.SwitchOrg(ctx, session.ID, m.OrgID)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	tokens, err := s.issueTokens(u, m, session.ID, newRefreshToken)
+	return tokens, u.ID, err
+}
+
+func (s *userService) SignOut(ctx context.Context, refreshToken string) (*model.Session, error) {
+	session, err := s.sessions.Revoke(ctx, hashToken(refreshToken))
+	if errors.IsNotFound(err) {
+		return nil, errors.WithNotFound(errors.New("Invalid refresh token"), "")
+	}
+	return session, err
+}
+
+// ForgotPassword mails a reset link to the user. It succeeds whether or not the
+// email belongs to an account, so callers cannot use it to probe for users.
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	u, err := s.users.FindByEmail(ctx, email)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !u.Active() {
+		return nil
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.userTokens.Create(ctx, model.TokenPasswordReset, u.ID, hash, time.Now().UTC().Add(s.cfg.PasswordResetTTL))
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone requested a password reset for your account.\n\n"+
+				"Open the link below to choose a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\n"+
+				"If you did not request this, you can ignore this email.",
+			s.cfg.PasswordResetTTL, s.cfg.AppURL, token,
+		),
+	})
+}
+
+// ResetPassword sets a new password using a token sent by ForgotPassword, then
+// invalidates the user's other reset tokens and signs them out everywhere.
+func (s *userService) ResetPassword(ctx context.Context, token, password string) (string, error) {
+	userID, err := s.userTokens.Consume(ctx, model.TokenPasswordReset, hashToken(token))
+	if errors.IsNotFound(err) {
+		return "", errors.WithInvalid(err, "")
+	}
+	if err != nil {
 		return "", err
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if err := s.users.UpdatePassword(ctx, userID, string(hash)); err != nil {
+		return "", err
+	}
+	if err := s.userTokens.InvalidateAll(ctx, model.TokenPasswordReset, userID); err != nil {
+		return "", err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
 		return "", err
 	}
-	return s.users.Create(ctx, email, string(hash))
+	return userID, nil
 }
 
-func (s *userService) SignIn(ctx context.Context, email, password string) (string, string, error) {
-	u, err := s.users.FindByEmail(ctx, email)
+func (s *userService) VerifyEmail(ctx context.Context, token string) (string, error) {
+	return s.verifier.Verify(ctx, token)
+}
+
+func (s *userService) startSession(ctx context.Context, u *model.User) (*Tokens, error) {
+	if err := checkActive(u); err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := newOpaqueToken()
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
-		return "", "", err
+	m, err := s.firstMembership(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sid, err := s.sessions.Create(ctx, u.ID, m.OrgID, refreshHash, time.Now().UTC().Add(s.cfg.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(u, m, sid, refreshToken)
+}
+
+// firstMembership returns the organization sessions of the user start in.
+func (s *userService) firstMembership(ctx context.Context, userID string) (*model.Membership, error) {
+	m, err := s.orgs.FirstMembership(ctx, userID)
+	if errors.IsNotFound(err) {
+		return nil, errors.WithInvalid(errors.New("Account belongs to no organization"), "")
+	}
+	return m, err
+}
+
+func (s *userService) ListOrgs(ctx context.Context, userID string) ([]model.Membership, error) {
+	return s.orgs.ListForUser(ctx, userID)
+}
+
+func (s *userService) SwitchOrg(ctx context.Context, userID, sessionID, orgID string) (*Tokens, error) {
+	if sessionID == "" {
+		return nil, errors.WithInvalid(errors.New("Only sessions can switch organization"), "")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": u.ID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+	m, err := s.orgs.FindMembership(ctx, orgID, userID)
+	if errors.IsNotFound(err) {
+		return nil, errors.WithNotFound(errors.New("Organization not found"), "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkActive(u); err != nil {
+		return nil, err
+	}
+
+	if err := s.sessions.SwitchOrg(ctx, sessionID, orgID); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(u, m, sessionID, "")
+}
+
+// checkActive refuses suspended and deleted users.
+func checkActive(u *model.User) error {
+	if u.Active() {
+		return nil
+	}
+	return errors.WithInvalid(errors.Errorf("Account %s", u.Status), "")
+}
+
+// issueTokens signs an access token acting in the organization of m, with the
+// user's role there.
+func (s *userService) issueTokens(u *model.User, m *model.Membership, sid, refreshToken string) (*Tokens, error) {
+	ss, err := s.cfg.JWTKeys.Sign(jwt.MapClaims{
+		"user_id":        u.ID,
+		"org":            m.OrgID,
+		"role":           m.Role,
+		"sid":            sid,
+		"email_verified": u.EmailVerified(),
+		"exp":            time.Now().Add(s.cfg.AccessTTL).Unix(),
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	ss, err := token.SignedString(s.jwtKey)
-	return ss, u.ID, err
+	return &Tokens{
+		AccessToken:  ss,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.cfg.AccessTTL.Seconds()),
+		OrgID:        m.OrgID,
+	}, nil
 }
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// UserAttributeService manages the schema of the custom user attributes.
+type UserAttributeService interface {
+	List(ctx context.Context) ([]model.AttributeDefinition, error)
+	// Save creates or replaces the definition of the attribute. Values users
+	// already hold are checked against it on their next change.
+	Save(ctx context.Context, actor model.Actor, d model.AttributeDefinition) (*model.AttributeDefinition, error)
+	// Delete removes the attribute from the schema and from every user.
+	Delete(ctx context.Context, actor model.Actor, name string) error
+}
+
+type userAttributeService struct {
+	attributes store.UserAttributeRepository
+}
+
+func NewUserAttributeService(attrs store.UserAttributeRepository) UserAttributeService {
+	return &userAttributeService{attributes: attrs}
+}
+
+func (s *userAttributeService) List(ctx context.Context) ([]model.AttributeDefinition, error) {
+	return s.attributes.List(ctx)
+}
+
+func (s *userAttributeService) Save(ctx context.Context, actor model.Actor, d model.AttributeDefinition) (*model.AttributeDefinition, error) {
+	if err := d.Check(); err != nil {
+		return nil, errors.WithInvalid(err, "")
+	}
+	return s.attributes.Save(ctx, d)
+}
+
+func (s *userAttributeService) Delete(ctx context.Context, actor model.Actor, name string) error {
+	return s.attributes.Delete(ctx, name)
+}
+
+// mergeAttributes applies patch to the current attributes and checks the
+// result against the schema. It records each changed attribute in changes
+// as "attributes.<name>" and returns nil when nothing changed.
+func mergeAttributes(schema []model.AttributeDefinition, current, patch model.Attributes, changes map[string][2]string) (model.Attributes, error) {
+	merged := maps.Clone(current)
+	if merged == nil {
+		merged = model.Attributes{}
+	}
+
+	changed := false
+	for name, v := range patch {
+		switch v.(type) {
+		case nil, string, float64, bool:
+		default:
+			return nil, errors.WithInvalid(model.AttributeErrors{name: "must be a string, number or boolean"}, "")
+		}
+
+		old, had := merged[name]
+		if v == nil {
+			delete(merged, name)
+		} else {
+			merged[name] = v
+		}
+		if had != (v != nil) || (had && old != v) {
+			changes["attributes."+name] = [2]string{attributeText(old), attributeText(v)}
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	if err := model.CheckAttributes(schema, merged); err != nil {
+		return nil, errors.WithInvalid(err, "")
+	}
+	return merged, nil
+}
+
+func attributeText(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// attributeFilter reads the text values of a user list filter as the types
+// of their attributes.
+func attributeFilter(schema []model.AttributeDefinition, filter map[string]string) (model.Attributes, error) {
+	attrs := model.Attributes{}
+	invalid := model.AttributeErrors{}
+	for name, text := range filter {
+		i := slices.IndexFunc(schema, func(d model.AttributeDefinition) bool { return d.Name == name })
+		if i < 0 {
+			invalid[name] = "unknown attribute"
+			continue
+		}
+		v, err := schema[i].ParseValue(text)
+		if err != nil {
+			invalid[name] = err.Error()
+			continue
+		}
+		attrs[name] = v
+	}
+
+	if len(invalid) > 0 {
+		return nil, errors.WithInvalid(invalid, "")
+	}
+	return attrs, nil
+}
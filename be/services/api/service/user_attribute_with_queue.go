@@ -0,0 +1,54 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type userAttributeServiceWithQueue struct {
+	svc          UserAttributeService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewUserAttributeServiceWithQueue(svc UserAttributeService, userLogQueue store.UserLogsQueue) UserAttributeService {
+	return &userAttributeServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *userAttributeServiceWithQueue) List(ctx context.Context) ([]model.AttributeDefinition, error) {
+	return s.svc.List(ctx)
+}
+
+func (s *userAttributeServiceWithQueue) Save(ctx context.Context, actor model.Actor, d model.AttributeDefinition) (*model.AttributeDefinition, error) {
+	saved, err := s.svc.Save(ctx, actor, d)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.saveUserAttribute",
+		EventTime: time.Now().UTC(),
+		Details: fmt.Sprintf("Admin %s saved user attribute %s: type=%s required=%t enum=%v max_length=%d",
+			actor, saved.Name, saved.Type, saved.Required, saved.Enum, saved.MaxLength),
+		Actor: actor.String(),
+	})
+	return saved, err
+}
+
+func (s *userAttributeServiceWithQueue) Delete(ctx context.Context, actor model.Actor, name string) error {
+	if err := s.svc.Delete(ctx, actor, name); err != nil {
+		return err
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.deleteUserAttribute",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s deleted user attribute %s", actor, name),
+		Actor:     actor.String(),
+	})
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// userCursor is the position in a user listing handed to clients as an opaque
+// string. It carries the sort so a cursor cannot be replayed against another
+// ordering, where its key would mean nothing, and whether it pages backwards
+// from the key rather than forwards.
+type userCursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Prev   bool   `json:"p,omitempty"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
+}
+
+// userCursorCodec turns cursors into "<payload>.<signature>", both base64url
+// encoded. The HMAC signature keeps clients from crafting keys of their own.
+type userCursorCodec struct {
+	secret []byte
+}
+
+func (c userCursorCodec) encode(cur userCursor) string {
+	payload, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+func (c userCursorCodec) decode(s, sortBy string, desc bool) (*userCursor, error) {
+	invalid := errors.WithInvalid(errors.New("Invalid cursor"), "")
+
+	p, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return nil, invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, c.sign(payload)) {
+		return nil, invalid
+	}
+
+	var cur userCursor
+	if err := json.Unmarshal(payload, &cur); err != nil || cur.ID == "" {
+		return nil, invalid
+	}
+	if cur.SortBy != sortBy || cur.Desc != desc {
+		return nil, errors.WithInvalid(errors.New("Cursor does not match the sort"), "")
+	}
+	return &cur, nil
+}
+
+func (c userCursorCodec) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+func (cur *userCursor) key() *store.UserListKey {
+	return &store.UserListKey{Value: cur.Value, ID: cur.ID}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+)
+
+// UserImportService creates users in bulk from an uploaded CSV or NDJSON file.
+// Rows hold an email, an optional name and an optional role; the worker
+// creates them and mails each user an invite to choose a password.
+type UserImportService interface {
+	Start(ctx context.Context, actor model.Actor, format string, data []byte) (*model.ImportJob, error)
+	// Get returns the job with the errors of the rows that failed so far.
+	Get(ctx context.Context, id string) (*model.ImportJob, []model.ImportRowError, error)
+}
+
+type userImportService struct {
+	jobs  store.ImportJobRepository
+	queue store.UserImportQueue
+}
+
+func NewUserImportService(j store.ImportJobRepository, q store.UserImportQueue) UserImportService {
+	return &userImportService{jobs: j, queue: q}
+}
+
+func (s *userImportService) Start(ctx context.Context, actor model.Actor, format string, data []byte) (*model.ImportJob, error) {
+	if format != model.ImportCSV && format != model.ImportNDJSON {
+		return nil, errors.WithInvalid(errors.Errorf("Unsupported import format %q", format), "")
+	}
+	if len(data) == 0 {
+		return nil, errors.WithInvalid(errors.New("Empty import file"), "")
+	}
+
+	j, err := s.jobs.Create(ctx, format, actor.String(), data)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.queue.EnqueueUserImport(ctx, events.UserImportRequested{JobID: j.ID}); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (s *userImportService) Get(ctx context.Context, id string) (*model.ImportJob, []model.ImportRowError, error) {
+	j, err := s.jobs.Find(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rowErrors, err := s.jobs.ListErrors(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return j, rowErrors, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/events"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+type userImportServiceWithQueue struct {
+	svc          UserImportService
+	userLogQueue store.UserLogsQueue
+}
+
+func NewUserImportServiceWithQueue(svc UserImportService, userLogQueue store.UserLogsQueue) UserImportService {
+	return &userImportServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
+}
+
+func (s *userImportServiceWithQueue) Start(ctx context.Context, actor model.Actor, format string, data []byte) (*model.ImportJob, error) {
+	j, err := s.svc.Start(ctx, actor, format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    actor.ID,
+		EventType: "admin.importUsers",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("Admin %s started user import %s (%s, %d bytes)", actor, j.ID, format, len(data)),
+		Actor:     actor.String(),
+	})
+	return j, err
+}
+
+func (s *userImportServiceWithQueue) Get(ctx context.Context, id string) (*model.ImportJob, []model.ImportRowError, error) {
+	return s.svc.Get(ctx, id)
+}
@@ -2,9 +2,13 @@ package service
 
 import (
 	"api/store"
+	"be/pkg/errors"
 	"be/pkg/events"
+	"be/pkg/model"
+	"be/pkg/tenant"
 	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -17,17 +21,114 @@ func NewUserServiceWithQueue(svc UserService, userLogQueue store.UserLogsQueue)
 	return &userServiceWithQueue{svc: svc, userLogQueue: userLogQueue}
 }
 
-func (s *userServiceWithQueue) SignUp(ctx context.Context, email, password string) (string, error) {
-	id, err := s.svc.SignUp(ctx, email, password)
+func (s *userServiceWithQueue) SignUp(ctx context.Context, email, password, invitationToken string) (string, *model.Invitation, error) {
+	id, inv, err := s.svc.SignUp(ctx, email, password, invitationToken)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	if err := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+	signUp := events.UserLogsEvent{
 		UserID:    id,
 		EventType: "users.signUp",
 		EventTime: time.Now().UTC(),
 		Details:   fmt.Sprintf("New user: id=%s email=%s", id, email),
+	}
+	if inv == nil {
+		return id, nil, s.userLogQueue.Enqueue(ctx, signUp)
+	}
+
+	// the user only belongs to the organization of the invitation
+	signUp.OrgID = inv.OrgID
+	err = s.userLogQueue.EnqueueBatch(ctx, []events.UserLogsEvent{signUp, {
+		UserID:    id,
+		OrgID:     inv.OrgID,
+		EventType: "users.acceptInvitation",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User accepted invitation %s as %s: id=%s email=%s", inv.ID, inv.Role, id, email),
+	}})
+	return id, inv, err
+}
+
+func (s *userServiceWithQueue) SignIn(ctx context.Context, email, password, ip string) (*Tokens, string, error) {
+	tokens, id, err := s.svc.SignIn(ctx, email, password, ip)
+	if err != nil {
+		return nil, "", s.signInFailed(ctx, id, email, ip, err)
+	}
+
+	// the sign-in is logged by SignInMFA once the second factor is checked
+	if tokens.MFAToken != "" {
+		return tokens, id, nil
+	}
+
+	if err := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    id,
+		OrgID:     tokens.OrgID,
+		EventType: "users.signIn",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User signed-in: email=%s", email),
+	}); err != nil {
+		return nil, "", err
+	}
+
+	return tokens, id, nil
+}
+
+func (s *userServiceWithQueue) Refresh(ctx context.Context, refreshToken string) (*Tokens, string, error) {
+	return s.svc.Refresh(ctx, refreshToken)
+}
+
+func (s *userServiceWithQueue) SignOut(ctx context.Context, refreshToken string) (*model.Session, error) {
+	session, err := s.svc.SignOut(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    session.UserID,
+		OrgID:     session.OrgID,
+		EventType: "users.signOut",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User signed-out: id=%s", session.UserID),
+	}); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *userServiceWithQueue) ForgotPassword(ctx context.Context, email string) error {
+	return s.svc.ForgotPassword(ctx, email)
+}
+
+func (s *userServiceWithQueue) ResetPassword(ctx context.Context, token, password string) (string, error) {
+	id, err := s.svc.ResetPassword(ctx, token, password)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.enqueueForUser(ctx, events.UserLogsEvent{
+		UserID:    id,
+		EventType: "users.passwordReset",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User reset password: id=%s", id),
+	}); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *userServiceWithQueue) VerifyEmail(ctx context.Context, token string) (string, error) {
+	id, err := s.svc.VerifyEmail(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.enqueueForUser(ctx, events.UserLogsEvent{
+		UserID:    id,
+		EventType: "users.verifyEmail",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User verified email: id=%s", id),
 	}); err != nil {
 		return "", err
 	}
@@ -35,20 +136,273 @@ func (s *userServiceWithQueue) SignUp(ctx context.Context, email, password strin
 	return id, nil
 }
 
-func (s *userServiceWithQueue) SignIn(ctx context.Context, email, password string) (string, string, error) {
-	ss, id, err := s.svc.SignIn(ctx, email, password)
+func (s *userServiceWithQueue) ListOrgs(ctx context.Context, userID string) ([]model.Membership, error) {
+	return s.svc.ListOrgs(ctx, userID)
+}
+
+func (s *userServiceWithQueue) SwitchOrg(ctx context.Context, userID, sessionID, orgID string) (*Tokens, error) {
+	tokens, err := s.svc.SwitchOrg(ctx, userID, sessionID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		OrgID:     orgID,
+		EventType: "users.switchOrg",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User switched organization: id=%s org=%s", userID, orgID),
+	}); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *userServiceWithQueue) GetProfile(ctx context.Context, userID string) (*model.User, error) {
+	return s.svc.GetProfile(ctx, userID)
+}
+
+func (s *userServiceWithQueue) UpdateProfile(ctx context.Context, userID string, p ProfileUpdate) (*model.User, error) {
+	u, err := s.svc.UpdateProfile(ctx, userID, p)
+	if err != nil {
+		return u, err
+	}
+
+	changes := []string{}
+	if p.Name != nil {
+		changes = append(changes, "name="+u.Name.String)
+	}
+	if p.Email != nil {
+		changes = append(changes, "email="+u.Email)
+	}
+
+	if err := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "users.updateProfile",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User updated profile: id=%s %s", userID, strings.Join(changes, " ")),
+	}); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (s *userServiceWithQueue) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
+	if err := s.svc.ChangePassword(ctx, userID, sessionID, currentPassword, newPassword); err != nil {
+		return err
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "users.changePassword",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User changed password: id=%s", userID),
+	})
+}
+
+func (s *userServiceWithQueue) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	enrollment, err := s.svc.EnrollTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "users.mfaEnroll",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User started TOTP enrollment: id=%s", userID),
+	}); err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+func (s *userServiceWithQueue) ConfirmTOTP(ctx context.Context, userID, code string) error {
+	if err := s.svc.ConfirmTOTP(ctx, userID, code); err != nil {
+		return s.mfaFailed(ctx, userID, "confirm TOTP enrollment", err)
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "users.mfaEnable",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User enabled TOTP: id=%s", userID),
+	})
+}
+
+func (s *userServiceWithQueue) DisableTOTP(ctx context.Context, userID, code string) error {
+	if err := s.svc.DisableTOTP(ctx, userID, code); err != nil {
+		return s.mfaFailed(ctx, userID, "disable TOTP", err)
+	}
+
+	return s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
+		UserID:    userID,
+		EventType: "users.mfaDisable",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User disabled TOTP: id=%s", userID),
+	})
+}
+
+func (s *userServiceWithQueue) SignInMFA(ctx context.Context, mfaToken, code string) (*Tokens, string, error) {
+	tokens, id, err := s.svc.SignInMFA(ctx, mfaToken, code)
 	if err != nil {
-		return "", "", err
+		return nil, "", s.mfaFailed(ctx, id, "sign in", err)
 	}
 
 	if err := s.userLogQueue.Enqueue(ctx, events.UserLogsEvent{
 		UserID:    id,
+		OrgID:     tokens.OrgID,
 		EventType: "users.signIn",
 		EventTime: time.Now().UTC(),
-		Details:   fmt.Sprintf("User signed-in: email=%s", email),
+		Details:   fmt.Sprintf("User signed-in with TOTP: id=%s", id),
 	}); err != nil {
-		return "", "", err
+		return nil, "", err
+	}
+
+	return tokens, id, nil
+}
+
+func (s *userServiceWithQueue) OIDCProviders() []string {
+	return s.svc.OIDCProviders()
+}
+
+func (s *userServiceWithQueue) OIDCAuthURL(ctx context.Context, provider string) (string, error) {
+	return s.svc.OIDCAuthURL(ctx, provider)
+}
+
+func (s *userServiceWithQueue) OIDCSignIn(ctx context.Context, provider, code, state string) (*Tokens, *OIDCLogin, error) {
+	tokens, login, err := s.svc.OIDCSignIn(ctx, provider, code, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	evts := []events.UserLogsEvent{}
+	if login.Created {
+		evts = append(evts, events.UserLogsEvent{
+			UserID:    login.UserID,
+			EventType: "users.signUp",
+			EventTime: time.Now().UTC(),
+			Details:   fmt.Sprintf("New user: id=%s email=%s provider=%s", login.UserID, login.Email, login.Provider),
+		})
+	}
+	if login.Linked {
+		evts = append(evts, events.UserLogsEvent{
+			UserID:    login.UserID,
+			EventType: "users.linkIdentity",
+			EventTime: time.Now().UTC(),
+			Details:   fmt.Sprintf("User linked identity: id=%s provider=%s", login.UserID, login.Provider),
+		})
+	}
+	// the sign-in is logged by SignInMFA once the second factor is checked
+	if tokens.MFAToken == "" {
+		evts = append(evts, events.UserLogsEvent{
+			UserID:    login.UserID,
+			OrgID:     tokens.OrgID,
+			EventType: "users.signIn",
+			EventTime: time.Now().UTC(),
+			Details:   fmt.Sprintf("User signed-in: email=%s provider=%s", login.Email, login.Provider),
+		})
+	}
+
+	for _, e := range evts {
+		if err := s.userLogQueue.Enqueue(ctx, e); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return tokens, login, nil
+}
+
+// signInFailed logs a users.signInFailed event when err rejects the
+// credentials, plus users.locked when it locked the account, and returns err
+// unchanged.
+func (s *userServiceWithQueue) signInFailed(ctx context.Context, userID, email, ip string, err error) error {
+	code := errors.ErrorCode(err)
+	if code != codeInvalidCredentials && code != codeSignInBlocked && code != codeAccountLocked {
+		return err
+	}
+
+	evts := []events.UserLogsEvent{{
+		UserID:    userID,
+		EventType: "users.signInFailed",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User failed to sign in: email=%s, ip=%s, reason=%s", email, ip, code),
+	}}
+	if code == codeAccountLocked && userID != "" {
+		evts = append(evts, events.UserLogsEvent{
+			UserID:    userID,
+			EventType: "users.locked",
+			EventTime: time.Now().UTC(),
+			Details:   fmt.Sprintf("User locked after repeated sign-in failures: id=%s, ip=%s", userID, ip),
+		})
+	}
+
+	for _, e := range evts {
+		if qerr := s.enqueueForUser(ctx, e); qerr != nil {
+			return qerr
+		}
+	}
+
+	return err
+}
+
+// enqueueForUser logs ev in the organization the context acts in. Outside of
+// one, as before signing in, it is logged in every organization of the user,
+// or in the default one for users without any, such as unknown emails.
+func (s *userServiceWithQueue) enqueueForUser(ctx context.Context, ev events.UserLogsEvent) error {
+	if tenant.OrgID(ctx) != "" || ev.UserID == "" {
+		return s.userLogQueue.Enqueue(ctx, ev)
+	}
+
+	memberships, err := s.svc.ListOrgs(ctx, ev.UserID)
+	if err != nil {
+		return err
+	}
+	if len(memberships) == 0 {
+		ev.OrgID = model.DefaultOrgID
+		return s.userLogQueue.Enqueue(ctx, ev)
+	}
+
+	evts := make([]events.UserLogsEvent, 0, len(memberships))
+	for _, m := range memberships {
+		ev.OrgID = m.OrgID
+		evts = append(evts, ev)
+	}
+	return s.userLogQueue.EnqueueBatch(ctx, evts)
+}
+
+// mfaFailed logs a users.mfaFailed event when err is caused by a wrong code or
+// the user being locked out of the second factor, plus users.locked when this
+// code locked them, and returns err unchanged.
+func (s *userServiceWithQueue) mfaFailed(ctx context.Context, userID, action string, err error) error {
+	code := errors.ErrorCode(err)
+	if userID == "" || (code != codeInvalidMFACode && code != codeSignInBlocked && code != codeAccountLocked) {
+		return err
+	}
+
+	evts := []events.UserLogsEvent{{
+		UserID:    userID,
+		EventType: "users.mfaFailed",
+		EventTime: time.Now().UTC(),
+		Details:   fmt.Sprintf("User failed to %s: id=%s, reason=%s", action, userID, err.Error()),
+	}}
+	if code == codeAccountLocked {
+		evts = append(evts, events.UserLogsEvent{
+			UserID:    userID,
+			EventType: "users.locked",
+			EventTime: time.Now().UTC(),
+			Details:   fmt.Sprintf("User locked after repeated invalid two-factor codes: id=%s", userID),
+		})
+	}
+
+	for _, e := range evts {
+		if qerr := s.enqueueForUser(ctx, e); qerr != nil {
+			return qerr
+		}
 	}
 
-	return ss, id, nil
+	return err
 }
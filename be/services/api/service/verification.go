@@ -0,0 +1,81 @@
+package service
+
+import (
+	"api/store"
+	"be/pkg/errors"
+	"be/pkg/mail"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Email verification policies, see UserConfig.EmailVerificationPolicy.
+const (
+	// VerifyEmailOff lets unverified accounts do everything.
+	VerifyEmailOff = "off"
+	// VerifyEmailSignIn refuses to sign in unverified accounts.
+	VerifyEmailSignIn = "signin"
+	// VerifyEmailAdmin lets unverified accounts sign in but not use admin routes.
+	VerifyEmailAdmin = "admin"
+)
+
+type EmailVerifier interface {
+	Send(ctx context.Context, u *model.User) error
+	Verify(ctx context.Context, token string) (string, error)
+}
+
+type emailVerifier struct {
+	users      store.UserRepository
+	userTokens store.UserTokenRepository
+	mailer     mail.Mailer
+	ttl        time.Duration
+	appURL     string
+}
+
+func NewEmailVerifier(u store.UserRepository, t store.UserTokenRepository, m mail.Mailer, ttl time.Duration, appURL string) EmailVerifier {
+	return &emailVerifier{users: u, userTokens: t, mailer: m, ttl: ttl, appURL: appURL}
+}
+
+// Send mails a fresh verification link to the user. Links sent earlier stop
+// working so only the latest mail can be used.
+func (v *emailVerifier) Send(ctx context.Context, u *model.User) error {
+	if u.EmailVerified() {
+		return errors.WithInvalid(errors.New("Email already verified"), "")
+	}
+
+	if err := v.userTokens.InvalidateAll(ctx, model.TokenEmailVerification, u.ID); err != nil {
+		return err
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = v.userTokens.Create(ctx, model.TokenEmailVerification, u.ID, hash, time.Now().UTC().Add(v.ttl))
+	if err != nil {
+		return err
+	}
+
+	return v.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Welcome! Please confirm your email address by opening the link below. It expires in %s.\n\n%s/verify-email?token=%s",
+			v.ttl, v.appURL, token,
+		),
+	})
+}
+
+func (v *emailVerifier) Verify(ctx context.Context, token string) (string, error) {
+	userID, err := v.userTokens.Consume(ctx, model.TokenEmailVerification, hashToken(token))
+	if errors.IsNotFound(err) {
+		return "", errors.WithInvalid(err, "")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return userID, v.users.MarkEmailVerified(ctx, userID)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// adminExportRetention is how long built admin exports are kept around.
+const adminExportRetention = 7 * 24 * time.Hour
+
+// AdminExportRepository keeps the admin exports of the organization the
+// context acts in.
+type AdminExportRepository interface {
+	// Create records a pending export and drops exports older than a week.
+	Create(ctx context.Context, kind, format, createdBy string, filter model.UserFilter) (*model.AdminExport, error)
+	// Find returns the export without its file.
+	Find(ctx context.Context, id string) (*model.AdminExport, error)
+	// FindFile returns the export with its file.
+	FindFile(ctx context.Context, id string) (*model.AdminExport, error)
+}
+
+const adminExportColumns = `id, kind, format, status, created_by, org_id, filter, error, completed_at, created_at`
+
+type adminExportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAdminExportRepo(pool *pgxpool.Pool) AdminExportRepository {
+	return &adminExportRepo{db: pool}
+}
+
+func scanAdminExport(row pgx.Row, file *[]byte) (*model.AdminExport, error) {
+	var e model.AdminExport
+	dest := []any{&e.ID, &e.Kind, &e.Format, &e.Status, &e.CreatedBy, &e.OrgID, &e.Filter, &e.Error, &e.CompletedAt, &e.CreatedAt}
+	if file != nil {
+		dest = append(dest, file)
+	}
+	err := row.Scan(dest...)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Export not found"), "")
+	}
+	return &e, errors.WithStack(err)
+}
+
+func (r *adminExportRepo) Create(ctx context.Context, kind, format, createdBy string, filter model.UserFilter) (*model.AdminExport, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(ctx, `DELETE FROM admin_exports WHERE created_at < $1`, now.Add(-adminExportRetention)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	e, err := scanAdminExport(tx.QueryRow(ctx, `
+        INSERT INTO admin_exports (id,kind,format,status,created_by,org_id,filter,created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+        RETURNING `+adminExportColumns,
+		uuid.NewString(), kind, format, model.DataExportPending, createdBy, tenant.OrgID(ctx), filter, now,
+	), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *adminExportRepo) Find(ctx context.Context, id string) (*model.AdminExport, error) {
+	return scanAdminExport(r.db.QueryRow(ctx, `SELECT `+adminExportColumns+` FROM admin_exports WHERE id = $1 AND org_id::text = $2`,
+		id, tenant.OrgID(ctx)), nil)
+}
+
+func (r *adminExportRepo) FindFile(ctx context.Context, id string) (*model.AdminExport, error) {
+	var file []byte
+	e, err := scanAdminExport(r.db.QueryRow(ctx, `SELECT `+adminExportColumns+`, file FROM admin_exports WHERE id = $1 AND org_id::text = $2`,
+		id, tenant.OrgID(ctx)), &file)
+	if err != nil {
+		return nil, err
+	}
+	e.File = file
+	return e, nil
+}
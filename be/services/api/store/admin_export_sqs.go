@@ -0,0 +1,22 @@
+package store
+
+import (
+	"be/pkg/events"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// AdminExportQueue hands background admin exports to the worker.
+type AdminExportQueue interface {
+	EnqueueAdminExport(ctx context.Context, ev events.AdminExportRequested) error
+}
+
+func NewAdminExportSQS(awsConfig aws.Config, queueURL string) AdminExportQueue {
+	return &sqsService{sqsClient: sqs.NewFromConfig(awsConfig), queueURL: queueURL}
+}
+
+func (s *sqsService) EnqueueAdminExport(ctx context.Context, ev events.AdminExportRequested) error {
+	return s.send(ctx, events.RouteAdminExports, ev)
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, k model.APIKey, keyHash string) (*model.APIKey, error)
+	// List and Revoke only see the keys of the given organization.
+	List(ctx context.Context, orgID string) ([]model.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	Revoke(ctx context.Context, orgID, id string) (*model.APIKey, error)
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+const apiKeyColumns = `id, name, prefix, scopes, org_id, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+// lastUsedResolution bounds how often using a key writes to the database.
+const lastUsedResolution = time.Minute
+
+type apiKeyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(pool *pgxpool.Pool) APIKeyRepository {
+	return &apiKeyRepo{db: pool}
+}
+
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var k model.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Scopes, &k.OrgID, &k.CreatedBy, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("API key not found"), "")
+	}
+	return &k, errors.WithStack(err)
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, k model.APIKey, keyHash string) (*model.APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(ctx, `
+        INSERT INTO api_keys (id,name,key_hash,prefix,scopes,org_id,created_by,expires_at,created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+        RETURNING `+apiKeyColumns,
+		uuid.NewString(), k.Name, keyHash, k.Prefix, k.Scopes, k.OrgID, k.CreatedBy, k.ExpiresAt, time.Now().UTC(),
+	))
+}
+
+func (r *apiKeyRepo) List(ctx context.Context, orgID string) ([]model.APIKey, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE org_id = $1 ORDER BY created_at DESC`, orgID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, errors.WithStack(rows.Err())
+}
+
+func (r *apiKeyRepo) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash))
+}
+
+func (r *apiKeyRepo) Revoke(ctx context.Context, orgID, id string) (*model.APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(ctx, `
+        UPDATE api_keys
+        SET revoked_at = COALESCE(revoked_at, $1)
+        WHERE id = $2 AND org_id = $3
+        RETURNING `+apiKeyColumns, time.Now().UTC(), id, orgID))
+}
+
+// TouchLastUsed records that the key was used, at most once per
+// lastUsedResolution so busy scripts do not write on every request.
+func (r *apiKeyRepo) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE api_keys
+        SET last_used_at = $1
+        WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)
+    `, at, id, at.Add(-lastUsedResolution))
+	return errors.WithStack(err)
+}
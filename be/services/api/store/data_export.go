@@ -0,0 +1,71 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DataExportRepository interface {
+	// Create records a pending export and drops the user's earlier ones, so
+	// only the latest archive is kept.
+	Create(ctx context.Context, userID string) (*model.DataExport, error)
+	FindLatest(ctx context.Context, userID string) (*model.DataExport, error)
+}
+
+const dataExportColumns = `id, user_id, status, archive, error, completed_at, created_at`
+
+type dataExportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDataExportRepo(pool *pgxpool.Pool) DataExportRepository {
+	return &dataExportRepo{db: pool}
+}
+
+func scanDataExport(row pgx.Row) (*model.DataExport, error) {
+	var e model.DataExport
+	err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.Archive, &e.Error, &e.CompletedAt, &e.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Data export not found"), "")
+	}
+	return &e, errors.WithStack(err)
+}
+
+func (r *dataExportRepo) Create(ctx context.Context, userID string) (*model.DataExport, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM data_exports WHERE user_id = $1`, userID); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	e, err := scanDataExport(tx.QueryRow(ctx, `
+        INSERT INTO data_exports (id,user_id,status,created_at)
+        VALUES ($1,$2,$3,$4)
+        RETURNING `+dataExportColumns,
+		uuid.NewString(), userID, model.DataExportPending, time.Now().UTC(),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return e, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *dataExportRepo) FindLatest(ctx context.Context, userID string) (*model.DataExport, error) {
+	return scanDataExport(r.db.QueryRow(ctx, `
+        SELECT `+dataExportColumns+`
+        FROM data_exports
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+        LIMIT 1`, userID))
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GroupRepository keeps the user groups of the organization the context acts
+// in. Members can only be users of that organization.
+type GroupRepository interface {
+	// List returns the groups by name.
+	List(ctx context.Context) ([]model.Group, error)
+	Find(ctx context.Context, id string) (*model.Group, error)
+	// ListForUser returns the groups the user is in, by name.
+	ListForUser(ctx context.Context, userID string) ([]model.Group, error)
+	Create(ctx context.Context, name, description string) (*model.Group, error)
+	Update(ctx context.Context, id, name, description string) (*model.Group, error)
+	// Delete removes the group and returns it as it was.
+	Delete(ctx context.Context, id string) (*model.Group, error)
+	// AddMembers adds the users to the group and returns the ids of those
+	// who were not in it yet. It fails when any of them is not found.
+	AddMembers(ctx context.Context, id string, userIDs []string) ([]string, error)
+	RemoveMember(ctx context.Context, id, userID string) error
+}
+
+const groupColumns = `g.id, g.org_id, g.name, g.description,
+    (SELECT count(*) FROM user_group_members m WHERE m.group_id = g.id),
+    g.updated_at, g.created_at`
+
+type groupRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewGroupRepo(pool *pgxpool.Pool) GroupRepository {
+	return &groupRepo{db: pool}
+}
+
+func scanGroup(row pgx.Row) (*model.Group, error) {
+	var g model.Group
+	err := row.Scan(&g.ID, &g.OrgID, &g.Name, &g.Description, &g.MemberCount, &g.UpdatedAt, &g.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Group not found"), "")
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, errors.WithInvalid(errors.New("Group name existed"), "")
+	}
+	return &g, errors.WithStack(err)
+}
+
+func (r *groupRepo) list(ctx context.Context, query string, args ...any) ([]model.Group, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	groups := []model.Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, *g)
+	}
+	return groups, errors.WithStack(rows.Err())
+}
+
+func (r *groupRepo) List(ctx context.Context) ([]model.Group, error) {
+	return r.list(ctx, `
+        SELECT `+groupColumns+`
+        FROM user_groups g
+        WHERE g.org_id::text = $1
+        ORDER BY lower(g.name)`, tenant.OrgID(ctx))
+}
+
+func (r *groupRepo) Find(ctx context.Context, id string) (*model.Group, error) {
+	return scanGroup(r.db.QueryRow(ctx, `
+        SELECT `+groupColumns+`
+        FROM user_groups g
+        WHERE g.id = $1 AND g.org_id::text = $2`, id, tenant.OrgID(ctx)))
+}
+
+func (r *groupRepo) ListForUser(ctx context.Context, userID string) ([]model.Group, error) {
+	return r.list(ctx, `
+        SELECT `+groupColumns+`
+        FROM user_groups g JOIN user_group_members gm ON gm.group_id = g.id
+        WHERE gm.user_id = $1 AND g.org_id::text = $2
+        ORDER BY lower(g.name)`, userID, tenant.OrgID(ctx))
+}
+
+func (r *groupRepo) Create(ctx context.Context, name, description string) (*model.Group, error) {
+	now := time.Now().UTC()
+	return scanGroup(r.db.QueryRow(ctx, `
+        INSERT INTO user_groups AS g (id,org_id,name,description,updated_at,created_at)
+        VALUES ($1,$2,$3,$4,$5,$5)
+        RETURNING `+groupColumns,
+		uuid.NewString(), tenant.OrgID(ctx), name, description, now,
+	))
+}
+
+func (r *groupRepo) Update(ctx context.Context, id, name, description string) (*model.Group, error) {
+	return scanGroup(r.db.QueryRow(ctx, `
+        UPDATE user_groups AS g
+        SET name = $1, description = $2, updated_at = $3
+        WHERE g.id = $4 AND g.org_id::text = $5
+        RETURNING `+groupColumns,
+		name, description, time.Now().UTC(), id, tenant.OrgID(ctx),
+	))
+}
+
+func (r *groupRepo) Delete(ctx context.Context, id string) (*model.Group, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	g, err := scanGroup(tx.QueryRow(ctx, `
+        SELECT `+groupColumns+`
+        FROM user_groups g
+        WHERE g.id = $1 AND g.org_id::text = $2
+        FOR UPDATE`, id, tenant.OrgID(ctx)))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_groups WHERE id = $1`, id); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return g, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *groupRepo) AddMembers(ctx context.Context, id string, userIDs []string) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := scanGroup(tx.QueryRow(ctx, `
+        SELECT `+groupColumns+`
+        FROM user_groups g
+        WHERE g.id = $1 AND g.org_id::text = $2
+        FOR UPDATE`, id, tenant.OrgID(ctx))); err != nil {
+		return nil, err
+	}
+
+	var found int
+	err = tx.QueryRow(ctx, `SELECT count(*) FROM users WHERE id = ANY($1) AND `+userInTenant, userIDs).Scan(&found)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if found < len(userIDs) {
+		return nil, errors.WithNotFound(errors.New("User not found"), "")
+	}
+
+	rows, err := tx.Query(ctx, `
+        INSERT INTO user_group_members (group_id,user_id,created_at)
+        SELECT $1, u, $3 FROM unnest($2::uuid[]) AS u
+        ON CONFLICT DO NOTHING
+        RETURNING user_id`, id, userIDs, time.Now().UTC())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	added := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		added = append(added, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	rows.Close()
+	return added, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *groupRepo) RemoveMember(ctx context.Context, id, userID string) error {
+	tag, err := r.db.Exec(ctx, `
+        DELETE FROM user_group_members m
+        USING user_groups g
+        WHERE g.id = m.group_id AND m.group_id = $1 AND m.user_id = $2 AND g.org_id::text = $3`,
+		id, userID, tenant.OrgID(ctx))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("Member not found"), "")
+	}
+	return nil
+}
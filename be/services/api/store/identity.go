@@ -0,0 +1,85 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdentityRepository stores the external identities linked to users and the
+// pending OpenID Connect authorization requests.
+type IdentityRepository interface {
+	Find(ctx context.Context, provider, subject string) (*model.Identity, error)
+	Create(ctx context.Context, identity model.Identity) error
+
+	CreateAuthRequest(ctx context.Context, stateHash, provider, codeVerifier, nonce string, expiresAt time.Time) error
+	ConsumeAuthRequest(ctx context.Context, stateHash, provider string) (codeVerifier, nonce string, err error)
+}
+
+type identityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepo(pool *pgxpool.Pool) IdentityRepository {
+	return &identityRepo{db: pool}
+}
+
+func (r *identityRepo) Find(ctx context.Context, provider, subject string) (*model.Identity, error) {
+	var i model.Identity
+	err := r.db.QueryRow(ctx, `
+        SELECT provider, subject, user_id, email, created_at
+        FROM user_identities
+        WHERE provider = $1 AND subject = $2
+    `, provider, subject).Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Identity not found"), "")
+	}
+	return &i, errors.WithStack(err)
+}
+
+func (r *identityRepo) Create(ctx context.Context, i model.Identity) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO user_identities (provider,subject,user_id,email,created_at) VALUES ($1,$2,$3,$4,$5)`,
+		i.Provider, i.Subject, i.UserID, i.Email, time.Now().UTC(),
+	)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return errors.WithInvalid(errors.New("Identity already linked"), "")
+	}
+	return errors.WithStack(err)
+}
+
+func (r *identityRepo) CreateAuthRequest(ctx context.Context, stateHash, provider, codeVerifier, nonce string, expiresAt time.Time) error {
+	// abandoned logins are never consumed, clean them up as we go
+	_, err := r.db.Exec(ctx, `DELETE FROM oidc_auth_requests WHERE expires_at <= $1`, time.Now().UTC())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = r.db.Exec(ctx, `
+        INSERT INTO oidc_auth_requests (state_hash,provider,code_verifier,nonce,expires_at,created_at)
+        VALUES ($1,$2,$3,$4,$5,$6)
+    `, stateHash, provider, codeVerifier, nonce, expiresAt, time.Now().UTC())
+	return errors.WithStack(err)
+}
+
+// ConsumeAuthRequest deletes the pending request so a state can only be used
+// once, and returns its PKCE verifier and nonce.
+func (r *identityRepo) ConsumeAuthRequest(ctx context.Context, stateHash, provider string) (string, string, error) {
+	var codeVerifier, nonce string
+	err := r.db.QueryRow(ctx, `
+        DELETE FROM oidc_auth_requests
+        WHERE state_hash = $1 AND provider = $2 AND expires_at > $3
+        RETURNING code_verifier, nonce
+    `, stateHash, provider, time.Now().UTC()).Scan(&codeVerifier, &nonce)
+	if err == pgx.ErrNoRows {
+		return "", "", errors.WithNotFound(errors.New("Invalid or expired state"), "")
+	}
+	return codeVerifier, nonce, errors.WithStack(err)
+}
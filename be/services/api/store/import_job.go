@@ -0,0 +1,78 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ImportJobRepository keeps the import jobs of the organization the context
+// acts in.
+type ImportJobRepository interface {
+	Create(ctx context.Context, format, createdBy string, data []byte) (*model.ImportJob, error)
+	// Find returns the job without its data.
+	Find(ctx context.Context, id string) (*model.ImportJob, error)
+	ListErrors(ctx context.Context, id string) ([]model.ImportRowError, error)
+}
+
+const importJobColumns = `id, format, status, created_by, org_id, total_rows, processed_rows, created_rows, failed_rows, error, started_at, completed_at, created_at`
+
+type importJobRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewImportJobRepo(pool *pgxpool.Pool) ImportJobRepository {
+	return &importJobRepo{db: pool}
+}
+
+func scanImportJob(row pgx.Row) (*model.ImportJob, error) {
+	var j model.ImportJob
+	err := row.Scan(&j.ID, &j.Format, &j.Status, &j.CreatedBy, &j.OrgID, &j.TotalRows, &j.ProcessedRows, &j.CreatedRows, &j.FailedRows,
+		&j.Error, &j.StartedAt, &j.CompletedAt, &j.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Import job not found"), "")
+	}
+	return &j, errors.WithStack(err)
+}
+
+func (r *importJobRepo) Create(ctx context.Context, format, createdBy string, data []byte) (*model.ImportJob, error) {
+	return scanImportJob(r.db.QueryRow(ctx, `
+        INSERT INTO import_jobs (id,format,status,created_by,org_id,data,created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7)
+        RETURNING `+importJobColumns,
+		uuid.NewString(), format, model.ImportPending, createdBy, tenant.OrgID(ctx), data, time.Now().UTC(),
+	))
+}
+
+func (r *importJobRepo) Find(ctx context.Context, id string) (*model.ImportJob, error) {
+	return scanImportJob(r.db.QueryRow(ctx, `SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1 AND org_id::text = $2`,
+		id, tenant.OrgID(ctx)))
+}
+
+func (r *importJobRepo) ListErrors(ctx context.Context, id string) ([]model.ImportRowError, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT row_number, email, message
+        FROM import_job_errors
+        WHERE job_id = $1
+        ORDER BY row_number`, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	rowErrors := []model.ImportRowError{}
+	for rows.Next() {
+		var e model.ImportRowError
+		if err := rows.Scan(&e.Row, &e.Email, &e.Message); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rowErrors = append(rowErrors, e)
+	}
+	return rowErrors, errors.WithStack(rows.Err())
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InvitationRepository interface {
+	// Create fails when the email has a pending invitation to the
+	// organization already.
+	Create(ctx context.Context, inv model.Invitation, tokenHash string) (*model.Invitation, error)
+	// List and Revoke only see the invitations of the given organization.
+	List(ctx context.Context, orgID string) ([]model.Invitation, error)
+	Revoke(ctx context.Context, orgID, id string) (*model.Invitation, error)
+	// Accept creates the user the pending invitation was sent to, a member of
+	// its organization with its role, and returns the accepted invitation.
+	// The email is taken as verified since the invitation was mailed to it.
+	Accept(ctx context.Context, tokenHash, email, hashedPassword string) (*model.Invitation, error)
+	// Join makes the existing user with the given email a member of the
+	// organization of the pending invitation, with its role, and returns the
+	// accepted invitation.
+	Join(ctx context.Context, tokenHash, userID, email string) (*model.Invitation, error)
+	// Expire marks the pending invitations that expired before now and
+	// returns them.
+	Expire(ctx context.Context, now time.Time) ([]model.Invitation, error)
+}
+
+const invitationColumns = `id, org_id, email, role, status, invited_by, user_id, expires_at, accepted_at, revoked_at, created_at`
+
+type invitationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInvitationRepo(pool *pgxpool.Pool) InvitationRepository {
+	return &invitationRepo{db: pool}
+}
+
+func scanInvitation(row pgx.Row) (*model.Invitation, error) {
+	var inv model.Invitation
+	err := row.Scan(&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.Status, &inv.InvitedBy, &inv.UserID,
+		&inv.ExpiresAt, &inv.AcceptedAt, &inv.RevokedAt, &inv.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Invitation not found"), "")
+	}
+	return &inv, errors.WithStack(err)
+}
+
+func (r *invitationRepo) list(ctx context.Context, query string, args ...any) ([]model.Invitation, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	invitations := []model.Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *inv)
+	}
+	return invitations, errors.WithStack(rows.Err())
+}
+
+func (r *invitationRepo) Create(ctx context.Context, inv model.Invitation, tokenHash string) (*model.Invitation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	var pending bool
+	err = tx.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM invitations
+            WHERE org_id = $1 AND lower(email) = lower($2) AND status = $3 AND expires_at > $4
+        )`, inv.OrgID, inv.Email, model.InvitationPending, now).Scan(&pending)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if pending {
+		return nil, errors.WithInvalid(errors.New("Invitation already pending"), "")
+	}
+
+	created, err := scanInvitation(tx.QueryRow(ctx, `
+        INSERT INTO invitations (id,org_id,email,role,token_hash,status,invited_by,expires_at,created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+        RETURNING `+invitationColumns,
+		uuid.NewString(), inv.OrgID, inv.Email, inv.Role, tokenHash, model.InvitationPending, inv.InvitedBy, inv.ExpiresAt, now,
+	))
+	if err != nil {
+		return nil, err
+	}
+	return created, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *invitationRepo) List(ctx context.Context, orgID string) ([]model.Invitation, error) {
+	return r.list(ctx, `SELECT `+invitationColumns+` FROM invitations WHERE org_id = $1 ORDER BY created_at DESC`, orgID)
+}
+
+func (r *invitationRepo) Revoke(ctx context.Context, orgID, id string) (*model.Invitation, error) {
+	return scanInvitation(r.db.QueryRow(ctx, `
+        UPDATE invitations
+        SET status = $1, revoked_at = $2
+        WHERE id = $3 AND org_id = $4 AND status = $5
+        RETURNING `+invitationColumns,
+		model.InvitationRevoked, time.Now().UTC(), id, orgID, model.InvitationPending,
+	))
+}
+
+func (r *invitationRepo) Accept(ctx context.Context, tokenHash, email, hashedPassword string) (*model.Invitation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	inv, err := pendingInvitation(ctx, tx, tokenHash, email, now)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := uuid.NewString()
+	_, err = tx.Exec(ctx, `
+        INSERT INTO users (id,email,password,email_verified_at,created_at)
+        VALUES ($1,$2,$3,$4,$4)`, userID, email, hashedPassword, now)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, errors.WithInvalid(errors.New("Email existed"), "")
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO organization_members (org_id,user_id,role,created_at)
+        VALUES ($1,$2,$3,$4)`, inv.OrgID, userID, inv.Role, now)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	accepted, err := markAccepted(ctx, tx, inv.ID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return accepted, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *invitationRepo) Join(ctx context.Context, tokenHash, userID, email string) (*model.Invitation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	inv, err := pendingInvitation(ctx, tx, tokenHash, email, now)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+        INSERT INTO organization_members (org_id,user_id,role,created_at)
+        VALUES ($1,$2,$3,$4)
+        ON CONFLICT (org_id, user_id) DO NOTHING`, inv.OrgID, userID, inv.Role, now)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, errors.WithInvalid(errors.New("Already a member of the organization"), "")
+	}
+
+	accepted, err := markAccepted(ctx, tx, inv.ID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return accepted, errors.WithStack(tx.Commit(ctx))
+}
+
+// pendingInvitation locks the pending invitation with the token, which must
+// have been sent to email.
+func pendingInvitation(ctx context.Context, tx pgx.Tx, tokenHash, email string, now time.Time) (*model.Invitation, error) {
+	inv, err := scanInvitation(tx.QueryRow(ctx, `
+        SELECT `+invitationColumns+`
+        FROM invitations
+        WHERE token_hash = $1 AND status = $2 AND expires_at > $3
+        FOR UPDATE`, tokenHash, model.InvitationPending, now))
+	if errors.IsNotFound(err) {
+		return nil, errors.WithInvalid(errors.New("Invalid or expired invitation"), "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(inv.Email, email) {
+		return nil, errors.WithInvalid(errors.New("Invitation was sent to another email"), "")
+	}
+	return inv, nil
+}
+
+func markAccepted(ctx context.Context, tx pgx.Tx, id, userID string, now time.Time) (*model.Invitation, error) {
+	return scanInvitation(tx.QueryRow(ctx, `
+        UPDATE invitations
+        SET status = $1, user_id = $2, accepted_at = $3
+        WHERE id = $4
+        RETURNING `+invitationColumns,
+		model.InvitationAccepted, userID, now, id,
+	))
+}
+
+func (r *invitationRepo) Expire(ctx context.Context, now time.Time) ([]model.Invitation, error) {
+	return r.list(ctx, `
+        UPDATE invitations
+        SET status = $1
+        WHERE status = $2 AND expires_at <= $3
+        RETURNING `+invitationColumns,
+		model.InvitationExpired, model.InvitationPending, now,
+	)
+}
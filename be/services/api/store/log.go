@@ -3,6 +3,7 @@ package store
 import (
 	"be/pkg/errors"
 	"be/pkg/model"
+	"be/pkg/tenant"
 	"context"
 	"time"
 
@@ -11,8 +12,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// LogRepository reads the user logs of the organization the context acts in.
 type LogRepository interface {
 	List(ctx context.Context, limit int, cursor string) ([]model.UserLogs, string, error)
+	// Stream calls fn with every log entry, newest first, a page at a time.
+	Stream(ctx context.Context, fn func(*model.UserLogs) error) error
 }
 
 type logRepo struct {
@@ -28,11 +32,12 @@ func NewLogRepo(cfg aws.Config, table string) LogRepository {
 }
 
 func (r *logRepo) List(ctx context.Context, limit int, cursor string) ([]model.UserLogs, string, error) {
+	pk := model.LogPartition(tenant.OrgID(ctx))
 	params := &dynamodb.QueryInput{
 		TableName:              &r.table,
 		KeyConditionExpression: aws.String("PK = :pk"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "logs"},
+			":pk": &types.AttributeValueMemberS{Value: pk},
 		},
 		Limit:            aws.Int32(int32(limit)),
 		ScanIndexForward: aws.Bool(false),
@@ -40,7 +45,7 @@ func (r *logRepo) List(ctx context.Context, limit int, cursor string) ([]model.U
 
 	if len(cursor) > 0 {
 		params.ExclusiveStartKey = map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: "logs"},
+			"PK": &types.AttributeValueMemberS{Value: pk},
 			"SK": &types.AttributeValueMemberS{
 				Value: cursor,
 			},
@@ -54,17 +59,11 @@ func (r *logRepo) List(ctx context.Context, limit int, cursor string) ([]model.U
 
 	logs := make([]model.UserLogs, 0, len(out.Items))
 	for _, it := range out.Items {
-		createdAt, err := time.Parse(time.RFC3339Nano, it["created_at"].(*types.AttributeValueMemberS).Value)
+		l, err := userLogOf(it)
 		if err != nil {
-			return nil, "", errors.WithStack(err)
+			return nil, "", err
 		}
-
-		logs = append(logs, model.UserLogs{
-			UserID:    it["user_id"].(*types.AttributeValueMemberS).Value,
-			EventType: it["event_type"].(*types.AttributeValueMemberS).Value,
-			Details:   it["details"].(*types.AttributeValueMemberS).Value,
-			CreatedAt: createdAt,
-		})
+		logs = append(logs, *l)
 	}
 
 	var nextCursor string
@@ -74,3 +73,63 @@ func (r *logRepo) List(ctx context.Context, limit int, cursor string) ([]model.U
 
 	return logs, nextCursor, nil
 }
+
+func (r *logRepo) Stream(ctx context.Context, fn func(*model.UserLogs) error) error {
+	pk := model.LogPartition(tenant.OrgID(ctx))
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              &r.table,
+		KeyConditionExpression: aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, it := range out.Items {
+			l, err := userLogOf(it)
+			if err != nil {
+				return err
+			}
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func userLogOf(it map[string]types.AttributeValue) (*model.UserLogs, error) {
+	id := stringAttr(it, "SK")
+	if id == "" {
+		return nil, errors.New("Log entry has no SK")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, stringAttr(it, "created_at"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "log entry %s", id)
+	}
+
+	// the worker leaves user_id out of entries without a user
+	return &model.UserLogs{
+		ID:           id,
+		UserID:       stringAttr(it, "user_id"),
+		EventType:    stringAttr(it, "event_type"),
+		Details:      stringAttr(it, "details"),
+		Actor:        stringAttr(it, "actor"),
+		Impersonator: stringAttr(it, "impersonator"),
+		CreatedAt:    createdAt,
+	}, nil
+}
+
+// stringAttr returns the string attribute of the item, empty when it is
+// missing or of another type.
+func stringAttr(it map[string]types.AttributeValue, name string) string {
+	if v, ok := it[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
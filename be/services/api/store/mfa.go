@@ -0,0 +1,143 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"be/pkg/secretbox"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MFARepository stores TOTP enrollments. Secrets are encrypted before they
+// reach Postgres and decrypted on read.
+type MFARepository interface {
+	Save(ctx context.Context, userID, secret string, recoveryCodeHashes []string) error
+	Find(ctx context.Context, userID string) (*model.MFA, error)
+	Confirm(ctx context.Context, userID string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	// UseTOTPStep records the time step of an accepted TOTP code. It fails
+	// with not found when a code of that step or a later one was accepted
+	// already.
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	Delete(ctx context.Context, userID string) error
+}
+
+type mfaRepo struct {
+	db  *pgxpool.Pool
+	box *secretbox.Box
+}
+
+func NewMFARepo(pool *pgxpool.Pool, box *secretbox.Box) MFARepository {
+	return &mfaRepo{db: pool, box: box}
+}
+
+// Save starts a new, unconfirmed enrollment, replacing any previous one that
+// was never confirmed.
+func (r *mfaRepo) Save(ctx context.Context, userID, secret string, recoveryCodeHashes []string) error {
+	encrypted, err := r.box.Seal([]byte(secret))
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.Exec(ctx, `
+        INSERT INTO user_mfa (user_id, secret_encrypted, recovery_codes, created_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id) DO UPDATE
+        SET secret_encrypted = EXCLUDED.secret_encrypted,
+            recovery_codes = EXCLUDED.recovery_codes,
+            created_at = EXCLUDED.created_at
+        WHERE user_mfa.confirmed_at IS NULL
+    `, userID, encrypted, recoveryCodeHashes, time.Now().UTC())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errors.WithInvalid(errors.New("Two-factor authentication already enabled"), "")
+	}
+
+	return nil
+}
+
+func (r *mfaRepo) Find(ctx context.Context, userID string) (*model.MFA, error) {
+	var m model.MFA
+	var encrypted string
+	err := r.db.QueryRow(ctx, `
+        SELECT user_id, secret_encrypted, recovery_codes, confirmed_at, created_at
+        FROM user_mfa
+        WHERE user_id = $1
+    `, userID).Scan(&m.UserID, &encrypted, &m.RecoveryCodes, &m.ConfirmedAt, &m.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Two-factor authentication not enrolled"), "")
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	secret, err := r.box.Open(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	m.Secret = string(secret)
+	return &m, nil
+}
+
+func (r *mfaRepo) Confirm(ctx context.Context, userID string) error {
+	res, err := r.db.Exec(ctx, `
+        UPDATE user_mfa
+        SET confirmed_at = $1
+        WHERE user_id = $2 AND confirmed_at IS NULL
+    `, time.Now().UTC(), userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("Two-factor authentication not enrolled"), "")
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes a recovery code so it cannot be used twice.
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	res, err := r.db.Exec(ctx, `
+        UPDATE user_mfa
+        SET recovery_codes = array_remove(recovery_codes, $1)
+        WHERE user_id = $2 AND $1 = ANY(recovery_codes)
+    `, codeHash, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("Recovery code not found"), "")
+	}
+
+	return nil
+}
+
+func (r *mfaRepo) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	res, err := r.db.Exec(ctx, `
+        UPDATE user_mfa
+        SET last_totp_step = $1
+        WHERE user_id = $2 AND (last_totp_step IS NULL OR last_totp_step < $1)
+    `, step, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("TOTP code already used"), "")
+	}
+
+	return nil
+}
+
+func (r *mfaRepo) Delete(ctx context.Context, userID string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/migrate"
+	"context"
+	"embed"
+	"io/fs"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MigrationsDir is where the migrations are kept, relative to the api
+// service. They are embedded in the binary when it is built.
+const MigrationsDir = "store/migrations"
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the advisory lock replicas take to migrate one at a
+// time, any number unlikely to be used by another lock.
+const migrationLockKey int64 = 7_160_832_019_441_557_001
+
+// Migrator applies the embedded migrations to the database, and records them
+// in the schema_migrations table. Calls wait for the ones running from other
+// replicas to finish.
+type Migrator interface {
+	Status(ctx context.Context) ([]migrate.Status, error)
+	// Up applies the pending migrations and returns them. It refuses to run
+	// on a database that has the schema but no recorded migrations, which
+	// must be baselined first.
+	Up(ctx context.Context) ([]migrate.Migration, error)
+	// Down reverts the last n applied migrations and returns them.
+	Down(ctx context.Context, n int) ([]migrate.Migration, error)
+	// Baseline records the first migration as applied without running it,
+	// for databases created before migrations, when the schema of the first
+	// migration was loaded on the first start of Postgres. Up then applies
+	// the later ones. It returns the migration recorded.
+	Baseline(ctx context.Context) (*migrate.Migration, error)
+}
+
+type migrator struct {
+	db         *pgxpool.Pool
+	migrations []migrate.Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool) (Migrator, error) {
+	dir, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	migrations, err := migrate.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{db: pool, migrations: migrations}, nil
+}
+
+func (m *migrator) Status(ctx context.Context) ([]migrate.Status, error) {
+	var statuses []migrate.Status
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied []migrate.Applied) error {
+		statuses = migrate.Statuses(m.migrations, applied)
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *migrator) Up(ctx context.Context) ([]migrate.Migration, error) {
+	var done []migrate.Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied []migrate.Applied) error {
+		if len(applied) == 0 {
+			legacy, err := hasLegacySchema(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if legacy {
+				return errors.New("Database has a schema but no recorded migrations, run `migrate baseline` once to record it")
+			}
+		}
+
+		pending, err := migrate.Pending(m.migrations, applied)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range pending {
+			if err := m.run(ctx, conn, mig, mig.Up, `
+                INSERT INTO schema_migrations (version,name,checksum,applied_at)
+                VALUES ($1,$2,$3,$4)`, mig.Version, mig.Name, mig.Checksum(), time.Now().UTC()); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *migrator) Down(ctx context.Context, n int) ([]migrate.Migration, error) {
+	var done []migrate.Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied []migrate.Applied) error {
+		revert, err := migrate.Rollback(m.migrations, applied, n)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range revert {
+			if err := m.run(ctx, conn, mig, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *migrator) Baseline(ctx context.Context) (*migrate.Migration, error) {
+	if len(m.migrations) == 0 {
+		return nil, errors.New("No migration to record")
+	}
+
+	baseline := m.migrations[0]
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied []migrate.Applied) error {
+		if len(applied) > 0 {
+			return errors.New("Migrations are already recorded")
+		}
+		legacy, err := hasLegacySchema(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if !legacy {
+			return errors.New("Database has no schema to baseline, run `migrate up` instead")
+		}
+		same, err := hasBaselineSchema(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if !same {
+			return errors.New("Database schema differs from the baseline, it cannot be recorded")
+		}
+
+		_, err = conn.Exec(ctx, `
+            INSERT INTO schema_migrations (version,name,checksum,applied_at)
+            VALUES ($1,$2,$3,$4)`, baseline.Version, baseline.Name, baseline.Checksum(), time.Now().UTC())
+		return errors.WithStack(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &baseline, nil
+}
+
+// hasLegacySchema tells whether the tables of the baseline exist already.
+func hasLegacySchema(ctx context.Context, conn *pgxpool.Conn) (bool, error) {
+	var legacy bool
+	err := conn.QueryRow(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&legacy)
+	return legacy, errors.WithStack(err)
+}
+
+// hasBaselineSchema tells whether the schema is the one of the baseline, the
+// original users table and nothing else, which the later migrations expect to
+// change.
+func hasBaselineSchema(ctx context.Context, conn *pgxpool.Conn) (bool, error) {
+	var same bool
+	err := conn.QueryRow(ctx, `
+        SELECT
+          (SELECT array_agg(column_name::text ORDER BY column_name)
+           FROM information_schema.columns
+           WHERE table_schema = current_schema() AND table_name = 'users')
+          = ARRAY['created_at','email','id','name','password']
+          AND NOT EXISTS (
+            SELECT 1 FROM information_schema.tables
+            WHERE table_schema = current_schema()
+              AND table_name NOT IN ('users', 'schema_migrations'))`).Scan(&same)
+	return same, errors.WithStack(err)
+}
+
+// run executes the script of mig and the query recording it in a single
+// transaction, so a failed migration leaves nothing behind.
+func (m *migrator) run(ctx context.Context, conn *pgxpool.Conn, mig migrate.Migration, script, record string, args ...any) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	// without arguments the script is sent as is, and may hold many statements
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return errors.Wrapf(err, "migration %d_%s", mig.Version, mig.Name)
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(tx.Commit(ctx))
+}
+
+// withLock calls fn with the applied migrations while holding the migration
+// lock, on the connection the lock is held by.
+func (m *migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, applied []migrate.Applied) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		// closing the connection releases the lock when unlocking fails
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+	}()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	applied := []migrate.Applied{}
+	for rows.Next() {
+		var a migrate.Applied
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return errors.WithStack(err)
+		}
+		applied = append(applied, a)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	rows.Close()
+
+	return fn(conn, applied)
+}
+
+// ensureTable creates the schema_migrations table when missing.
+func (m *migrator) ensureTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+          version BIGINT PRIMARY KEY,
+          name TEXT NOT NULL,
+          checksum TEXT NOT NULL,
+          applied_at TIMESTAMPTZ NOT NULL
+        )`)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"be/pkg/migrate"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// legacySchema is the schema Postgres loaded on its first start before
+// migrations.
+const legacySchema = `
+CREATE TABLE users (
+  id UUID PRIMARY KEY,
+  name VARCHAR(50),
+  email VARCHAR(50) NOT NULL,
+  password TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX users_email_unique_idx ON users(email);
+`
+
+// testPool connects to the database in MIGRATION_TEST_PG_URI, in a schema of
+// its own dropped at the end of the test, or skips the test without it.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	uri := os.Getenv("MIGRATION_TEST_PG_URI")
+	if uri == "" {
+		t.Skip("MIGRATION_TEST_PG_URI is not set")
+	}
+	ctx := context.Background()
+
+	schema := fmt.Sprintf("migration_test_%d", time.Now().UnixNano())
+	admin, err := pgxpool.New(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Error(err)
+		}
+	})
+
+	config, err := pgxpool.ParseConfig(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// public stays reachable for the extensions installed there
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestMigratorBaselineLegacySchema(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	if _, err := pool.Exec(ctx, legacySchema); err != nil {
+		t.Fatal(err)
+	}
+	userID := "7b1f5c3e-2d4a-4e8b-9c6f-0a1b2c3d4e5f"
+	if _, err := pool.Exec(ctx, `
+        INSERT INTO users (id,name,email,password,created_at)
+        VALUES ($1,'Legacy','legacy@example.com','hash',NOW())`, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err == nil {
+		t.Fatal("expected up to refuse a schema without recorded migrations")
+	}
+
+	baseline, err := migrator.Baseline(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if baseline.Name != "baseline" {
+		t.Fatalf("expected the baseline to be recorded, got %s", baseline.Name)
+	}
+	if _, err := migrator.Baseline(ctx); err == nil {
+		t.Fatal("expected a second baseline to fail")
+	}
+
+	done, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) == 0 {
+		t.Fatal("expected the later migrations to be applied")
+	}
+	assertAllApplied(t, migrator)
+	assertLegacyUserMigrated(t, pool, userID)
+
+	// every migration reverts down to the baseline and applies again
+	if _, err := migrator.Down(ctx, len(done)); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := hasBaselineSchema(ctx, conn)
+	conn.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same {
+		t.Fatal("expected down to restore the baseline schema")
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertAllApplied(t, migrator)
+	assertLegacyUserMigrated(t, pool, userID)
+}
+
+func TestMigratorBaselineRejectsChangedSchema(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	if _, err := pool.Exec(ctx, legacySchema+`ALTER TABLE users ADD COLUMN nickname TEXT;`); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Baseline(ctx); err == nil {
+		t.Fatal("expected baseline to refuse a schema changed by hand")
+	}
+}
+
+func assertAllApplied(t *testing.T, migrator Migrator) {
+	t.Helper()
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.State != migrate.StateApplied {
+			t.Errorf("expected %d_%s to be applied, got %s", s.Version, s.Name, s.State)
+		}
+	}
+}
+
+// assertLegacyUserMigrated checks the user created before migrations is an
+// active member of the default organization.
+func assertLegacyUserMigrated(t *testing.T, pool *pgxpool.Pool, userID string) {
+	t.Helper()
+	var role, status string
+	err := pool.QueryRow(context.Background(), `
+        SELECT role, status FROM organization_members
+        WHERE org_id = $1 AND user_id = $2`, model.DefaultOrgID, userID).Scan(&role, &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != "user" || status != "active" {
+		t.Errorf("expected an active user, got role %s and status %s", role, status)
+	}
+}
@@ -0,0 +1,11 @@
+-- The schema loaded on the first start of Postgres before migrations, which
+-- `migrate baseline` records as applied. Every later change is a migration.
+CREATE TABLE users (
+  id UUID PRIMARY KEY,
+  name VARCHAR(50),
+  email VARCHAR(50) NOT NULL,
+  password TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX users_email_unique_idx ON users(email);
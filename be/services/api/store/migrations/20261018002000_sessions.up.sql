@@ -0,0 +1,11 @@
+CREATE TABLE sessions (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  refresh_token_hash TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX sessions_refresh_token_hash_unique_idx ON sessions(refresh_token_hash);
+CREATE INDEX sessions_user_id_idx ON sessions(user_id);
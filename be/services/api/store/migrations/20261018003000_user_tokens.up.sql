@@ -0,0 +1,10 @@
+CREATE TABLE user_tokens (
+  token_hash TEXT PRIMARY KEY,
+  kind VARCHAR(30) NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX user_tokens_user_id_kind_idx ON user_tokens(user_id, kind);
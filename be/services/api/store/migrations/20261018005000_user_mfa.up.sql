@@ -0,0 +1,7 @@
+CREATE TABLE user_mfa (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret_encrypted TEXT NOT NULL,
+  recovery_codes TEXT[] NOT NULL,
+  confirmed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE oidc_auth_requests, user_identities;
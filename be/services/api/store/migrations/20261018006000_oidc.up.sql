@@ -0,0 +1,19 @@
+CREATE TABLE user_identities (
+  provider VARCHAR(50) NOT NULL,
+  subject TEXT NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email VARCHAR(50) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities(user_id);
+
+CREATE TABLE oidc_auth_requests (
+  state_hash TEXT PRIMARY KEY,
+  provider VARCHAR(50) NOT NULL,
+  code_verifier TEXT NOT NULL,
+  nonce TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,14 @@
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  key_hash TEXT NOT NULL,
+  prefix VARCHAR(20) NOT NULL,
+  scopes TEXT[] NOT NULL,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  expires_at TIMESTAMP,
+  last_used_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX api_keys_key_hash_unique_idx ON api_keys(key_hash);
@@ -0,0 +1,6 @@
+CREATE TABLE sign_in_attempts (
+  key TEXT PRIMARY KEY,
+  failures INT NOT NULL,
+  window_started_at TIMESTAMP NOT NULL,
+  blocked_until TIMESTAMP
+);
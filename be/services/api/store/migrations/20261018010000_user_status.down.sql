@@ -0,0 +1,4 @@
+ALTER TABLE users
+  DROP COLUMN status,
+  DROP COLUMN suspended_at,
+  DROP COLUMN deleted_at;
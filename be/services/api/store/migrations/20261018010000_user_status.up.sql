@@ -0,0 +1,6 @@
+ALTER TABLE users
+  ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+  ADD COLUMN suspended_at TIMESTAMP,
+  ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX users_status_idx ON users(status);
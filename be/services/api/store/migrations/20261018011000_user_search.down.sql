@@ -0,0 +1,2 @@
+-- pg_trgm is left installed, other schemas may use it
+DROP INDEX users_created_at_idx, users_email_trgm_idx, users_name_trgm_idx;
@@ -0,0 +1,23 @@
+CREATE TABLE import_jobs (
+  id UUID PRIMARY KEY,
+  format VARCHAR(10) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  created_by TEXT NOT NULL,
+  data BYTEA NOT NULL,
+  total_rows INT NOT NULL DEFAULT 0,
+  processed_rows INT NOT NULL DEFAULT 0,
+  created_rows INT NOT NULL DEFAULT 0,
+  failed_rows INT NOT NULL DEFAULT 0,
+  error TEXT,
+  started_at TIMESTAMP,
+  completed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE import_job_errors (
+  job_id UUID NOT NULL REFERENCES import_jobs(id) ON DELETE CASCADE,
+  row_number INT NOT NULL,
+  email TEXT NOT NULL,
+  message TEXT NOT NULL,
+  PRIMARY KEY (job_id, row_number)
+);
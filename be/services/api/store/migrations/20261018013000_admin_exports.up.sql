@@ -0,0 +1,14 @@
+CREATE TABLE admin_exports (
+  id UUID PRIMARY KEY,
+  kind VARCHAR(20) NOT NULL,
+  format VARCHAR(10) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  created_by TEXT NOT NULL,
+  filter JSONB NOT NULL DEFAULT '{}',
+  file BYTEA,
+  error TEXT,
+  completed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX admin_exports_created_at_idx ON admin_exports(created_at);
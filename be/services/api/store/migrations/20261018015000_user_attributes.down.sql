@@ -0,0 +1,3 @@
+DROP TABLE user_attribute_definitions;
+
+ALTER TABLE users DROP COLUMN attributes;
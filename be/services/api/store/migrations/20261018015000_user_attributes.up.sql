@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX users_attributes_idx ON users USING gin (attributes jsonb_path_ops);
+
+CREATE TABLE user_attribute_definitions (
+  name VARCHAR(50) PRIMARY KEY,
+  type VARCHAR(20) NOT NULL,
+  required BOOLEAN NOT NULL DEFAULT FALSE,
+  enum TEXT[] NOT NULL DEFAULT '{}',
+  max_length INT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,24 @@
+DROP POLICY users_tenant ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+-- what other organizations held is dropped with them
+DELETE FROM sessions WHERE org_id <> '00000000-0000-0000-0000-000000000001';
+DELETE FROM api_keys WHERE org_id <> '00000000-0000-0000-0000-000000000001';
+DELETE FROM import_jobs WHERE org_id <> '00000000-0000-0000-0000-000000000001';
+DELETE FROM admin_exports WHERE org_id <> '00000000-0000-0000-0000-000000000001';
+
+ALTER TABLE sessions DROP COLUMN org_id;
+ALTER TABLE api_keys DROP COLUMN org_id;
+ALTER TABLE import_jobs DROP COLUMN org_id;
+ALTER TABLE admin_exports DROP COLUMN org_id;
+
+-- users get back their role in the default organization
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+UPDATE users u
+SET role = m.role
+FROM organization_members m
+WHERE m.user_id = u.id AND m.org_id = '00000000-0000-0000-0000-000000000001';
+
+DROP TABLE organization_members, organizations;
@@ -0,0 +1,51 @@
+CREATE TABLE organizations (
+  id UUID PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO organizations (id, name, created_at)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default', NOW());
+
+-- roles are held per organization
+CREATE TABLE organization_members (
+  org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(20) NOT NULL DEFAULT 'user',
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX organization_members_user_id_idx ON organization_members(user_id, created_at);
+
+-- existing users, sessions, keys and jobs move to the default organization
+INSERT INTO organization_members (org_id, user_id, role, created_at)
+SELECT '00000000-0000-0000-0000-000000000001', id, role, created_at FROM users;
+
+ALTER TABLE users DROP COLUMN role;
+
+ALTER TABLE sessions ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE sessions ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE api_keys ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE api_keys ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE import_jobs ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE import_jobs ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE admin_exports ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE admin_exports ALTER COLUMN org_id DROP DEFAULT;
+
+CREATE INDEX api_keys_org_id_idx ON api_keys(org_id);
+
+-- Connections act in the organization set in app.org_id and only see its
+-- members. Background jobs leave it empty and see every user.
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY users_tenant ON users
+  USING (
+    COALESCE(current_setting('app.org_id', true), '') = ''
+    OR EXISTS (
+      SELECT 1 FROM organization_members m
+      WHERE m.user_id = users.id AND m.org_id::text = current_setting('app.org_id', true)
+    )
+  )
+  WITH CHECK (true);
@@ -0,0 +1 @@
+DROP TABLE user_group_members, user_groups;
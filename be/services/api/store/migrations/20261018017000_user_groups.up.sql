@@ -0,0 +1,19 @@
+CREATE TABLE user_groups (
+  id UUID PRIMARY KEY,
+  org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX user_groups_org_id_name_unique_idx ON user_groups(org_id, lower(name));
+
+CREATE TABLE user_group_members (
+  group_id UUID NOT NULL REFERENCES user_groups(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX user_group_members_user_id_idx ON user_group_members(user_id);
@@ -0,0 +1,20 @@
+-- sign-up invitations; the status moves from pending to accepted, revoked or
+-- expired exactly once
+CREATE TABLE invitations (
+  id UUID PRIMARY KEY,
+  org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  email VARCHAR(50) NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  token_hash TEXT NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  invited_by TEXT NOT NULL,
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  expires_at TIMESTAMP NOT NULL,
+  accepted_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX invitations_token_hash_unique_idx ON invitations(token_hash);
+CREATE INDEX invitations_org_id_idx ON invitations(org_id, created_at);
+CREATE INDEX invitations_pending_expires_at_idx ON invitations(expires_at) WHERE status = 'pending';
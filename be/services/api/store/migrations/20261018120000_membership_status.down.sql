@@ -0,0 +1,23 @@
+ALTER TABLE users
+  ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+  ADD COLUMN suspended_at TIMESTAMP,
+  ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX users_status_idx ON users(status);
+
+-- users get back the status of their oldest membership
+UPDATE users u
+SET status = m.status, suspended_at = m.suspended_at, deleted_at = m.deleted_at
+FROM (
+  SELECT DISTINCT ON (user_id) user_id, status, suspended_at, deleted_at
+  FROM organization_members
+  ORDER BY user_id, created_at, org_id
+) m
+WHERE m.user_id = u.id;
+
+DROP INDEX organization_members_deleted_at_idx;
+
+ALTER TABLE organization_members
+  DROP COLUMN status,
+  DROP COLUMN suspended_at,
+  DROP COLUMN deleted_at;
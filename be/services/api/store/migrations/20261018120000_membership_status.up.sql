@@ -0,0 +1,18 @@
+-- Statuses are held per organization, like roles: suspending or deleting a
+-- user in one organization leaves their other organizations alone.
+ALTER TABLE organization_members
+  ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+  ADD COLUMN suspended_at TIMESTAMP,
+  ADD COLUMN deleted_at TIMESTAMP;
+
+UPDATE organization_members m
+SET status = u.status, suspended_at = u.suspended_at, deleted_at = u.deleted_at
+FROM users u
+WHERE u.id = m.user_id;
+
+CREATE INDEX organization_members_deleted_at_idx ON organization_members(deleted_at) WHERE status = 'deleted';
+
+ALTER TABLE users
+  DROP COLUMN status,
+  DROP COLUMN suspended_at,
+  DROP COLUMN deleted_at;
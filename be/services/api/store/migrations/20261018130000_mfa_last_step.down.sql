@@ -0,0 +1 @@
+ALTER TABLE user_mfa DROP COLUMN last_totp_step;
@@ -0,0 +1,2 @@
+-- The time step of the last TOTP code accepted, so a code cannot be used twice.
+ALTER TABLE user_mfa ADD COLUMN last_totp_step BIGINT;
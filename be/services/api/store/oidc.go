@@ -0,0 +1,181 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// ParseOIDCProviders parses provider specs formatted as comma separated
+// key=value pairs, e.g.
+// "name=corp,issuer=https://id.example.com,client_id=app,client_secret=s,redirect_url=https://app.example.com/oidc/callback".
+func ParseOIDCProviders(specs []string) ([]OIDCProviderConfig, error) {
+	cfgs := []OIDCProviderConfig{}
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+
+		var c OIDCProviderConfig
+		for _, kv := range strings.Split(spec, ",") {
+			k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+			if !ok {
+				return nil, errors.Errorf("Invalid OIDC provider option %q", kv)
+			}
+
+			switch k {
+			case "name":
+				c.Name = v
+			case "issuer":
+				c.Issuer = v
+			case "client_id":
+				c.ClientID = v
+			case "client_secret":
+				c.ClientSecret = v
+			case "redirect_url":
+				c.RedirectURL = v
+			default:
+				return nil, errors.Errorf("Unknown OIDC provider option %q", k)
+			}
+		}
+
+		if c.Name == "" || c.Issuer == "" || c.ClientID == "" || c.RedirectURL == "" {
+			return nil, errors.Errorf("OIDC provider %q requires name, issuer, client_id and redirect_url", spec)
+		}
+		cfgs = append(cfgs, c)
+	}
+	return cfgs, nil
+}
+
+type OIDCProvider interface {
+	// AuthCodeURL returns the URL to send the user to, using PKCE with the
+	// given verifier.
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	// Exchange trades an authorization code for a verified ID token and
+	// returns the identity it asserts.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*model.Identity, error)
+}
+
+type OIDCProviders interface {
+	Get(name string) (OIDCProvider, bool)
+	Names() []string
+}
+
+type oidcProviders map[string]*oidcProvider
+
+// NewOIDCProviders returns the configured providers. Discovery happens on first
+// use so the API starts even when a provider is unreachable. ctx must outlive
+// the providers since it is used to refresh their signing keys.
+func NewOIDCProviders(ctx context.Context, cfgs []OIDCProviderConfig) OIDCProviders {
+	ps := oidcProviders{}
+	for _, c := range cfgs {
+		ps[c.Name] = &oidcProvider{cfg: c, ctx: ctx}
+	}
+	return ps
+}
+
+func (ps oidcProviders) Get(name string) (OIDCProvider, bool) {
+	p, ok := ps[name]
+	return p, ok
+}
+
+func (ps oidcProviders) Names() []string {
+	names := make([]string, 0, len(ps))
+	for n := range ps {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
+
+type oidcProvider struct {
+	cfg OIDCProviderConfig
+	ctx context.Context
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func (p *oidcProvider) init() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth2 != nil {
+		return nil
+	}
+
+	provider, err := oidc.NewProvider(p.ctx, p.cfg.Issuer)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+	return nil
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	if err := p.init(); err != nil {
+		return "", err
+	}
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*model.Identity, error) {
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+
+	tok, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, errors.WithInvalid(err, "")
+	}
+
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.WithInvalid(errors.New("Missing ID token"), "")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, errors.WithInvalid(err, "")
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.WithInvalid(errors.New("Invalid ID token nonce"), "")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errors.WithInvalid(err, "")
+	}
+
+	return &model.Identity{
+		Provider:      p.cfg.Name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}, nil
+}
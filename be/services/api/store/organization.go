@@ -0,0 +1,150 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OrganizationRepository keeps the organizations and who belongs to them.
+// Memberships are not subject to the tenant of the context: they are what
+// decides which organizations a user may act in. Suspended and deleted
+// memberships give no access, so they are not returned.
+type OrganizationRepository interface {
+	// Create adds an organization with creatorID as its first admin.
+	Create(ctx context.Context, name, creatorID string) (*model.Organization, error)
+	// ListForUser returns the memberships of the user, oldest first.
+	ListForUser(ctx context.Context, userID string) ([]model.Membership, error)
+	FindMembership(ctx context.Context, orgID, userID string) (*model.Membership, error)
+	// FirstMembership returns the oldest membership of the user, the
+	// organization a new session starts in.
+	FirstMembership(ctx context.Context, userID string) (*model.Membership, error)
+	// AddMember makes the user an active member of the organization with the
+	// role. Users join other organizations through invitations, this is for
+	// bootstrapping.
+	AddMember(ctx context.Context, orgID, userID, role string) (*model.Membership, error)
+	// RemoveMember takes the user out of the organization and of its groups.
+	RemoveMember(ctx context.Context, orgID, userID string) error
+}
+
+const membershipColumns = `m.org_id, o.name, m.user_id, m.role, m.created_at`
+
+type organizationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOrganizationRepo(pool *pgxpool.Pool) OrganizationRepository {
+	return &organizationRepo{db: pool}
+}
+
+func scanMembership(row pgx.Row) (*model.Membership, error) {
+	var m model.Membership
+	err := row.Scan(&m.OrgID, &m.OrgName, &m.UserID, &m.Role, &m.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Membership not found"), "")
+	}
+	return &m, errors.WithStack(err)
+}
+
+func (r *organizationRepo) Create(ctx context.Context, name, creatorID string) (*model.Organization, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	o := model.Organization{ID: uuid.NewString(), Name: name, CreatedAt: time.Now().UTC()}
+	_, err = tx.Exec(ctx, `INSERT INTO organizations (id,name,created_at) VALUES ($1,$2,$3)`, o.ID, o.Name, o.CreatedAt)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	_, err = tx.Exec(ctx, `
+        INSERT INTO organization_members (org_id,user_id,role,created_at)
+        VALUES ($1,$2,$3,$4)`, o.ID, creatorID, model.RoleAdmin, o.CreatedAt)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &o, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *organizationRepo) ListForUser(ctx context.Context, userID string) ([]model.Membership, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT `+membershipColumns+`
+        FROM organization_members m JOIN organizations o ON o.id = m.org_id
+        WHERE m.user_id = $1 AND m.status = $2
+        ORDER BY m.created_at, m.org_id`, userID, model.StatusActive)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	memberships := []model.Membership{}
+	for rows.Next() {
+		m, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, *m)
+	}
+	return memberships, errors.WithStack(rows.Err())
+}
+
+func (r *organizationRepo) FindMembership(ctx context.Context, orgID, userID string) (*model.Membership, error) {
+	return scanMembership(r.db.QueryRow(ctx, `
+        SELECT `+membershipColumns+`
+        FROM organization_members m JOIN organizations o ON o.id = m.org_id
+        WHERE m.org_id = $1 AND m.user_id = $2 AND m.status = $3`, orgID, userID, model.StatusActive))
+}
+
+func (r *organizationRepo) FirstMembership(ctx context.Context, userID string) (*model.Membership, error) {
+	return scanMembership(r.db.QueryRow(ctx, `
+        SELECT `+membershipColumns+`
+        FROM organization_members m JOIN organizations o ON o.id = m.org_id
+        WHERE m.user_id = $1 AND m.status = $2
+        ORDER BY m.created_at, m.org_id
+        LIMIT 1`, userID, model.StatusActive))
+}
+
+func (r *organizationRepo) AddMember(ctx context.Context, orgID, userID, role string) (*model.Membership, error) {
+	return scanMembership(r.db.QueryRow(ctx, `
+        WITH m AS (
+            INSERT INTO organization_members (org_id,user_id,role,created_at)
+            VALUES ($1,$2,$3,$4)
+            ON CONFLICT (org_id, user_id) DO UPDATE
+            SET role = EXCLUDED.role, status = 'active', suspended_at = NULL, deleted_at = NULL
+            RETURNING org_id, user_id, role, created_at
+        )
+        SELECT `+membershipColumns+`
+        FROM m JOIN organizations o ON o.id = m.org_id`,
+		orgID, userID, role, time.Now().UTC(),
+	))
+}
+
+func (r *organizationRepo) RemoveMember(ctx context.Context, orgID, userID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("Membership not found"), "")
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM user_group_members
+        WHERE user_id = $2 AND group_id IN (SELECT id FROM user_groups WHERE org_id = $1)`, orgID, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(tx.Commit(ctx))
+}
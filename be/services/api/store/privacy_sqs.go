@@ -0,0 +1,30 @@
+package store
+
+import (
+	"be/pkg/events"
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// PrivacyQueue hands data export and purge jobs to the worker.
+type PrivacyQueue interface {
+	EnqueueDataExport(ctx context.Context, ev events.DataExportRequested) error
+	// EnqueueUserPurge has the worker receive the purge once delay, at most
+	// the 15 minutes SQS allows, has passed.
+	EnqueueUserPurge(ctx context.Context, ev events.UserPurgeRequested, delay time.Duration) error
+}
+
+func NewPrivacySQS(awsConfig aws.Config, queueURL string) PrivacyQueue {
+	return &sqsService{sqsClient: sqs.NewFromConfig(awsConfig), queueURL: queueURL}
+}
+
+func (s *sqsService) EnqueueDataExport(ctx context.Context, ev events.DataExportRequested) error {
+	return s.send(ctx, events.RouteDataExports, ev)
+}
+
+func (s *sqsService) EnqueueUserPurge(ctx context.Context, ev events.UserPurgeRequested, delay time.Duration) error {
+	return s.sendAfter(ctx, events.RouteUserPurges, ev, delay)
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, userID, orgID, refreshTokenHash string, expiresAt time.Time) (string, error)
+	// SwitchOrg moves an active session to another organization.
+	SwitchOrg(ctx context.Context, id, orgID string) error
+	Find(ctx context.Context, id string) (*model.Session, error)
+	Rotate(ctx context.Context, refreshTokenHash, newRefreshTokenHash string) (*model.Session, error)
+	Revoke(ctx context.Context, refreshTokenHash string) (*model.Session, error)
+	RevokeAll(ctx context.Context, userID string) error
+	// RevokeInOrg revokes the sessions of the user acting in the organization.
+	RevokeInOrg(ctx context.Context, userID, orgID string) error
+	// RevokeOthers revokes every session of the user but keepID.
+	RevokeOthers(ctx context.Context, userID, keepID string) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+const sessionColumns = `id, user_id, org_id, expires_at, revoked_at, created_at`
+
+type sessionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepo(pool *pgxpool.Pool) SessionRepository {
+	return &sessionRepo{db: pool}
+}
+
+func scanSession(row pgx.Row) (*model.Session, error) {
+	var s model.Session
+	err := row.Scan(&s.ID, &s.UserID, &s.OrgID, &s.ExpiresAt, &s.RevokedAt, &s.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Session not found"), "")
+	}
+	return &s, errors.WithStack(err)
+}
+
+func (r *sessionRepo) Create(ctx context.Context, userID, orgID, refreshTokenHash string, expiresAt time.Time) (string, error) {
+	id := uuid.NewString()
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO sessions (id,user_id,org_id,refresh_token_hash,expires_at,created_at) VALUES ($1,$2,$3,$4,$5,$6)`,
+		id, userID, orgID, refreshTokenHash, expiresAt, time.Now().UTC(),
+	)
+	return id, errors.WithStack(err)
+}
+
+func (r *sessionRepo) SwitchOrg(ctx context.Context, id, orgID string) error {
+	tag, err := r.db.Exec(ctx, `
+        UPDATE sessions
+        SET org_id = $1
+        WHERE id = $2 AND revoked_at IS NULL AND expires_at > $3`, orgID, id, time.Now().UTC())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("Session not found"), "")
+	}
+	return nil
+}
+
+func (r *sessionRepo) Find(ctx context.Context, id string) (*model.Session, error) {
+	return scanSession(r.db.QueryRow(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id=$1`, id))
+}
+
+// Rotate swaps the refresh token of an active session in a single statement, so
+// a refresh token can only ever be exchanged once. The session keeps its
+// original expiry.
+func (r *sessionRepo) Rotate(ctx context.Context, refreshTokenHash, newRefreshTokenHash string) (*model.Session, error) {
+	return scanSession(r.db.QueryRow(ctx, `
+        UPDATE sessions
+        SET refresh_token_hash = $1
+        WHERE refresh_token_hash = $2 AND revoked_at IS NULL AND expires_at > $3
+        RETURNING `+sessionColumns, newRefreshTokenHash, refreshTokenHash, time.Now().UTC()))
+}
+
+func (r *sessionRepo) Revoke(ctx context.Context, refreshTokenHash string) (*model.Session, error) {
+	return scanSession(r.db.QueryRow(ctx, `
+        UPDATE sessions
+        SET revoked_at = $1
+        WHERE refresh_token_hash = $2 AND revoked_at IS NULL
+        RETURNING `+sessionColumns, time.Now().UTC(), refreshTokenHash))
+}
+
+func (r *sessionRepo) RevokeAll(ctx context.Context, userID string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE sessions
+        SET revoked_at = $1
+        WHERE user_id = $2 AND revoked_at IS NULL
+    `, time.Now().UTC(), userID)
+	return errors.WithStack(err)
+}
+
+func (r *sessionRepo) RevokeInOrg(ctx context.Context, userID, orgID string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE sessions
+        SET revoked_at = $1
+        WHERE user_id = $2 AND org_id = $3 AND revoked_at IS NULL
+    `, time.Now().UTC(), userID, orgID)
+	return errors.WithStack(err)
+}
+
+func (r *sessionRepo) RevokeOthers(ctx context.Context, userID, keepID string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE sessions
+        SET revoked_at = $1
+        WHERE user_id = $2 AND id <> $3 AND revoked_at IS NULL
+    `, time.Now().UTC(), userID, keepID)
+	return errors.WithStack(err)
+}
+
+func (r *sessionRepo) IsRevoked(ctx context.Context, id string) (bool, error) {
+	s, err := r.Find(ctx, id)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !s.Active(time.Now().UTC()), nil
+}
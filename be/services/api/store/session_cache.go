@@ -0,0 +1,128 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"sync"
+	"time"
+)
+
+type cachedSession struct {
+	session  *model.Session
+	cachedAt time.Time
+}
+
+// sessionRepoWithCache keeps sessions looked up by IsRevoked in memory so the
+// auth middleware does not hit Postgres on every request. Revocations made
+// through this instance apply immediately; ones made by another API replica
+// apply once the cached entry is older than ttl, so ttl is kept short; a
+// zero ttl disables the cache.
+type sessionRepoWithCache struct {
+	SessionRepository
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	sessions map[string]cachedSession
+}
+
+func NewSessionRepoWithCache(repo SessionRepository, ttl time.Duration) SessionRepository {
+	return &sessionRepoWithCache{
+		SessionRepository: repo,
+		ttl:               ttl,
+		sessions:          map[string]cachedSession{},
+	}
+}
+
+func (r *sessionRepoWithCache) IsRevoked(ctx context.Context, id string) (bool, error) {
+	now := time.Now().UTC()
+
+	r.mu.RLock()
+	c, ok := r.sessions[id]
+	r.mu.RUnlock()
+	if ok && now.Sub(c.cachedAt) < r.ttl {
+		return !c.session.Active(now), nil
+	}
+
+	s, err := r.SessionRepository.Find(ctx, id)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	r.evictExpired(now)
+	r.sessions[id] = cachedSession{session: s, cachedAt: now}
+	r.mu.Unlock()
+	return !s.Active(now), nil
+}
+
+func (r *sessionRepoWithCache) Revoke(ctx context.Context, refreshTokenHash string) (*model.Session, error) {
+	s, err := r.SessionRepository.Revoke(ctx, refreshTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	delete(r.sessions, s.ID)
+	r.mu.Unlock()
+	return s, nil
+}
+
+func (r *sessionRepoWithCache) RevokeAll(ctx context.Context, userID string) error {
+	if err := r.SessionRepository.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	for id, c := range r.sessions {
+		if c.session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *sessionRepoWithCache) RevokeInOrg(ctx context.Context, userID, orgID string) error {
+	if err := r.SessionRepository.RevokeInOrg(ctx, userID, orgID); err != nil {
+		return err
+	}
+
+	// the cached entry may predate a switch to the organization
+	r.mu.Lock()
+	for id, c := range r.sessions {
+		if c.session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *sessionRepoWithCache) RevokeOthers(ctx context.Context, userID, keepID string) error {
+	if err := r.SessionRepository.RevokeOthers(ctx, userID, keepID); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	for id, c := range r.sessions {
+		if c.session.UserID == userID && id != keepID {
+			delete(r.sessions, id)
+		}
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// evictExpired drops entries past their ttl so the map does not keep every
+// session ever seen. Must be called with mu held.
+func (r *sessionRepoWithCache) evictExpired(now time.Time) {
+	for id, c := range r.sessions {
+		if now.Sub(c.cachedAt) >= r.ttl {
+			delete(r.sessions, id)
+		}
+	}
+}
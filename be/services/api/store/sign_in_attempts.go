@@ -0,0 +1,71 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SignInAttemptStore keeps the failed sign-in counters used to throttle
+// password guessing.
+type SignInAttemptStore interface {
+	// Get returns the counters for key, zero when nothing was recorded.
+	Get(ctx context.Context, key string) (*model.SignInAttempts, error)
+	// RecordFailure counts a failure for key and returns the number of failures
+	// in the current window. A window starts with the first failure recorded
+	// after the previous one is over.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Block(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+type signInAttemptRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewSignInAttemptRepo shares the counters between API replicas.
+func NewSignInAttemptRepo(pool *pgxpool.Pool) SignInAttemptStore {
+	return &signInAttemptRepo{db: pool}
+}
+
+func (r *signInAttemptRepo) Get(ctx context.Context, key string) (*model.SignInAttempts, error) {
+	a := model.SignInAttempts{Key: key}
+	err := r.db.QueryRow(ctx, `
+        SELECT failures, blocked_until
+        FROM sign_in_attempts
+        WHERE key = $1
+    `, key).Scan(&a.Failures, &a.BlockedUntil)
+	if err == pgx.ErrNoRows {
+		return &a, nil
+	}
+	return &a, errors.WithStack(err)
+}
+
+func (r *signInAttemptRepo) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	now := time.Now().UTC()
+
+	var failures int
+	err := r.db.QueryRow(ctx, `
+        INSERT INTO sign_in_attempts AS a (key, failures, window_started_at)
+        VALUES ($1, 1, $2)
+        ON CONFLICT (key) DO UPDATE
+        SET failures = CASE WHEN a.window_started_at <= $3 THEN 1 ELSE a.failures + 1 END,
+            window_started_at = CASE WHEN a.window_started_at <= $3 THEN $2 ELSE a.window_started_at END
+        RETURNING failures
+    `, key, now, now.Add(-window)).Scan(&failures)
+	return failures, errors.WithStack(err)
+}
+
+func (r *signInAttemptRepo) Block(ctx context.Context, key string, until time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE sign_in_attempts SET blocked_until = $1 WHERE key = $2`, until, key)
+	return errors.WithStack(err)
+}
+
+func (r *signInAttemptRepo) Reset(ctx context.Context, key string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM sign_in_attempts WHERE key = $1`, key)
+	return errors.WithStack(err)
+}
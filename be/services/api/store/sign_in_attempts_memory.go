@@ -0,0 +1,92 @@
+package store
+
+import (
+	"be/pkg/model"
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+type memoryAttempts struct {
+	failures      int
+	windowStarted time.Time
+	blockedUntil  time.Time
+}
+
+// signInAttemptMemory keeps the counters in process. Each API replica then
+// counts on its own, which suits a single instance or local development.
+type signInAttemptMemory struct {
+	mu       sync.Mutex
+	attempts map[string]*memoryAttempts
+	prunedAt time.Time
+}
+
+func NewSignInAttemptMemory() SignInAttemptStore {
+	return &signInAttemptMemory{attempts: map[string]*memoryAttempts{}}
+}
+
+func (m *signInAttemptMemory) Get(ctx context.Context, key string) (*model.SignInAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := model.SignInAttempts{Key: key}
+	if e, ok := m.attempts[key]; ok {
+		a.Failures = e.failures
+		if !e.blockedUntil.IsZero() {
+			a.BlockedUntil = sql.NullTime{Time: e.blockedUntil, Valid: true}
+		}
+	}
+	return &a, nil
+}
+
+func (m *signInAttemptMemory) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	now := time.Now().UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.prunedAt) >= window {
+		m.prune(now, window)
+		m.prunedAt = now
+	}
+
+	e, ok := m.attempts[key]
+	if !ok {
+		e = &memoryAttempts{windowStarted: now}
+		m.attempts[key] = e
+	} else if !now.Before(e.windowStarted.Add(window)) {
+		e.failures = 0
+		e.windowStarted = now
+	}
+	e.failures++
+	return e.failures, nil
+}
+
+func (m *signInAttemptMemory) Block(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.attempts[key]; ok {
+		e.blockedUntil = until
+	}
+	return nil
+}
+
+func (m *signInAttemptMemory) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+// prune drops counters whose window and block are both over, so keys seen
+// once do not pile up.
+func (m *signInAttemptMemory) prune(now time.Time, window time.Duration) {
+	for k, e := range m.attempts {
+		if !now.Before(e.windowStarted.Add(window)) && !now.Before(e.blockedUntil) {
+			delete(m.attempts, k)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"be/pkg/tenant"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ScopeToTenant makes every connection taken from the pool act in the
+// organization of the context it is taken with, by setting app.org_id for the
+// row-level security policies of the users table. A context without an
+// organization sees every row, as sign-in and background jobs need to.
+//
+// It costs a round trip per acquire, which keeps tenant isolation out of
+// every single query.
+func ScopeToTenant(cfg *pgxpool.Config) {
+	cfg.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		_, err := conn.Exec(ctx, `SELECT set_config('app.org_id', $1, false)`, tenant.OrgID(ctx))
+		return err == nil, err
+	}
+}
+
+// userRoleColumn is the role of the user in the organization the connection
+// acts in, empty outside of any.
+const userRoleColumn = `COALESCE((
+    SELECT m.role FROM organization_members m
+    WHERE m.user_id = users.id AND m.org_id::text = current_setting('app.org_id', true)
+), '') AS role`
+
+// userMembership picks the membership of the user that their status is read
+// from: the one in the organization the connection acts in, or outside of
+// any, the one letting them in the most.
+const userMembership = `FROM organization_members m
+    WHERE m.user_id = users.id AND COALESCE(current_setting('app.org_id', true), '') IN ('', m.org_id::text)
+    ORDER BY m.status <> 'active', m.status <> 'suspended', m.created_at
+    LIMIT 1`
+
+// userStatus is the status of the user's membership, see userMembership.
+const userStatus = `COALESCE((SELECT m.status ` + userMembership + `), 'active')`
+
+// userStatusColumns are the status of the user and when they were suspended
+// or deleted, in the order of model.User.
+const userStatusColumns = userStatus + ` AS status, (SELECT m.suspended_at ` + userMembership + `) AS suspended_at, (SELECT m.deleted_at ` + userMembership + `) AS deleted_at`
+
+// userInTenant keeps the users that are members of the organization the
+// connection acts in, or every user outside of any. It repeats the row-level
+// security policy of the users table, which superusers and the owner of the
+// table bypass.
+const userInTenant = `(COALESCE(current_setting('app.org_id', true), '') = '' OR EXISTS (
+    SELECT 1 FROM organization_members m
+    WHERE m.user_id = users.id AND m.org_id::text = current_setting('app.org_id', true)
+))`
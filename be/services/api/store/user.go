@@ -4,7 +4,6 @@ import (
 	"be/pkg/errors"
 	"be/pkg/model"
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,14 +12,64 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// UserRepository reads and writes the users of the organization the context
+// acts in. Every query filters on it, as the row-level security of the users
+// table does not apply to superusers. Role is the user's role in that
+// organization.
 type UserRepository interface {
+	// Create adds a user to the default organization.
 	Create(ctx context.Context, email, hashed string) (string, error)
+	FindByID(ctx context.Context, id string) (*model.User, error)
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
-	UpdateUser(ctx context.Context, id, email, name string) (*model.User, error)
-	List(ctx context.Context, limit int, cursor string) ([]model.User, error)
+	// PatchUser and UpdateProfile only write the user when it is still at
+	// the given version. Otherwise they fail with a conflict error and return
+	// the user as it is now.
+	//
+	// PatchUser changes the fields that are not nil, an empty name clears it.
+	// Non-nil attributes replace all of the user's attributes. A changed
+	// email is marked unverified.
+	PatchUser(ctx context.Context, id string, email, name *string, attributes model.Attributes, version int) (*model.User, error)
+	// UpdateProfile changes the fields that are not nil. A changed email is
+	// marked unverified.
+	UpdateProfile(ctx context.Context, id string, name, email *string, version int) (*model.User, error)
+	// UpdateRole changes the role of the user in the organization the context
+	// acts in.
+	UpdateRole(ctx context.Context, id, role string) (*model.User, error)
+	UpdatePassword(ctx context.Context, id, hashed string) error
+	MarkEmailVerified(ctx context.Context, id string) error
+	// SetStatus moves the membership of the user in the organization the
+	// context acts in to status, stamping suspended_at or deleted_at, or
+	// clearing both when the user is made active again. Their other
+	// organizations are left alone.
+	SetStatus(ctx context.Context, id, status string) (*model.User, error)
+	// IsActive reports whether the user exists and is active in the
+	// organization the context acts in, or in any outside of one.
+	IsActive(ctx context.Context, id string) (bool, error)
+	// InOtherOrgs returns which of the users are also members of an
+	// organization besides the one the context acts in, whatever their
+	// status there.
+	InOtherOrgs(ctx context.Context, ids []string) (map[string]bool, error)
+	List(ctx context.Context, q UserListQuery) ([]model.User, error)
+	// Count returns how many users match q, ignoring its paging.
+	Count(ctx context.Context, q UserListQuery) (int, error)
+	// Stream calls fn with every user matching q, in order, ignoring its
+	// paging.
+	Stream(ctx context.Context, q UserListQuery, fn func(*model.User) error) error
+	// Select returns the selected users, failing when there are more than
+	// limit of them.
+	Select(ctx context.Context, sel UserSelection, limit int) ([]model.User, error)
+	// BulkUpdate locks the selected users and writes them as plan returns
+	// them, in one transaction. It fails like Select past limit users.
+	BulkUpdate(ctx context.Context, sel UserSelection, limit int, plan UserBulkPlan) ([]model.User, error)
+	// DeleteUser removes the row for good, from every organization.
 	DeleteUser(ctx context.Context, id string) error
+	// PurgeDeleted removes the memberships deleted before the given time, and
+	// the users left without any, and returns the memberships.
+	PurgeDeleted(ctx context.Context, before time.Time) ([]model.Membership, error)
 }
 
+const userColumns = `id, email, password, name, ` + userRoleColumn + `, created_at, email_verified_at, ` + userStatusColumns + `, version, attributes`
+
 type userRepo struct {
 	db *pgxpool.Pool
 }
@@ -29,91 +78,235 @@ func NewUserRepo(pool *pgxpool.Pool) UserRepository {
 	return &userRepo{db: pool}
 }
 
+func scanUser(row pgx.Row) (*model.User, error) {
+	var u model.User
+	err := row.Scan(&u.ID, &u.Email, &u.Password, &u.Name, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt,
+		&u.Status, &u.SuspendedAt, &u.DeletedAt, &u.Version, &u.Attributes)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("User not found"), "")
+	}
+	return &u, errors.WithStack(err)
+}
+
 func (r *userRepo) Create(ctx context.Context, email, hashed string) (string, error) {
 	id := uuid.NewString()
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO users (id,email,password,created_at) VALUES ($1,$2,$3,$4)`,
-		id, email, hashed, time.Now().UTC(),
+	_, err := r.db.Exec(ctx, `
+        WITH u AS (
+            INSERT INTO users (id,email,password,created_at) VALUES ($1,$2,$3,$4)
+            RETURNING id, created_at
+        )
+        INSERT INTO organization_members (org_id,user_id,role,created_at)
+        SELECT $5, id, $6, created_at FROM u`,
+		id, email, hashed, time.Now().UTC(), model.DefaultOrgID, model.RoleUser,
 	)
 	return id, errors.WithStack(err)
 }
 
+func (r *userRepo) FindByID(ctx context.Context, id string) (*model.User, error) {
+	return scanUser(r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id=$1 AND `+userInTenant, id))
+}
+
 func (r *userRepo) FindByEmail(ctx context.Context, email string) (*model.User, error) {
-	row := r.db.QueryRow(ctx, `SELECT id,email,password,name,created_at FROM users WHERE email=$1`, email)
-	var u model.User
-	err := row.Scan(&u.ID, &u.Email, &u.Password, &u.Name, &u.CreatedAt)
-	if err == pgx.ErrNoRows {
-		return nil, errors.WithNotFound(errors.New("User not found"), "")
+	return scanUser(r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE email=$1 AND `+userInTenant, email))
+}
+
+func (r *userRepo) PatchUser(ctx context.Context, id string, email, name *string, attributes model.Attributes, version int) (*model.User, error) {
+	// a nil map would be written as a JSON null
+	var attrs any
+	if attributes != nil {
+		attrs = attributes
 	}
-	return &u, errors.WithStack(err)
+
+	u, err := scanUser(r.db.QueryRow(ctx, `
+        UPDATE users
+        SET
+            email             = COALESCE($1, email),
+            email_verified_at = CASE WHEN $1::text IS NULL OR $1 = email THEN email_verified_at END,
+            name              = CASE WHEN $2::text IS NULL THEN name ELSE NULLIF($2, '') END,
+            attributes        = COALESCE($3::jsonb, attributes),
+            version           = version + 1
+        WHERE id = $4 AND version = $5 AND `+userInTenant+`
+        RETURNING `+userColumns, email, name, attrs, id, version))
+	if errors.IsNotFound(err) {
+		return r.staleOrMissing(ctx, id)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, errors.WithInvalid(errors.New("Email existed"), "")
+	}
+	return u, err
 }
 
-func (r *userRepo) UpdateUser(ctx context.Context, id, email, name string) (*model.User, error) {
-	var u model.User
-	err := r.db.QueryRow(ctx, `
+func (r *userRepo) UpdateProfile(ctx context.Context, id string, name, email *string, version int) (*model.User, error) {
+	u, err := scanUser(r.db.QueryRow(ctx, `
         UPDATE users
         SET
-            email = COALESCE(NULLIF($1, ''), email),
-            name  = COALESCE(NULLIF($2, ''), name)
-        WHERE id = $3
-        RETURNING id, email, password, name, created_at
-    `, email, name, id).Scan(
-		&u.ID,
-		&u.Email,
-		&u.Password,
-		&u.Name,
-		&u.CreatedAt,
-	)
+            name              = COALESCE($1, name),
+            email             = COALESCE($2, email),
+            email_verified_at = CASE WHEN $2::text IS NULL OR $2 = email THEN email_verified_at END,
+            version           = version + 1
+        WHERE id = $3 AND version = $4 AND `+userInTenant+`
+        RETURNING `+userColumns, name, email, id, version))
+	if errors.IsNotFound(err) {
+		return r.staleOrMissing(ctx, id)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, errors.WithInvalid(errors.New("Email existed"), "")
+	}
+	return u, err
+}
 
+// staleOrMissing explains why a versioned update matched no row. On a
+// conflict the current user is returned along with the error.
+func (r *userRepo) staleOrMissing(ctx context.Context, id string) (*model.User, error) {
+	u, err := r.FindByID(ctx, id)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return nil, errors.WithInvalid(errors.New("Email existed"), "")
-		}
+		return nil, err
+	}
+	return u, errors.WithConflict(errors.New("User was changed by someone else"), "")
+}
 
-		if err == pgx.ErrNoRows {
-			return nil, errors.WithNotFound(errors.New("User not found"), "")
-		}
+func (r *userRepo) UpdateRole(ctx context.Context, id, role string) (*model.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
-	return &u, errors.WithStack(err)
+	defer tx.Rollback(ctx)
+
+	if err := updateMemberRole(ctx, tx, id, role); err != nil {
+		return nil, err
+	}
+	u, err := scanUser(tx.QueryRow(ctx, `
+        UPDATE users
+        SET version = version + 1
+        WHERE id = $1 AND `+userInTenant+`
+        RETURNING `+userColumns, id))
+	if err != nil {
+		return nil, err
+	}
+	return u, errors.WithStack(tx.Commit(ctx))
 }
 
-func (r *userRepo) List(ctx context.Context, limit int, cursor string) ([]model.User, error) {
-	query := `
-		SELECT id, email, password, name, created_at
-		FROM users
-	`
-	args := []any{}
-	if cursor != "" {
-		query += ` WHERE id < $1`
-		args = append(args, cursor)
+// updateMemberRole sets the role of the user in the organization the
+// connection acts in.
+func updateMemberRole(ctx context.Context, tx pgx.Tx, userID, role string) error {
+	tag, err := tx.Exec(ctx, `
+        UPDATE organization_members
+        SET role = $1
+        WHERE user_id = $2 AND org_id::text = current_setting('app.org_id', true)`, role, userID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("User not found"), "")
 	}
+	return nil
+}
 
-	query = fmt.Sprintf("%s ORDER BY id DESC LIMIT $%d", query, len(args)+1)
-	args = append(args, limit)
+func (r *userRepo) UpdatePassword(ctx context.Context, id, hashed string) error {
+	res, err := r.db.Exec(ctx, `
+        UPDATE users
+        SET password = $1, version = version + 1
+        WHERE id = $2 AND `+userInTenant, hashed, id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("User not found"), "")
+	}
 
-	rows, err := r.db.Query(ctx, query, args...)
+	return nil
+}
+
+func (r *userRepo) MarkEmailVerified(ctx context.Context, id string) error {
+	res, err := r.db.Exec(ctx, `
+        UPDATE users
+        SET email_verified_at = COALESCE(email_verified_at, $1), version = version + 1
+        WHERE id = $2 AND `+userInTenant, time.Now().UTC(), id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("User not found"), "")
+	}
+
+	return nil
+}
+
+func (r *userRepo) SetStatus(ctx context.Context, id, status string) (*model.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	tag, err := tx.Exec(ctx, `
+        UPDATE organization_members
+        SET
+            status       = $1,
+            suspended_at = CASE WHEN $1 = 'suspended' THEN $2::timestamp END,
+            deleted_at   = CASE WHEN $1 = 'deleted' THEN $2::timestamp END
+        WHERE user_id = $3 AND org_id::text = current_setting('app.org_id', true)`, status, now, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, errors.WithNotFound(errors.New("User not found"), "")
+	}
+
+	u, err := scanUser(tx.QueryRow(ctx, `
+        UPDATE users
+        SET version = version + 1
+        WHERE id = $1 AND `+userInTenant+`
+        RETURNING `+userColumns, id))
+	if err != nil {
+		return nil, err
+	}
+	return u, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *userRepo) IsActive(ctx context.Context, id string) (bool, error) {
+	var status string
+	err := r.db.QueryRow(ctx, `SELECT `+userStatus+` FROM users WHERE id = $1 AND `+userInTenant, id).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return status == model.StatusActive, nil
+}
+
+func (r *userRepo) InOtherOrgs(ctx context.Context, ids []string) (map[string]bool, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT DISTINCT user_id::text FROM organization_members
+        WHERE user_id::text = ANY($1) AND org_id::text <> COALESCE(current_setting('app.org_id', true), '')`, ids)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	defer rows.Close()
 
-	var users []model.User
+	shared := map[string]bool{}
 	for rows.Next() {
-		var u model.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.Password, &u.Name, &u.CreatedAt); err != nil {
+		var id string
+		if err := rows.Scan(&id); err != nil {
 			return nil, errors.WithStack(err)
 		}
-		users = append(users, u)
+		shared[id] = true
 	}
-	return users, errors.WithStack(rows.Err())
+	return shared, errors.WithStack(rows.Err())
 }
 
 func (r *userRepo) DeleteUser(ctx context.Context, id string) error {
 	res, err := r.db.Exec(ctx, `
         DELETE FROM users
-        WHERE id = $1
-    `, id)
+        WHERE id = $1 AND `+userInTenant, id)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -124,3 +317,64 @@ func (r *userRepo) DeleteUser(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (r *userRepo) PurgeDeleted(ctx context.Context, before time.Time) ([]model.Membership, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+        DELETE FROM organization_members
+        WHERE status = $1 AND deleted_at < $2
+        RETURNING org_id, user_id, role, created_at`, model.StatusDeleted, before)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	memberships := []model.Membership{}
+	userIDs := []string{}
+	for rows.Next() {
+		var m model.Membership
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		memberships = append(memberships, m)
+		userIDs = append(userIDs, m.UserID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(memberships) == 0 {
+		return memberships, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM user_group_members gm
+        USING user_groups g, unnest($1::uuid[], $2::uuid[]) AS p(org_id, user_id)
+        WHERE g.id = gm.group_id AND g.org_id = p.org_id AND gm.user_id = p.user_id`,
+		orgIDsOf(memberships), userIDs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// an account no organization keeps is gone for good
+	_, err = tx.Exec(ctx, `
+        DELETE FROM users
+        WHERE id = ANY($1) AND NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.user_id = users.id)`,
+		userIDs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return memberships, errors.WithStack(tx.Commit(ctx))
+}
+
+func orgIDsOf(memberships []model.Membership) []string {
+	ids := make([]string, 0, len(memberships))
+	for _, m := range memberships {
+		ids = append(ids, m.OrgID)
+	}
+	return ids
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserAttributeRepository keeps the schema of the custom user attributes.
+type UserAttributeRepository interface {
+	List(ctx context.Context) ([]model.AttributeDefinition, error)
+	// Save creates the definition or replaces the one with the same name.
+	// Values users already hold are not checked against the new definition.
+	Save(ctx context.Context, d model.AttributeDefinition) (*model.AttributeDefinition, error)
+	// Delete removes the definition along with the values users hold for it.
+	Delete(ctx context.Context, name string) error
+}
+
+const attributeColumns = `name, type, required, enum, max_length, updated_at, created_at`
+
+type userAttributeRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserAttributeRepo(pool *pgxpool.Pool) UserAttributeRepository {
+	return &userAttributeRepo{db: pool}
+}
+
+func scanAttribute(row pgx.Row) (*model.AttributeDefinition, error) {
+	var d model.AttributeDefinition
+	err := row.Scan(&d.Name, &d.Type, &d.Required, &d.Enum, &d.MaxLength, &d.UpdatedAt, &d.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Attribute not found"), "")
+	}
+	return &d, errors.WithStack(err)
+}
+
+func (r *userAttributeRepo) List(ctx context.Context) ([]model.AttributeDefinition, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+attributeColumns+` FROM user_attribute_definitions ORDER BY name`)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	defs := []model.AttributeDefinition{}
+	for rows.Next() {
+		d, err := scanAttribute(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, *d)
+	}
+	return defs, errors.WithStack(rows.Err())
+}
+
+func (r *userAttributeRepo) Save(ctx context.Context, d model.AttributeDefinition) (*model.AttributeDefinition, error) {
+	if d.Enum == nil {
+		d.Enum = []string{}
+	}
+	now := time.Now().UTC()
+	return scanAttribute(r.db.QueryRow(ctx, `
+        INSERT INTO user_attribute_definitions (name,type,required,enum,max_length,updated_at,created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$6)
+        ON CONFLICT (name) DO UPDATE
+        SET type = $2, required = $3, enum = $4, max_length = $5, updated_at = $6
+        RETURNING `+attributeColumns,
+		d.Name, d.Type, d.Required, d.Enum, d.MaxLength, now,
+	))
+}
+
+func (r *userAttributeRepo) Delete(ctx context.Context, name string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM user_attribute_definitions WHERE name = $1`, name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.WithNotFound(errors.New("Attribute not found"), "")
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE users
+        SET attributes = attributes - $1, version = version + 1
+        WHERE attributes ? $1`, name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(tx.Commit(ctx))
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UserSelection picks the users of a bulk operation: the users with the given
+// ids, or when IDs is empty, every user matching Filter. Filter's sort and
+// paging are ignored.
+type UserSelection struct {
+	IDs    []string
+	Filter UserListQuery
+}
+
+// UserBulkPlan gets the selected users and returns them as they should be
+// written. Users left out of the result are not written.
+type UserBulkPlan func(users []model.User) ([]model.User, error)
+
+// queryer is what selectUsers needs from a pool or a transaction.
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// selectUsers reads the selected users by id, failing when there are more
+// than limit of them.
+func selectUsers(ctx context.Context, db queryer, sel UserSelection, limit int, lock bool) ([]model.User, error) {
+	args := []any{}
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(sel.IDs) > 0 {
+		args = append(args, sel.IDs)
+		query += ` WHERE id = ANY($1) AND ` + userInTenant
+	} else {
+		query += sel.Filter.where(&args)
+	}
+	args = append(args, limit+1)
+	query += fmt.Sprintf(` ORDER BY id LIMIT $%d`, len(args))
+	if lock {
+		query += ` FOR UPDATE`
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(users) > limit {
+		return nil, errors.WithInvalid(errors.Errorf("Selection matches more than %d users", limit), "")
+	}
+	return users, nil
+}
+
+func (r *userRepo) Select(ctx context.Context, sel UserSelection, limit int) ([]model.User, error) {
+	return selectUsers(ctx, r.db, sel, limit, false)
+}
+
+func (r *userRepo) BulkUpdate(ctx context.Context, sel UserSelection, limit int, plan UserBulkPlan) ([]model.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	users, err := selectUsers(ctx, tx, sel, limit, true)
+	if err != nil {
+		return nil, err
+	}
+
+	before := make(map[string]model.User, len(users))
+	for _, u := range users {
+		before[u.ID] = u
+	}
+
+	updated, err := plan(users)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &pgx.Batch{}
+	for _, u := range updated {
+		batch.Queue(`
+            UPDATE users
+            SET name = $1, version = version + 1
+            WHERE id = $2 AND `+userInTenant,
+			u.Name, u.ID)
+		// roles and statuses live in the membership of the organization acted in
+		if b := before[u.ID]; u.Role != b.Role || u.Status != b.Status {
+			batch.Queue(`
+                UPDATE organization_members
+                SET role = $1, status = $2, suspended_at = $3, deleted_at = $4
+                WHERE user_id = $5 AND org_id::text = current_setting('app.org_id', true)`,
+				u.Role, u.Status, u.SuspendedAt, u.DeletedAt, u.ID)
+		}
+	}
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return updated, errors.WithStack(tx.Commit(ctx))
+}
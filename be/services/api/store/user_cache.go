@@ -0,0 +1,115 @@
+package store
+
+import (
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"context"
+	"sync"
+	"time"
+)
+
+type cachedStatus struct {
+	active   bool
+	cachedAt time.Time
+}
+
+// statusKey is a user in an organization, as statuses are held per
+// membership.
+type statusKey struct {
+	orgID  string
+	userID string
+}
+
+// userRepoWithStatusCache keeps the answers of IsActive in memory so the auth
+// middleware does not hit Postgres on every request. Status changes made
+// through this instance apply immediately; ones made by another API replica
+// apply once the cached entry is older than ttl, so ttl is kept short; a
+// zero ttl disables the cache.
+type userRepoWithStatusCache struct {
+	UserRepository
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	statuses map[statusKey]cachedStatus
+}
+
+func NewUserRepoWithStatusCache(repo UserRepository, ttl time.Duration) UserRepository {
+	return &userRepoWithStatusCache{
+		UserRepository: repo,
+		ttl:            ttl,
+		statuses:       map[statusKey]cachedStatus{},
+	}
+}
+
+func (r *userRepoWithStatusCache) IsActive(ctx context.Context, id string) (bool, error) {
+	now := time.Now().UTC()
+	key := statusKey{orgID: tenant.OrgID(ctx), userID: id}
+
+	r.mu.RLock()
+	c, ok := r.statuses[key]
+	r.mu.RUnlock()
+	if ok && now.Sub(c.cachedAt) < r.ttl {
+		return c.active, nil
+	}
+
+	active, err := r.UserRepository.IsActive(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	r.evictExpired(now)
+	r.statuses[key] = cachedStatus{active: active, cachedAt: now}
+	r.mu.Unlock()
+	return active, nil
+}
+
+func (r *userRepoWithStatusCache) SetStatus(ctx context.Context, id, status string) (*model.User, error) {
+	u, err := r.UserRepository.SetStatus(ctx, id, status)
+	r.forget(id)
+	return u, err
+}
+
+func (r *userRepoWithStatusCache) BulkUpdate(ctx context.Context, sel UserSelection, limit int, plan UserBulkPlan) ([]model.User, error) {
+	users, err := r.UserRepository.BulkUpdate(ctx, sel, limit, plan)
+	for _, u := range users {
+		r.forget(u.ID)
+	}
+	return users, err
+}
+
+func (r *userRepoWithStatusCache) DeleteUser(ctx context.Context, id string) error {
+	err := r.UserRepository.DeleteUser(ctx, id)
+	r.forget(id)
+	return err
+}
+
+func (r *userRepoWithStatusCache) PurgeDeleted(ctx context.Context, before time.Time) ([]model.Membership, error) {
+	memberships, err := r.UserRepository.PurgeDeleted(ctx, before)
+	for _, m := range memberships {
+		r.forget(m.UserID)
+	}
+	return memberships, err
+}
+
+// forget drops the statuses of the user in every organization, as the one
+// read outside of any depends on all of them.
+func (r *userRepoWithStatusCache) forget(id string) {
+	r.mu.Lock()
+	for key := range r.statuses {
+		if key.userID == id {
+			delete(r.statuses, key)
+		}
+	}
+	r.mu.Unlock()
+}
+
+// evictExpired drops entries past their ttl so the map does not keep every
+// user ever seen. Must be called with mu held.
+func (r *userRepoWithStatusCache) evictExpired(now time.Time) {
+	for key, c := range r.statuses {
+		if now.Sub(c.cachedAt) >= r.ttl {
+			delete(r.statuses, key)
+		}
+	}
+}
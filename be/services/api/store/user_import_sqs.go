@@ -0,0 +1,22 @@
+package store
+
+import (
+	"be/pkg/events"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// UserImportQueue hands bulk user imports to the worker.
+type UserImportQueue interface {
+	EnqueueUserImport(ctx context.Context, ev events.UserImportRequested) error
+}
+
+func NewUserImportSQS(awsConfig aws.Config, queueURL string) UserImportQueue {
+	return &sqsService{sqsClient: sqs.NewFromConfig(awsConfig), queueURL: queueURL}
+}
+
+func (s *sqsService) EnqueueUserImport(ctx context.Context, ev events.UserImportRequested) error {
+	return s.send(ctx, events.RouteUserImports, ev)
+}
@@ -0,0 +1,221 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Fields the user list can be sorted on. Ties are broken by id.
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortEmail     = "email"
+	UserSortName      = "name"
+)
+
+var UserSortFields = []string{UserSortCreatedAt, UserSortEmail, UserSortName}
+
+func IsValidUserSort(field string) bool {
+	return slices.Contains(UserSortFields, field)
+}
+
+var userSortColumns = map[string]string{
+	UserSortCreatedAt: "created_at",
+	UserSortEmail:     "email",
+	UserSortName:      "COALESCE(name, '')",
+}
+
+// UserListQuery selects and orders the users returned by List.
+type UserListQuery struct {
+	// Search matches a case-insensitive substring of the email or name.
+	Search string
+	// Status filters on one status; empty matches every user but deleted ones.
+	Status string
+	// CreatedFrom and CreatedTo bound the creation time, zero for no bound.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Attributes matches users holding each of the attributes with the
+	// given value.
+	Attributes model.Attributes
+	// GroupID matches the members of the group.
+	GroupID string
+
+	SortBy string
+	Desc   bool
+	Limit  int
+	// After continues the listing past the given position.
+	After *UserListKey
+}
+
+// UserListKey is the position of a user in a listing: the value of the sort
+// field, formatted by UserListKeyOf, and the id.
+type UserListKey struct {
+	Value string
+	ID    string
+}
+
+func UserListKeyOf(u *model.User, sortBy string) UserListKey {
+	switch sortBy {
+	case UserSortEmail:
+		return UserListKey{Value: u.Email, ID: u.ID}
+	case UserSortName:
+		return UserListKey{Value: u.Name.String, ID: u.ID}
+	default:
+		return UserListKey{Value: u.CreatedAt.Format(time.RFC3339Nano), ID: u.ID}
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// where builds the filter part of q, appending its parameters to args.
+func (q UserListQuery) where(args *[]any) string {
+	param := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	conds := []string{userInTenant}
+	if q.Status != "" {
+		conds = append(conds, userStatus+" = "+param(q.Status))
+	} else {
+		conds = append(conds, userStatus+" <> "+param(model.StatusDeleted))
+	}
+	if q.Search != "" {
+		p := param("%" + likeEscaper.Replace(q.Search) + "%")
+		conds = append(conds, fmt.Sprintf("(email ILIKE %s OR name ILIKE %s)", p, p))
+	}
+	if !q.CreatedFrom.IsZero() {
+		conds = append(conds, "created_at >= "+param(q.CreatedFrom))
+	}
+	if !q.CreatedTo.IsZero() {
+		conds = append(conds, "created_at < "+param(q.CreatedTo))
+	}
+	if len(q.Attributes) > 0 {
+		// marshalled here so that Stream can inline it as text
+		b, _ := json.Marshal(q.Attributes)
+		conds = append(conds, "attributes @> "+param(string(b))+"::jsonb")
+	}
+	if q.GroupID != "" {
+		conds = append(conds, "id IN (SELECT user_id FROM user_group_members WHERE group_id = "+param(q.GroupID)+"::uuid)")
+	}
+
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func (r *userRepo) List(ctx context.Context, q UserListQuery) ([]model.User, error) {
+	column, ok := userSortColumns[q.SortBy]
+	if !ok {
+		return nil, errors.WithInvalid(errors.Errorf("Invalid sort %q", q.SortBy), "")
+	}
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	args := []any{}
+	query := `SELECT ` + userColumns + ` FROM users` + q.where(&args)
+
+	if q.After != nil {
+		var value any = q.After.Value
+		if q.SortBy == UserSortCreatedAt {
+			t, err := time.Parse(time.RFC3339Nano, q.After.Value)
+			if err != nil {
+				return nil, errors.WithInvalid(errors.New("Invalid cursor"), "")
+			}
+			value = t
+		}
+		args = append(args, value, q.After.ID)
+		query += fmt.Sprintf(` AND (%s, id) %s ($%d, $%d)`, column, cmp, len(args)-1, len(args))
+	}
+
+	args = append(args, q.Limit)
+	query += fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT $%d`, column, dir, dir, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	return users, errors.WithStack(rows.Err())
+}
+
+func (r *userRepo) Count(ctx context.Context, q UserListQuery) (int, error) {
+	args := []any{}
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM users`+q.where(&args), args...).Scan(&n)
+	return n, errors.WithStack(err)
+}
+
+// streamBatchSize is how many rows Stream fetches from its cursor at a time.
+const streamBatchSize = 500
+
+// Stream reads the users through a server-side cursor, so an export of the
+// whole table never sits in memory.
+func (r *userRepo) Stream(ctx context.Context, q UserListQuery, fn func(*model.User) error) error {
+	column, ok := userSortColumns[q.SortBy]
+	if !ok {
+		return errors.WithInvalid(errors.Errorf("Invalid sort %q", q.SortBy), "")
+	}
+	dir := "ASC"
+	if q.Desc {
+		dir = "DESC"
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	args := []any{}
+	query := `DECLARE users_stream NO SCROLL CURSOR FOR SELECT ` + userColumns + ` FROM users` + q.where(&args) +
+		fmt.Sprintf(` ORDER BY %s %s, id %s`, column, dir, dir)
+	// DECLARE takes no bind parameters, so pgx inlines them
+	args = append([]any{pgx.QueryExecModeSimpleProtocol}, args...)
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH %d FROM users_stream`, streamBatchSize))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		n := 0
+		for rows.Next() {
+			n++
+			u, err := scanUser(rows)
+			if err == nil {
+				err = fn(u)
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		if n < streamBatchSize {
+			return nil
+		}
+	}
+}
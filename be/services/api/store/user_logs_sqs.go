@@ -3,16 +3,26 @@ package store
 import (
 	"be/pkg/errors"
 	"be/pkg/events"
+	"be/pkg/impersonation"
+	"be/pkg/tenant"
 	"context"
 	"encoding/json"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
+// UserLogsQueue sends user log events to the worker. Events without an OrgID
+// are logged in the organization the context acts in, and events sent while
+// an admin impersonates the user record that admin.
 type UserLogsQueue interface {
 	Enqueue(ctx context.Context, ev events.UserLogsEvent) error
+	// EnqueueBatch sends the events ten at a time, the most SendMessageBatch
+	// takes.
+	EnqueueBatch(ctx context.Context, evs []events.UserLogsEvent) error
 }
 
 type sqsService struct {
@@ -25,18 +35,32 @@ func NewUserLogsSQS(awsConfig aws.Config, queueURL string) UserLogsQueue {
 }
 
 func (s *sqsService) Enqueue(ctx context.Context, ev events.UserLogsEvent) error {
-	bts, err := json.Marshal(ev)
+	return s.send(ctx, events.RouteUserLogs, withContext(ctx, ev))
+}
+
+// send publishes v as JSON to the worker handler registered for route.
+func (s *sqsService) send(ctx context.Context, route string, v any) error {
+	return s.sendAfter(ctx, route, v, 0)
+}
+
+// maxDelay is the longest SQS holds back a message.
+const maxDelay = 15 * time.Minute
+
+// sendAfter is send with the message hidden from the worker for delay.
+func (s *sqsService) sendAfter(ctx context.Context, route string, v any, delay time.Duration) error {
+	bts, err := json.Marshal(v)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
 	in := &sqs.SendMessageInput{
-		QueueUrl:    aws.String(s.queueURL),
-		MessageBody: aws.String(string(bts)),
+		QueueUrl:     aws.String(s.queueURL),
+		MessageBody:  aws.String(string(bts)),
+		DelaySeconds: int32(min(delay, maxDelay) / time.Second),
 		MessageAttributes: map[string]types.MessageAttributeValue{
 			"route": {
 				DataType:    aws.String("String"),
-				StringValue: aws.String("userloggers"),
+				StringValue: aws.String(route),
 			},
 		},
 	}
@@ -44,3 +68,54 @@ func (s *sqsService) Enqueue(ctx context.Context, ev events.UserLogsEvent) error
 	_, err = s.sqsClient.SendMessage(ctx, in)
 	return errors.WithStack(err)
 }
+
+func withContext(ctx context.Context, ev events.UserLogsEvent) events.UserLogsEvent {
+	if ev.OrgID == "" {
+		ev.OrgID = tenant.OrgID(ctx)
+	}
+	if ev.Impersonator == "" {
+		ev.Impersonator = impersonation.Impersonator(ctx)
+	}
+	return ev
+}
+
+// maxBatchEntries is the most messages SQS accepts in one SendMessageBatch.
+const maxBatchEntries = 10
+
+func (s *sqsService) EnqueueBatch(ctx context.Context, evs []events.UserLogsEvent) error {
+	for start := 0; start < len(evs); start += maxBatchEntries {
+		chunk := evs[start:min(start+maxBatchEntries, len(evs))]
+
+		entries := make([]types.SendMessageBatchRequestEntry, 0, len(chunk))
+		for i, ev := range chunk {
+			bts, err := json.Marshal(withContext(ctx, ev))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			entries = append(entries, types.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(i)),
+				MessageBody: aws.String(string(bts)),
+				MessageAttributes: map[string]types.MessageAttributeValue{
+					"route": {
+						DataType:    aws.String("String"),
+						StringValue: aws.String(events.RouteUserLogs),
+					},
+				},
+			})
+		}
+
+		out, err := s.sqsClient.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(s.queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// a batch succeeds as a whole call even when some entries fail
+		if len(out.Failed) > 0 {
+			f := out.Failed[0]
+			return errors.Errorf("%d of %d user log events not sent: %s", len(out.Failed), len(entries), aws.ToString(f.Message))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserTokenRepository stores hashed, expiring, single-use tokens such as
+// password reset tokens. kind is one of the model.Token* constants.
+type UserTokenRepository interface {
+	Create(ctx context.Context, kind, userID, tokenHash string, expiresAt time.Time) error
+	Consume(ctx context.Context, kind, tokenHash string) (string, error)
+	InvalidateAll(ctx context.Context, kind, userID string) error
+}
+
+type userTokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTokenRepo(pool *pgxpool.Pool) UserTokenRepository {
+	return &userTokenRepo{db: pool}
+}
+
+func (r *userTokenRepo) Create(ctx context.Context, kind, userID, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO user_tokens (token_hash,kind,user_id,expires_at,created_at) VALUES ($1,$2,$3,$4,$5)`,
+		tokenHash, kind, userID, expiresAt, time.Now().UTC(),
+	)
+	return errors.WithStack(err)
+}
+
+// Consume marks an unused, unexpired token as used and returns its user ID.
+func (r *userTokenRepo) Consume(ctx context.Context, kind, tokenHash string) (string, error) {
+	var userID string
+	now := time.Now().UTC()
+	err := r.db.QueryRow(ctx, `
+        UPDATE user_tokens
+        SET used_at = $1
+        WHERE token_hash = $2 AND kind = $3 AND used_at IS NULL AND expires_at > $1
+        RETURNING user_id
+    `, now, tokenHash, kind).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return "", errors.WithNotFound(errors.New("Invalid or expired token"), "")
+	}
+	return userID, errors.WithStack(err)
+}
+
+func (r *userTokenRepo) InvalidateAll(ctx context.Context, kind, userID string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE user_tokens
+        SET used_at = $1
+        WHERE user_id = $2 AND kind = $3 AND used_at IS NULL
+    `, time.Now().UTC(), userID, kind)
+	return errors.WithStack(err)
+}
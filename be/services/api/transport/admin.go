@@ -7,6 +7,7 @@ import (
 
 	"be/pkg/errors"
 	pkghttp "be/pkg/http"
+	"be/pkg/model"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -15,36 +16,74 @@ type AdminController struct {
 	r        chi.Router
 	userSvc  service.UserService
 	adminSvc service.AdminService
-	jwtKey   string
+	auth     func(http.Handler) http.Handler
 }
 
-func NewAdminController(r chi.Router, userSvc service.UserService, adminSvc service.AdminService, jwtKey string) *AdminController {
-	return &AdminController{r: r, userSvc: userSvc, adminSvc: adminSvc, jwtKey: jwtKey}
+func NewAdminController(r chi.Router, userSvc service.UserService, adminSvc service.AdminService, auth func(http.Handler) http.Handler) *AdminController {
+	return &AdminController{r: r, userSvc: userSvc, adminSvc: adminSvc, auth: auth}
 }
 
 func (uc *AdminController) RegisterRoutes() {
 	uc.r.Group(func(r chi.Router) {
-		r.Use(pkghttp.AuthMiddleware(uc.jwtKey))
-		r.Get("/admin/users", uc.listUsers)
-		r.Put("/admin/users", uc.updateUser)
-		r.Delete("/admin/users", uc.deleteUser)
+		r.Use(uc.auth)
 
-		r.Get("/admin/userlogs", uc.listUserLogs)
+		r.With(pkghttp.RequireAccess(model.ScopeUsersRead, model.RoleAdmin, model.RoleAuditor)).
+			Get("/admin/users", uc.listUsers)
+		r.With(pkghttp.RequireAccess(model.ScopeUsersRead, model.RoleAdmin, model.RoleAuditor)).
+			Get("/admin/users/{id}", uc.getUser)
+		r.With(pkghttp.RequireAccess(model.ScopeUserLogsRead, model.RoleAdmin, model.RoleAuditor)).
+			Get("/admin/userlogs", uc.listUserLogs)
+
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireAccess(model.ScopeUsersWrite, model.RoleAdmin))
+			r.Put("/admin/users", uc.updateUser)
+			r.Patch("/admin/users/{id}", uc.patchUser)
+			r.Post("/admin/users/{id}/restore", uc.restoreUser)
+			r.Post("/admin/users/verification", uc.resendVerification)
+
+			r.Group(func(r chi.Router) {
+				r.Use(pkghttp.DenyImpersonation)
+				r.Delete("/admin/users", uc.deleteUser)
+				r.Post("/admin/users/{id}/suspend", uc.suspendUser)
+				r.Put("/admin/users/bulk", uc.bulkUpdateUsers)
+				r.Delete("/admin/users/bulk", uc.bulkDeleteUsers)
+			})
+		})
+
+		// granting roles and impersonating stay with human admins, API keys
+		// cannot escalate
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireRole(model.RoleAdmin), pkghttp.DenyImpersonation)
+			r.Put("/admin/users/role", uc.updateUserRole)
+			r.Post("/admin/users/{id}/impersonate", uc.impersonateUser)
+		})
 	})
 }
 
 func (uc *AdminController) listUsers(w http.ResponseWriter, r *http.Request) {
 	input := AdminListUsersInput{}
-	input.Bind(r.URL.Query())
+	if err := input.Bind(r.URL.Query()); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeUserPage(w, r, uc.adminSvc, input.Options())
+}
 
-	users, err := uc.adminSvc.ListUsers(r.Context(), input.Limit, input.Cursor)
+// writeUserPage answers with the page of the user list opts selects.
+func writeUserPage(w http.ResponseWriter, r *http.Request, svc service.AdminService, opts service.ListUsersOptions) {
+	page, err := svc.ListUsers(r.Context(), opts)
 	if err != nil {
-		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, newErrorResponse(err))
 		return
 	}
 
 	res := AdminListUsersResponse{}
-	res.Bind(users)
+	res.Bind(page)
 	pkghttp.JSON(w, http.StatusOK, res)
 }
 
@@ -64,8 +103,8 @@ func (uc *AdminController) listUserLogs(w http.ResponseWriter, r *http.Request)
 }
 
 func (uc *AdminController) updateUser(w http.ResponseWriter, r *http.Request) {
-	adminID := pkghttp.GetUserID(w, r)
-	if adminID == "" {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
 		return
 	}
 
@@ -75,7 +114,55 @@ func (uc *AdminController) updateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := uc.adminSvc.UpdateUser(r.Context(), adminID, input.ID, input.Email, input.Name)
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	// empty fields are left as they are, PATCH can clear them
+	var patch service.UserPatch
+	if input.Email != "" {
+		patch.Email = &input.Email
+	}
+	if input.Name != "" {
+		patch.Name = &input.Name
+	}
+	uc.writePatchedUser(w, r, actor, input.ID, patch, version)
+}
+
+func (uc *AdminController) patchUser(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	if !isMergePatch(r) {
+		pkghttp.JSON(w, http.StatusUnsupportedMediaType, ErrorResponse{Error: "Content-Type must be application/merge-patch+json"})
+		return
+	}
+
+	var input AdminPatchUserInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, newErrorResponse(err))
+		return
+	}
+
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	uc.writePatchedUser(w, r, actor, chi.URLParam(r, "id"), input.Patch, version)
+}
+
+func (uc *AdminController) writePatchedUser(w http.ResponseWriter, r *http.Request, actor model.Actor, userID string, patch service.UserPatch, version int) {
+	u, _, err := uc.adminSvc.PatchUser(r.Context(), actor, userID, patch, version)
+	if errors.IsConflict(err) {
+		res := AdminUpdateUserResponse{}
+		res.Bind(u)
+		writeVersioned(w, http.StatusPreconditionFailed, u, res)
+		return
+	}
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.IsInvalid(err) {
@@ -84,18 +171,35 @@ func (uc *AdminController) updateUser(w http.ResponseWriter, r *http.Request) {
 		if errors.IsNotFound(err) {
 			status = http.StatusNotFound
 		}
-		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		if errors.IsForbidden(err) {
+			status = http.StatusForbidden
+		}
+		pkghttp.JSON(w, status, newErrorResponse(err))
 		return
 	}
 
 	res := AdminUpdateUserResponse{}
 	res.Bind(u)
-	pkghttp.JSON(w, http.StatusOK, res)
+	writeVersioned(w, http.StatusOK, u, res)
+}
+
+func (uc *AdminController) getUser(w http.ResponseWriter, r *http.Request) {
+	u, err := uc.adminSvc.GetUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeVersioned(w, http.StatusOK, u, newAdminUserResponse(u))
 }
 
 func (uc *AdminController) deleteUser(w http.ResponseWriter, r *http.Request) {
-	adminID := pkghttp.GetUserID(w, r)
-	if adminID == "" {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
 		return
 	}
 
@@ -105,7 +209,7 @@ func (uc *AdminController) deleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := uc.adminSvc.DeleteUser(r.Context(), adminID, input.ID)
+	err := uc.adminSvc.DeleteUser(r.Context(), actor, input.ID)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.IsInvalid(err) {
@@ -120,3 +224,175 @@ func (uc *AdminController) deleteUser(w http.ResponseWriter, r *http.Request) {
 
 	pkghttp.JSON(w, http.StatusNoContent, "")
 }
+
+func (uc *AdminController) suspendUser(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := uc.adminSvc.SuspendUser(r.Context(), actor, chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := AdminUpdateUserResponse{}
+	res.Bind(u)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *AdminController) restoreUser(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	u, _, err := uc.adminSvc.RestoreUser(r.Context(), actor, chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := AdminUpdateUserResponse{}
+	res.Bind(u)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *AdminController) updateUserRole(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input AdminUpdateUserRoleInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	u, _, err := uc.adminSvc.UpdateUserRole(r.Context(), actor, input.ID, input.Role)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := AdminUpdateUserResponse{}
+	res.Bind(u)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *AdminController) resendVerification(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input AdminResendVerificationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	err := uc.adminSvc.ResendVerification(r.Context(), actor, input.ID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusAccepted, "")
+}
+
+func (uc *AdminController) bulkUpdateUsers(w http.ResponseWriter, r *http.Request) {
+	uc.bulkUsers(w, r, false)
+}
+
+func (uc *AdminController) bulkDeleteUsers(w http.ResponseWriter, r *http.Request) {
+	uc.bulkUsers(w, r, true)
+}
+
+func (uc *AdminController) bulkUsers(w http.ResponseWriter, r *http.Request, del bool) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input AdminBulkUsersInput
+	op, err := input.Bind(r, del)
+	if err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	// granting roles stays with human admins, as on PUT /admin/users/role
+	if op.Role != "" && pkghttp.GetRole(r) != model.RoleAdmin {
+		pkghttp.JSON(w, http.StatusForbidden, ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	report, err := uc.adminSvc.BulkUpdateUsers(r.Context(), actor, op)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := AdminBulkUsersResponse{}
+	res.Bind(report)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *AdminController) impersonateUser(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+	sessionID, _ := r.Context().Value(pkghttp.SessionIDKey).(string)
+
+	userID := chi.URLParam(r, "id")
+	tokens, err := uc.adminSvc.Impersonate(r.Context(), actor, sessionID, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		} else if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ImpersonateUserResponse{}
+	res.Bind(userID, tokens)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
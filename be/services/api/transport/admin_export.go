@@ -0,0 +1,209 @@
+package transport
+
+import (
+	"api/service"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"be/pkg/errors"
+	"be/pkg/export"
+	pkghttp "be/pkg/http"
+	"be/pkg/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// exportFlushEvery is how many records are buffered before a streamed export
+// is flushed to the client.
+const exportFlushEvery = 100
+
+type AdminExportController struct {
+	r    chi.Router
+	svc  service.AdminExportService
+	auth func(http.Handler) http.Handler
+}
+
+func NewAdminExportController(r chi.Router, svc service.AdminExportService, auth func(http.Handler) http.Handler) *AdminExportController {
+	return &AdminExportController{r: r, svc: svc, auth: auth}
+}
+
+func (ec *AdminExportController) RegisterRoutes() {
+	ec.r.Group(func(r chi.Router) {
+		r.Use(ec.auth)
+
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireAccess(model.ScopeUsersRead, model.RoleAdmin, model.RoleAuditor))
+			r.Get("/admin/users/export", ec.exportUsers)
+			r.Get("/admin/users/export/{id}", ec.get(model.AdminExportUsers))
+			r.Get("/admin/users/export/{id}/download", ec.download(model.AdminExportUsers))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireAccess(model.ScopeUserLogsRead, model.RoleAdmin, model.RoleAuditor))
+			r.Get("/admin/userlogs/export", ec.exportUserLogs)
+			r.Get("/admin/userlogs/export/{id}", ec.get(model.AdminExportUserLogs))
+			r.Get("/admin/userlogs/export/{id}/download", ec.download(model.AdminExportUserLogs))
+		})
+	})
+}
+
+func (ec *AdminExportController) exportUsers(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	input := AdminExportInput{}
+	if err := input.Bind(r, model.AdminExportUsers); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if input.Async {
+		ec.start(w, r, actor, model.AdminExportUsers, input)
+		return
+	}
+
+	streamExport(w, model.AdminExportUsers, input.Format, func(write func(export.UserRecord) error) error {
+		return ec.svc.StreamUsers(r.Context(), actor, input.Format, input.Filter, func(u *model.User) error {
+			return write(export.NewUserRecord(u))
+		})
+	})
+}
+
+func (ec *AdminExportController) exportUserLogs(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	input := AdminExportInput{}
+	if err := input.Bind(r, model.AdminExportUserLogs); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if input.Async {
+		ec.start(w, r, actor, model.AdminExportUserLogs, input)
+		return
+	}
+
+	streamExport(w, model.AdminExportUserLogs, input.Format, func(write func(export.UserLogRecord) error) error {
+		return ec.svc.StreamUserLogs(r.Context(), actor, input.Format, func(l *model.UserLogs) error {
+			return write(export.NewUserLogRecord(l))
+		})
+	})
+}
+
+// streamExport writes the records produced by stream as they come. The
+// response starts with the first record, so a failure before it still gets a
+// status code; a failure midway aborts the connection, leaving the download
+// visibly incomplete rather than silently short.
+func streamExport[T export.Record](w http.ResponseWriter, kind, format string, stream func(write func(T) error) error) {
+	var out *export.Writer[T]
+	n := 0
+	begin := func() error {
+		var err error
+		if out, err = export.NewWriter[T](w, format); err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, kind, time.Now().UTC().Format("20060102T150405Z"), format))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	err := stream(func(rec T) error {
+		if out == nil {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		if err := out.Write(rec); err != nil {
+			return err
+		}
+
+		n++
+		if n%exportFlushEvery == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			return http.NewResponseController(w).Flush()
+		}
+		return nil
+	})
+	if err == nil && out == nil {
+		err = begin()
+	}
+
+	if err != nil {
+		if out != nil {
+			panic(http.ErrAbortHandler)
+		}
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := out.Flush(); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (ec *AdminExportController) start(w http.ResponseWriter, r *http.Request, actor model.Actor, kind string, input AdminExportInput) {
+	e, err := ec.svc.Start(r.Context(), actor, kind, input.Format, input.Filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusAccepted, newAdminExportResponse(e))
+}
+
+func (ec *AdminExportController) get(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e, err := ec.svc.Get(r.Context(), kind, chi.URLParam(r, "id"), false)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.IsNotFound(err) {
+				status = http.StatusNotFound
+			}
+			pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		pkghttp.JSON(w, http.StatusOK, newAdminExportResponse(e))
+	}
+}
+
+func (ec *AdminExportController) download(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e, err := ec.svc.Get(r.Context(), kind, chi.URLParam(r, "id"), true)
+		if err == nil && e.Status != model.DataExportReady {
+			err = errors.WithNotFound(errors.New("Export not ready"), "")
+		}
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.IsNotFound(err) {
+				status = http.StatusNotFound
+			}
+			pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		w.Header().Set("Content-Type", export.ContentType(e.Format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, e.Kind, e.ID, e.Format))
+		w.Header().Set("Content-Length", strconv.Itoa(len(e.File)))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(e.File)
+	}
+}
@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"be/pkg/export"
+	"be/pkg/model"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFormat picks the format of an export from the "format" query
+// parameter, or else the first supported type in the Accept header. CSV is the
+// default.
+func exportFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		switch mediaType {
+		case "text/csv":
+			return export.FormatCSV
+		case "application/x-ndjson", "application/ndjson":
+			return export.FormatNDJSON
+		}
+	}
+	return export.FormatCSV
+}
+
+// AdminExportInput reads the export options and, for user exports, the same
+// filters as the user list.
+type AdminExportInput struct {
+	Format string
+	// Async builds the export in the background instead of streaming it.
+	Async  bool
+	Filter model.UserFilter
+}
+
+func (req *AdminExportInput) Bind(r *http.Request, kind string) error {
+	req.Format = exportFormat(r)
+	req.Async, _ = strconv.ParseBool(r.URL.Query().Get("async"))
+
+	if kind != model.AdminExportUsers {
+		return nil
+	}
+
+	list := AdminListUsersInput{}
+	if err := list.Bind(r.URL.Query()); err != nil {
+		return err
+	}
+	req.Filter = model.UserFilter{
+		Search:      list.Search,
+		Status:      list.Status,
+		CreatedFrom: list.CreatedFrom,
+		CreatedTo:   list.CreatedTo,
+		SortBy:      list.Sort,
+		Desc:        list.Order != "asc",
+	}
+	return nil
+}
+
+type AdminExportResponse struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Format string `json:"format"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// DownloadURL is set once the export is ready.
+	DownloadURL string     `json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+func newAdminExportResponse(e *model.AdminExport) AdminExportResponse {
+	res := AdminExportResponse{
+		ID:        e.ID,
+		Kind:      e.Kind,
+		Format:    e.Format,
+		Status:    e.Status,
+		Error:     e.Error.String,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.Status == model.DataExportReady {
+		res.DownloadURL = fmt.Sprintf("/admin/%s/export/%s/download", e.Kind, e.ID)
+	}
+	if e.CompletedAt.Valid {
+		res.CompletedAt = &e.CompletedAt.Time
+	}
+	return res
+}
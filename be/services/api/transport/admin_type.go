@@ -1,29 +1,75 @@
 package transport
 
 import (
+	"api/service"
 	"be/pkg/model"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime"
 	"net/http"
 	"net/mail"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// AdminUserResponse is UserResponse plus the fields only admins see.
 type AdminUserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	UserResponse
+	Role        string     `json:"role"`
+	Status      string     `json:"status"`
+	SuspendedAt *time.Time `json:"suspended_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	// Version is what a PUT sends back in If-Match, like the ETag of a
+	// single user.
+	Version    int              `json:"version"`
+	Attributes model.Attributes `json:"attributes"`
+}
+
+func newAdminUserResponse(u *model.User) AdminUserResponse {
+	res := AdminUserResponse{
+		UserResponse: newUserResponse(u),
+		Role:         u.Role,
+		Status:       u.Status,
+		Version:      u.Version,
+		Attributes:   u.Attributes,
+	}
+	if res.Attributes == nil {
+		res.Attributes = model.Attributes{}
+	}
+	if u.SuspendedAt.Valid {
+		res.SuspendedAt = &u.SuspendedAt.Time
+	}
+	if u.DeletedAt.Valid {
+		res.DeletedAt = &u.DeletedAt.Time
+	}
+	return res
 }
 
 type AdminListUsersInput struct {
 	Limit  int    `json:"limit"`
 	Cursor string `json:"cursor"`
+	// Search matches a substring of the email or name.
+	Search string `json:"q"`
+	// Status filters on one status; empty lists every user but deleted ones.
+	Status string `json:"status"`
+	// CreatedFrom and CreatedTo bound the creation time, as RFC 3339
+	// timestamps or dates. A date as CreatedTo includes that whole day.
+	CreatedFrom time.Time `json:"created_from"`
+	CreatedTo   time.Time `json:"created_to"`
+	Sort        string    `json:"sort"`
+	// Order is "asc" or "desc", desc by default.
+	Order     string `json:"order"`
+	WithTotal bool   `json:"total"`
+	// Attributes filters on attribute values, given as attr.<name>=value.
+	Attributes map[string]string `json:"-"`
+	// Group filters on the members of a group.
+	Group string `json:"group"`
 }
 
-func (req *AdminListUsersInput) Bind(values url.Values) {
+func (req *AdminListUsersInput) Bind(values url.Values) error {
 	req.Limit = 10
 	if s := values.Get("limit"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil && v > 0 {
@@ -32,30 +78,94 @@ func (req *AdminListUsersInput) Bind(values url.Values) {
 	}
 
 	req.Cursor = values.Get("cursor")
+	req.Search = strings.TrimSpace(values.Get("q"))
+
+	req.Status = values.Get("status")
+	if req.Status != "" && !model.IsValidStatus(req.Status) {
+		return errors.New("invalid status")
+	}
+
+	var err error
+	if req.CreatedFrom, err = parseTimeBound(values.Get("created_from"), false); err != nil {
+		return errors.New("invalid created_from")
+	}
+	if req.CreatedTo, err = parseTimeBound(values.Get("created_to"), true); err != nil {
+		return errors.New("invalid created_to")
+	}
+
+	req.Sort = values.Get("sort")
+	req.Order = strings.ToLower(values.Get("order"))
+	if req.Order != "" && req.Order != "asc" && req.Order != "desc" {
+		return errors.New("invalid order")
+	}
+
+	req.WithTotal, _ = strconv.ParseBool(values.Get("total"))
+	req.Group = values.Get("group")
+
+	for key := range values {
+		if name, ok := strings.CutPrefix(key, "attr."); ok {
+			if req.Attributes == nil {
+				req.Attributes = map[string]string{}
+			}
+			req.Attributes[name] = values.Get(key)
+		}
+	}
+
+	return nil
+}
+
+func (req *AdminListUsersInput) Options() service.ListUsersOptions {
+	return service.ListUsersOptions{
+		Search:      req.Search,
+		Status:      req.Status,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		SortBy:      req.Sort,
+		Desc:        req.Order != "asc",
+		Limit:       req.Limit,
+		Cursor:      req.Cursor,
+		WithTotal:   req.WithTotal,
+		Attributes:  req.Attributes,
+		GroupID:     req.Group,
+	}
+}
+
+// parseTimeBound reads an RFC 3339 timestamp or a date. As an upper bound a
+// date stands for the end of that day.
+func parseTimeBound(s string, upper bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
 }
 
 type AdminListUsersResponse struct {
 	Users      []AdminUserResponse `json:"users"`
 	NextCursor string              `json:"next_cursor"`
+	PrevCursor string              `json:"prev_cursor"`
+	Total      *int                `json:"total,omitempty"`
 }
 
-func (res *AdminListUsersResponse) Bind(users []model.User) {
-	if len(users) == 0 {
-		res.Users = []AdminUserResponse{}
-		return
-	}
-
-	res.Users = make([]AdminUserResponse, 0, len(users))
-	for _, u := range users {
-		res.Users = append(res.Users, AdminUserResponse{
-			ID:        u.ID,
-			Email:     u.Email,
-			Name:      u.Name.String,
-			CreatedAt: u.CreatedAt,
-		})
+func (res *AdminListUsersResponse) Bind(page *service.UserPage) {
+	res.Users = make([]AdminUserResponse, 0, len(page.Users))
+	for _, u := range page.Users {
+		res.Users = append(res.Users, newAdminUserResponse(&u))
 	}
 
-	res.NextCursor = res.Users[len(res.Users)-1].ID
+	res.NextCursor = page.NextCursor
+	res.PrevCursor = page.PrevCursor
+	res.Total = page.Total
 }
 
 type AdminUpdateUserInput struct {
@@ -80,22 +190,112 @@ func (req *AdminUpdateUserInput) Bind(r *http.Request) error {
 	return nil
 }
 
+// AdminPatchUserInput is a JSON Merge Patch (RFC 7396) of a user: a field
+// left out is kept and a field set to null is cleared.
+type AdminPatchUserInput struct {
+	Patch service.UserPatch
+}
+
+// isMergePatch reports whether the body is a merge patch. Plain JSON is
+// accepted too as it reads the same.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/merge-patch+json" || mediaType == "application/json"
+}
+
+func (req *AdminPatchUserInput) Bind(r *http.Request) error {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil || fields == nil {
+		return errors.New("body must be a JSON object")
+	}
+
+	invalid := fieldErrors{}
+	for field, raw := range fields {
+		isNull := string(raw) == "null"
+		var value string
+		if field != "attributes" && !isNull && json.Unmarshal(raw, &value) != nil {
+			invalid[field] = "must be a string"
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "email":
+			switch {
+			case isNull:
+				invalid[field] = "cannot be removed"
+			case !model.IsValidEmail(value):
+				invalid[field] = "invalid email format"
+			default:
+				req.Patch.Email = &value
+			}
+		case "name":
+			if len(value) > model.MaxNameLength {
+				invalid[field] = fmt.Sprintf("longer than %d characters", model.MaxNameLength)
+				continue
+			}
+			req.Patch.Name = &value
+		case "attributes":
+			// each attribute is patched on its own, null removes it
+			if isNull || json.Unmarshal(raw, &req.Patch.Attributes) != nil {
+				invalid[field] = "must be an object"
+			}
+		default:
+			invalid[field] = "unknown field"
+		}
+	}
+
+	if len(invalid) > 0 {
+		return invalid
+	}
+	return nil
+}
+
 type AdminUpdateUserResponse struct {
 	AdminUserResponse
 }
 
 func (res *AdminUpdateUserResponse) Bind(u *model.User) {
-	res.ID = u.ID
-	res.Email = u.Email
-	res.Name = u.Name.String
-	res.CreatedAt = u.CreatedAt
+	res.AdminUserResponse = newAdminUserResponse(u)
+}
+
+type AdminUpdateUserRoleInput struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+}
+
+func (req *AdminUpdateUserRoleInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if !model.IsValidRole(req.Role) {
+		return errors.New("invalid role")
+	}
+
+	return nil
 }
 
 type AdminUserLogResponse struct {
-	UserID    string    `json:"user_id"`
-	EventType string    `json:"event_type"`
-	Details   string    `json:"details"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	EventType    string    `json:"event_type"`
+	Details      string    `json:"details"`
+	Actor        string    `json:"actor,omitempty"`
+	Impersonator string    `json:"impersonator,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newAdminUserLogResponse(l *model.UserLogs) AdminUserLogResponse {
+	return AdminUserLogResponse{
+		ID:           l.ID,
+		UserID:       l.UserID,
+		EventType:    l.EventType,
+		Details:      l.Details,
+		Actor:        l.Actor,
+		Impersonator: l.Impersonator,
+		CreatedAt:    l.CreatedAt,
+	}
 }
 
 type AdminListUserLogsInput struct {
@@ -126,8 +326,8 @@ func (res *AdminListUserLogsResponse) Bind(userlogs []model.UserLogs, nextCursor
 	}
 
 	res.UserLogs = make([]AdminUserLogResponse, 0, len(userlogs))
-	for _, u := range userlogs {
-		res.UserLogs = append(res.UserLogs, AdminUserLogResponse(u))
+	for i := range userlogs {
+		res.UserLogs = append(res.UserLogs, newAdminUserLogResponse(&userlogs[i]))
 	}
 
 	res.NextCursor = nextCursor
@@ -136,3 +336,116 @@ func (res *AdminListUserLogsResponse) Bind(userlogs []model.UserLogs, nextCursor
 type AdminDeleteUserInput struct {
 	ID string `json:"id"`
 }
+
+type AdminResendVerificationInput struct {
+	ID string `json:"id"`
+}
+
+// AdminBulkFilter selects users like the user list query parameters.
+type AdminBulkFilter struct {
+	Search      string `json:"q"`
+	Status      string `json:"status"`
+	CreatedFrom string `json:"created_from"`
+	CreatedTo   string `json:"created_to"`
+	Group       string `json:"group"`
+}
+
+// AdminBulkUsersInput is the body of the bulk endpoints. Exactly one of IDs
+// and Filter is given.
+type AdminBulkUsersInput struct {
+	IDs    []string         `json:"ids"`
+	Filter *AdminBulkFilter `json:"filter"`
+	// Status is active or suspended; deleting goes through DELETE.
+	Status string `json:"status"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	DryRun bool   `json:"dry_run"`
+}
+
+func (req *AdminBulkUsersInput) Bind(r *http.Request, del bool) (service.BulkUserOperation, error) {
+	op := service.BulkUserOperation{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return op, err
+	}
+
+	if del {
+		if req.Status != "" || req.Name != "" || req.Role != "" {
+			return op, errors.New("bulk delete takes no fields")
+		}
+		req.Status = model.StatusDeleted
+	} else if req.Status == model.StatusDeleted {
+		return op, errors.New("use DELETE to delete users")
+	}
+
+	op = service.BulkUserOperation{
+		IDs:    req.IDs,
+		Status: req.Status,
+		Name:   strings.TrimSpace(req.Name),
+		Role:   req.Role,
+		DryRun: req.DryRun,
+	}
+	if f := req.Filter; f != nil {
+		op.Filter = &service.ListUsersOptions{Search: strings.TrimSpace(f.Search), Status: f.Status, GroupID: f.Group}
+
+		var err error
+		if op.Filter.CreatedFrom, err = parseTimeBound(f.CreatedFrom, false); err != nil {
+			return op, errors.New("invalid created_from")
+		}
+		if op.Filter.CreatedTo, err = parseTimeBound(f.CreatedTo, true); err != nil {
+			return op, errors.New("invalid created_to")
+		}
+	}
+	return op, nil
+}
+
+type AdminBulkFieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type AdminBulkUserResponse struct {
+	ID      string                          `json:"id"`
+	Email   string                          `json:"email,omitempty"`
+	Changes map[string]AdminBulkFieldChange `json:"changes,omitempty"`
+	Skipped string                          `json:"skipped,omitempty"`
+}
+
+type AdminBulkUsersResponse struct {
+	DryRun  bool                    `json:"dry_run"`
+	Matched int                     `json:"matched"`
+	Changed int                     `json:"changed"`
+	Users   []AdminBulkUserResponse `json:"users"`
+}
+
+func (res *AdminBulkUsersResponse) Bind(report *service.BulkUserReport) {
+	res.DryRun = report.DryRun
+	res.Matched = report.Matched
+	res.Changed = report.Changed
+	res.Users = make([]AdminBulkUserResponse, 0, len(report.Users))
+	for _, c := range report.Users {
+		u := AdminBulkUserResponse{ID: c.UserID, Email: c.Email, Skipped: c.Skipped}
+		if len(c.Changes) > 0 {
+			u.Changes = make(map[string]AdminBulkFieldChange, len(c.Changes))
+			for field, v := range c.Changes {
+				u.Changes[field] = AdminBulkFieldChange{From: v[0], To: v[1]}
+			}
+		}
+		res.Users = append(res.Users, u)
+	}
+}
+
+// ImpersonateUserResponse carries an access token acting as the user. There
+// is no refresh token: once it expires, the admin impersonates again.
+type ImpersonateUserResponse struct {
+	UserID    string `json:"user_id"`
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"`
+	OrgID     string `json:"org_id"`
+}
+
+func (res *ImpersonateUserResponse) Bind(userID string, tokens *service.Tokens) {
+	res.UserID = userID
+	res.Token = tokens.AccessToken
+	res.ExpiresIn = tokens.ExpiresIn
+	res.OrgID = tokens.OrgID
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"api/service"
+	"encoding/json"
+	"net/http"
+
+	"be/pkg/errors"
+	pkghttp "be/pkg/http"
+	"be/pkg/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeyController struct {
+	r    chi.Router
+	svc  service.APIKeyService
+	auth func(http.Handler) http.Handler
+}
+
+func NewAPIKeyController(r chi.Router, svc service.APIKeyService, auth func(http.Handler) http.Handler) *APIKeyController {
+	return &APIKeyController{r: r, svc: svc, auth: auth}
+}
+
+func (kc *APIKeyController) RegisterRoutes() {
+	kc.r.Group(func(r chi.Router) {
+		r.Use(kc.auth)
+		// keys carry no role, so they cannot manage keys themselves
+		r.Use(pkghttp.RequireRole(model.RoleAdmin), pkghttp.DenyImpersonation)
+		r.Get("/admin/apikeys", kc.list)
+		r.Post("/admin/apikeys", kc.create)
+		r.Delete("/admin/apikeys", kc.revoke)
+	})
+}
+
+func (kc *APIKeyController) list(w http.ResponseWriter, r *http.Request) {
+	keys, err := kc.svc.List(r.Context())
+	if err != nil {
+		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ListAPIKeysResponse{}
+	res.Bind(keys)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (kc *APIKeyController) create(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input CreateAPIKeyInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	k, key, err := kc.svc.Create(r.Context(), actor, input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := CreateAPIKeyResponse{}
+	res.Bind(k, key)
+	pkghttp.JSON(w, http.StatusCreated, res)
+}
+
+func (kc *APIKeyController) revoke(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input RevokeAPIKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_, err := kc.svc.Revoke(r.Context(), actor, input.ID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
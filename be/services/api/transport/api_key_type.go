@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"be/pkg/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(k *model.APIKey) APIKeyResponse {
+	res := APIKeyResponse{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		CreatedBy: k.CreatedBy.String,
+		CreatedAt: k.CreatedAt,
+	}
+	if k.ExpiresAt.Valid {
+		res.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		res.LastUsedAt = &k.LastUsedAt.Time
+	}
+	if k.RevokedAt.Valid {
+		res.RevokedAt = &k.RevokedAt.Time
+	}
+	return res
+}
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (req *CreateAPIKeyInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Name) == 0 {
+		return errors.New("missing name")
+	}
+
+	if len(req.Scopes) == 0 {
+		return errors.New("missing scopes")
+	}
+
+	return nil
+}
+
+// CreateAPIKeyResponse is the only response carrying the key itself.
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func (res *CreateAPIKeyResponse) Bind(k *model.APIKey, key string) {
+	res.APIKeyResponse = newAPIKeyResponse(k)
+	res.Key = key
+}
+
+type ListAPIKeysResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
+
+func (res *ListAPIKeysResponse) Bind(keys []model.APIKey) {
+	res.APIKeys = make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		res.APIKeys = append(res.APIKeys, newAPIKeyResponse(&keys[i]))
+	}
+}
+
+type RevokeAPIKeyInput struct {
+	ID string `json:"id"`
+}
@@ -0,0 +1,204 @@
+package transport
+
+import (
+	"api/service"
+	"net/http"
+
+	"be/pkg/errors"
+	pkghttp "be/pkg/http"
+	"be/pkg/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type GroupController struct {
+	r        chi.Router
+	svc      service.GroupService
+	adminSvc service.AdminService
+	auth     func(http.Handler) http.Handler
+}
+
+func NewGroupController(r chi.Router, svc service.GroupService, adminSvc service.AdminService, auth func(http.Handler) http.Handler) *GroupController {
+	return &GroupController{r: r, svc: svc, adminSvc: adminSvc, auth: auth}
+}
+
+func (gc *GroupController) RegisterRoutes() {
+	gc.r.Group(func(r chi.Router) {
+		r.Use(gc.auth)
+
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireAccess(model.ScopeUsersRead, model.RoleAdmin, model.RoleAuditor))
+			r.Get("/admin/groups", gc.list)
+			r.Get("/admin/groups/{id}", gc.get)
+			r.Get("/admin/groups/{id}/members", gc.listMembers)
+			r.Get("/admin/users/{id}/groups", gc.listUserGroups)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireAccess(model.ScopeUsersWrite, model.RoleAdmin))
+			r.Post("/admin/groups", gc.create)
+			r.Put("/admin/groups/{id}", gc.update)
+			r.With(pkghttp.DenyImpersonation).Delete("/admin/groups/{id}", gc.delete)
+			r.Post("/admin/groups/{id}/members", gc.addMembers)
+			r.Delete("/admin/groups/{id}/members/{userID}", gc.removeMember)
+		})
+	})
+}
+
+// groupErrorStatus maps the errors of the group service to a status.
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.IsInvalid(err):
+		return http.StatusBadRequest
+	case errors.IsNotFound(err):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (gc *GroupController) list(w http.ResponseWriter, r *http.Request) {
+	groups, err := gc.svc.List(r.Context())
+	if err != nil {
+		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ListGroupsResponse{}
+	res.Bind(groups)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (gc *GroupController) get(w http.ResponseWriter, r *http.Request) {
+	g, err := gc.svc.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusOK, newGroupResponse(g))
+}
+
+// listMembers pages through the members of the group like the user list,
+// taking the same query parameters.
+func (gc *GroupController) listMembers(w http.ResponseWriter, r *http.Request) {
+	g, err := gc.svc.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	input := AdminListUsersInput{}
+	if err := input.Bind(r.URL.Query()); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	opts := input.Options()
+	opts.GroupID = g.ID
+	writeUserPage(w, r, gc.adminSvc, opts)
+}
+
+func (gc *GroupController) listUserGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := gc.svc.ListUserGroups(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ListGroupsResponse{}
+	res.Bind(groups)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (gc *GroupController) create(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input SaveGroupInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	g, err := gc.svc.Create(r.Context(), actor, input.Name, input.Description)
+	if err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusCreated, newGroupResponse(g))
+}
+
+func (gc *GroupController) update(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input SaveGroupInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	g, err := gc.svc.Update(r.Context(), actor, chi.URLParam(r, "id"), input.Name, input.Description)
+	if err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusOK, newGroupResponse(g))
+}
+
+func (gc *GroupController) delete(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := gc.svc.Delete(r.Context(), actor, chi.URLParam(r, "id")); err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
+
+func (gc *GroupController) addMembers(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input AddGroupMembersInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	added, err := gc.svc.AddMembers(r.Context(), actor, chi.URLParam(r, "id"), input.UserIDs)
+	if err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusOK, AddGroupMembersResponse{Added: added})
+}
+
+func (gc *GroupController) removeMember(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	err := gc.svc.RemoveMember(r.Context(), actor, chi.URLParam(r, "id"), chi.URLParam(r, "userID"))
+	if err != nil {
+		pkghttp.JSON(w, groupErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
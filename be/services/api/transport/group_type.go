@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"be/pkg/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type GroupResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MemberCount int       `json:"member_count"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newGroupResponse(g *model.Group) GroupResponse {
+	return GroupResponse{
+		ID:          g.ID,
+		Name:        g.Name,
+		Description: g.Description,
+		MemberCount: g.MemberCount,
+		UpdatedAt:   g.UpdatedAt,
+		CreatedAt:   g.CreatedAt,
+	}
+}
+
+type ListGroupsResponse struct {
+	Groups []GroupResponse `json:"groups"`
+}
+
+func (res *ListGroupsResponse) Bind(groups []model.Group) {
+	res.Groups = make([]GroupResponse, 0, len(groups))
+	for i := range groups {
+		res.Groups = append(res.Groups, newGroupResponse(&groups[i]))
+	}
+}
+
+// SaveGroupInput is the body of both creating and replacing a group.
+type SaveGroupInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (req *SaveGroupInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Name) == 0 {
+		return errors.New("missing name")
+	}
+
+	return nil
+}
+
+type AddGroupMembersInput struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+func (req *AddGroupMembersInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.UserIDs) == 0 {
+		return errors.New("missing user_ids")
+	}
+
+	return nil
+}
+
+// AddGroupMembersResponse lists the users who were not in the group yet.
+type AddGroupMembersResponse struct {
+	Added []string `json:"added"`
+}
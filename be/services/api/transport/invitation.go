@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"api/service"
+	"net/http"
+
+	"be/pkg/errors"
+	pkghttp "be/pkg/http"
+	"be/pkg/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type InvitationController struct {
+	r         chi.Router
+	svc       service.InvitationService
+	auth      func(http.Handler) http.Handler
+	adminAuth func(http.Handler) http.Handler
+}
+
+// NewInvitationController takes the auth middleware of users, who accept
+// invitations, and the one of the admin routes.
+func NewInvitationController(r chi.Router, svc service.InvitationService, auth, adminAuth func(http.Handler) http.Handler) *InvitationController {
+	return &InvitationController{r: r, svc: svc, auth: auth, adminAuth: adminAuth}
+}
+
+func (ic *InvitationController) RegisterRoutes() {
+	ic.r.Group(func(r chi.Router) {
+		r.Use(ic.adminAuth)
+
+		r.With(pkghttp.RequireAccess(model.ScopeUsersRead, model.RoleAdmin, model.RoleAuditor)).
+			Get("/admin/invitations", ic.list)
+
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireAccess(model.ScopeUsersWrite, model.RoleAdmin), pkghttp.DenyImpersonation)
+			r.Post("/admin/invitations", ic.create)
+			r.Delete("/admin/invitations/{id}", ic.revoke)
+		})
+	})
+
+	ic.r.Group(func(r chi.Router) {
+		r.Use(ic.auth, pkghttp.DenyImpersonation)
+		r.Post("/users/me/invitations/accept", ic.accept)
+	})
+}
+
+func (ic *InvitationController) list(w http.ResponseWriter, r *http.Request) {
+	invitations, err := ic.svc.List(r.Context())
+	if err != nil {
+		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ListInvitationsResponse{}
+	res.Bind(invitations)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (ic *InvitationController) create(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input CreateInvitationInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	inv, err := ic.svc.Create(r.Context(), actor, input.Email, input.Role, input.ExpiresAt)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusCreated, newInvitationResponse(inv))
+}
+
+func (ic *InvitationController) accept(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	var input AcceptInvitationInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	inv, err := ic.svc.Accept(r.Context(), userID, input.Token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusOK, newInvitationResponse(inv))
+}
+
+func (ic *InvitationController) revoke(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := ic.svc.Revoke(r.Context(), actor, chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
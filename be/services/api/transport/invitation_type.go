@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"be/pkg/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type InvitationResponse struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	Status     string     `json:"status"`
+	InvitedBy  string     `json:"invited_by"`
+	UserID     string     `json:"user_id,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newInvitationResponse(inv *model.Invitation) InvitationResponse {
+	res := InvitationResponse{
+		ID:        inv.ID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		Status:    inv.Status,
+		InvitedBy: inv.InvitedBy,
+		UserID:    inv.UserID.String,
+		ExpiresAt: inv.ExpiresAt,
+		CreatedAt: inv.CreatedAt,
+	}
+	if inv.AcceptedAt.Valid {
+		res.AcceptedAt = &inv.AcceptedAt.Time
+	}
+	if inv.RevokedAt.Valid {
+		res.RevokedAt = &inv.RevokedAt.Time
+	}
+	return res
+}
+
+type ListInvitationsResponse struct {
+	Invitations []InvitationResponse `json:"invitations"`
+}
+
+func (res *ListInvitationsResponse) Bind(invitations []model.Invitation) {
+	res.Invitations = make([]InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		res.Invitations = append(res.Invitations, newInvitationResponse(&invitations[i]))
+	}
+}
+
+type CreateInvitationInput struct {
+	Email string `json:"email"`
+	// Role defaults to user.
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (req *CreateInvitationInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Email) == 0 {
+		return errors.New("missing email")
+	}
+
+	if req.Role == "" {
+		req.Role = model.RoleUser
+	}
+
+	return nil
+}
+
+type AcceptInvitationInput struct {
+	Token string `json:"token"`
+}
+
+func (req *AcceptInvitationInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Token) == 0 {
+		return errors.New("missing token")
+	}
+
+	return nil
+}
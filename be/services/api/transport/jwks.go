@@ -0,0 +1,31 @@
+package transport
+
+import (
+	"net/http"
+
+	pkghttp "be/pkg/http"
+	"be/pkg/jwtkeys"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// JWKSController publishes the public keys access tokens are signed with, so
+// other services can verify them without sharing a secret.
+type JWKSController struct {
+	r    chi.Router
+	keys *jwtkeys.KeySet
+}
+
+func NewJWKSController(r chi.Router, keys *jwtkeys.KeySet) *JWKSController {
+	return &JWKSController{r: r, keys: keys}
+}
+
+func (jc *JWKSController) RegisterRoutes() {
+	jc.r.Get("/.well-known/jwks.json", jc.jwks)
+}
+
+func (jc *JWKSController) jwks(w http.ResponseWriter, r *http.Request) {
+	// rotations wait longer than this between publishing a key and signing with it
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	pkghttp.JSON(w, http.StatusOK, jc.keys.JWKS())
+}
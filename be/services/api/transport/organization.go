@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"api/service"
+	"net/http"
+
+	"be/pkg/errors"
+	pkghttp "be/pkg/http"
+	"be/pkg/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OrganizationController struct {
+	r    chi.Router
+	svc  service.OrganizationService
+	auth func(http.Handler) http.Handler
+}
+
+func NewOrganizationController(r chi.Router, svc service.OrganizationService, auth func(http.Handler) http.Handler) *OrganizationController {
+	return &OrganizationController{r: r, svc: svc, auth: auth}
+}
+
+func (oc *OrganizationController) RegisterRoutes() {
+	oc.r.Group(func(r chi.Router) {
+		r.Use(oc.auth)
+		r.Use(pkghttp.RequireRole(model.RoleAdmin), pkghttp.DenyImpersonation)
+		// the default organization is the operator's, its admins onboard tenants
+		r.With(pkghttp.RequireOrg(model.DefaultOrgID)).Post("/admin/orgs", oc.create)
+		r.Delete("/admin/members/{id}", oc.removeMember)
+	})
+}
+
+func (oc *OrganizationController) create(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+	var input CreateOrganizationInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	o, err := oc.svc.Create(r.Context(), actor, input.Name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusCreated, newOrganizationResponse(o))
+}
+
+func (oc *OrganizationController) removeMember(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	err := oc.svc.RemoveMember(r.Context(), actor, chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
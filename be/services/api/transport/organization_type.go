@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"be/pkg/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type OrganizationResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newOrganizationResponse(o *model.Organization) OrganizationResponse {
+	return OrganizationResponse{ID: o.ID, Name: o.Name, CreatedAt: o.CreatedAt}
+}
+
+type MembershipResponse struct {
+	OrgID     string    `json:"org_id"`
+	OrgName   string    `json:"org_name"`
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newMembershipResponse(m *model.Membership) MembershipResponse {
+	return MembershipResponse{
+		OrgID:     m.OrgID,
+		OrgName:   m.OrgName,
+		UserID:    m.UserID,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+type ListMembershipsResponse struct {
+	Orgs []MembershipResponse `json:"orgs"`
+}
+
+func (res *ListMembershipsResponse) Bind(memberships []model.Membership) {
+	res.Orgs = make([]MembershipResponse, 0, len(memberships))
+	for i := range memberships {
+		res.Orgs = append(res.Orgs, newMembershipResponse(&memberships[i]))
+	}
+}
+
+type CreateOrganizationInput struct {
+	Name string `json:"name"`
+}
+
+func (req *CreateOrganizationInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Name) == 0 {
+		return errors.New("missing name")
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"api/service"
+	"be/pkg/errors"
+	"be/pkg/model"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	pkghttp "be/pkg/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PrivacyController struct {
+	r    chi.Router
+	svc  service.PrivacyService
+	auth func(http.Handler) http.Handler
+}
+
+func NewPrivacyController(r chi.Router, svc service.PrivacyService, auth func(http.Handler) http.Handler) *PrivacyController {
+	return &PrivacyController{r: r, svc: svc, auth: auth}
+}
+
+func (pc *PrivacyController) RegisterRoutes() {
+	pc.r.Group(func(r chi.Router) {
+		r.Use(pc.auth)
+		r.Get("/users/me/export", pc.export)
+		r.With(pkghttp.DenyImpersonation).Delete("/users/me", pc.deleteAccount)
+	})
+}
+
+// export answers 202 with the export status until the worker has built the
+// archive, then serves the archive itself.
+func (pc *PrivacyController) export(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	e, _, err := pc.svc.Export(r.Context(), userID)
+	if err != nil {
+		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if e.Status != model.DataExportReady {
+		pkghttp.JSON(w, http.StatusAccepted, newDataExportResponse(e))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, e.ID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(e.Archive)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(e.Archive)
+}
+
+func (pc *PrivacyController) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	var input DeleteAccountInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := pc.svc.DeleteAccount(r.Context(), userID, input.Password); err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
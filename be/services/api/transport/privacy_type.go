@@ -0,0 +1,35 @@
+package transport
+
+import (
+	"be/pkg/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type DataExportResponse struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+func newDataExportResponse(e *model.DataExport) DataExportResponse {
+	return DataExportResponse{ID: e.ID, Status: e.Status, RequestedAt: e.CreatedAt}
+}
+
+type DeleteAccountInput struct {
+	Password string `json:"password"`
+}
+
+func (req *DeleteAccountInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Password) == 0 {
+		return errors.New("missing password")
+	}
+
+	return nil
+}
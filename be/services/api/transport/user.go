@@ -3,6 +3,7 @@ package transport
 import (
 	"api/service"
 	"be/pkg/errors"
+	"be/pkg/model"
 	"net/http"
 
 	pkghttp "be/pkg/http"
@@ -11,17 +12,45 @@ import (
 )
 
 type UserController struct {
-	r   chi.Router
-	svc service.UserService
+	r    chi.Router
+	svc  service.UserService
+	auth func(http.Handler) http.Handler
 }
 
-func NewUserController(r chi.Router, svc service.UserService) *UserController {
-	return &UserController{r: r, svc: svc}
+func NewUserController(r chi.Router, svc service.UserService, auth func(http.Handler) http.Handler) *UserController {
+	return &UserController{r: r, svc: svc, auth: auth}
 }
 
 func (uc *UserController) RegisterRoutes() {
 	uc.r.Post("/users/signup", uc.signup)
 	uc.r.Post("/users/signin", uc.signin)
+	uc.r.Post("/users/signin/mfa", uc.signinMFA)
+	uc.r.Post("/users/refresh", uc.refresh)
+	uc.r.Post("/users/signout", uc.signout)
+	uc.r.Post("/users/password/forgot", uc.forgotPassword)
+	uc.r.Post("/users/password/reset", uc.resetPassword)
+	uc.r.Get("/users/verify", uc.verifyEmail)
+	uc.r.Get("/users/oidc/providers", uc.oidcProviders)
+	uc.r.Get("/users/oidc/{provider}/login", uc.oidcLogin)
+	uc.r.Get("/users/oidc/{provider}/callback", uc.oidcCallback)
+
+	uc.r.Group(func(r chi.Router) {
+		r.Use(uc.auth)
+		r.Get("/users/me", uc.getProfile)
+		r.Patch("/users/me", uc.updateProfile)
+		r.Get("/users/me/orgs", uc.listOrgs)
+
+		// credentials stay with the user, and an impersonation token carries
+		// the admin's session
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.DenyImpersonation)
+			r.Put("/users/me/password", uc.changePassword)
+			r.Post("/users/me/orgs/{id}/switch", uc.switchOrg)
+			r.Post("/users/mfa/totp", uc.enrollTOTP)
+			r.Post("/users/mfa/totp/confirm", uc.confirmTOTP)
+			r.Delete("/users/mfa/totp", uc.disableTOTP)
+		})
+	})
 }
 
 func (uc *UserController) signup(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +60,7 @@ func (uc *UserController) signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := uc.svc.SignUp(r.Context(), input.Email, input.Password)
+	id, _, err := uc.svc.SignUp(r.Context(), input.Email, input.Password, input.InvitationToken)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.IsInvalid(err) {
@@ -51,11 +80,415 @@ func (uc *UserController) signin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, _, err := uc.svc.SignIn(r.Context(), input.Email, input.Password)
+	tokens, _, err := uc.svc.SignIn(r.Context(), input.Email, input.Password, pkghttp.ClientIP(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusUnauthorized
+		}
+		if errors.IsTemporary(err) {
+			status = http.StatusTooManyRequests
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := SignInResponse{}
+	res.Bind(tokens)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *UserController) signinMFA(w http.ResponseWriter, r *http.Request) {
+	var input SignInMFAInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tokens, _, err := uc.svc.SignInMFA(r.Context(), input.MFAToken, input.Code)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) || errors.IsNotFound(err) {
+			status = http.StatusUnauthorized
+		}
+		if errors.IsTemporary(err) {
+			status = http.StatusTooManyRequests
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := SignInResponse{}
+	res.Bind(tokens)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *UserController) refresh(w http.ResponseWriter, r *http.Request) {
+	var input RefreshTokenInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tokens, _, err := uc.svc.Refresh(r.Context(), input.RefreshToken)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) || errors.IsInvalid(err) {
+			status = http.StatusUnauthorized
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := SignInResponse{}
+	res.Bind(tokens)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *UserController) signout(w http.ResponseWriter, r *http.Request) {
+	var input RefreshTokenInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_, err := uc.svc.SignOut(r.Context(), input.RefreshToken)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusUnauthorized
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
+
+func (uc *UserController) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input ForgotPasswordInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := uc.svc.ForgotPassword(r.Context(), input.Email); err != nil {
+		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusAccepted, "")
+}
+
+func (uc *UserController) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var input ResetPasswordInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_, err := uc.svc.ResetPassword(r.Context(), input.Token, input.Password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
+
+func (uc *UserController) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: "missing token"})
+		return
+	}
+
+	id, err := uc.svc.VerifyEmail(r.Context(), token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusOK, VerifyEmailResponse{UserID: id})
+}
+
+func (uc *UserController) oidcProviders(w http.ResponseWriter, r *http.Request) {
+	pkghttp.JSON(w, http.StatusOK, OIDCProvidersResponse{Providers: uc.svc.OIDCProviders()})
+}
+
+func (uc *UserController) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	url, err := uc.svc.OIDCAuthURL(r.Context(), chi.URLParam(r, "provider"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusOK, OIDCLoginResponse{AuthorizationURL: url})
+}
+
+func (uc *UserController) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	var input OIDCCallbackInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tokens, _, err := uc.svc.OIDCSignIn(r.Context(), chi.URLParam(r, "provider"), input.Code, input.State)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		if errors.IsInvalid(err) {
+			status = http.StatusUnauthorized
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := SignInResponse{}
+	res.Bind(tokens)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *UserController) getProfile(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	u, err := uc.svc.GetProfile(r.Context(), userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeVersioned(w, http.StatusOK, u, newUserResponse(u))
+}
+
+func (uc *UserController) updateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	var input UpdateProfileInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := uc.svc.UpdateProfile(r.Context(), userID, service.ProfileUpdate{Name: input.Name, Email: input.Email, Version: version})
+	if errors.IsConflict(err) {
+		writeVersioned(w, http.StatusPreconditionFailed, u, newUserResponse(u))
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeVersioned(w, http.StatusOK, u, newUserResponse(u))
+}
+
+func (uc *UserController) listOrgs(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	memberships, err := uc.svc.ListOrgs(r.Context(), userID)
+	if err != nil {
+		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ListMembershipsResponse{}
+	res.Bind(memberships)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *UserController) switchOrg(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	sessionID, _ := r.Context().Value(pkghttp.SessionIDKey).(string)
+	tokens, err := uc.svc.SwitchOrg(r.Context(), userID, sessionID, chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := SignInResponse{}
+	res.Bind(tokens)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (uc *UserController) changePassword(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	var input ChangePasswordInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	sessionID, _ := r.Context().Value(pkghttp.SessionIDKey).(string)
+	err := uc.svc.ChangePassword(r.Context(), userID, sessionID, input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
+
+func (uc *UserController) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	enrollment, err := uc.svc.EnrollTOTP(r.Context(), userID)
 	if err != nil {
-		pkghttp.JSON(w, http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := TOTPEnrollResponse{}
+	res.Bind(enrollment)
+	pkghttp.JSON(w, http.StatusCreated, res)
+}
+
+func (uc *UserController) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	var input TOTPCodeInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	pkghttp.JSON(w, http.StatusOK, SignInResponse{Token: token})
+	if err := uc.svc.ConfirmTOTP(r.Context(), userID, input.Code); err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
+
+func (uc *UserController) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := pkghttp.GetUserID(w, r)
+	if userID == "" {
+		return
+	}
+
+	var input TOTPCodeInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := uc.svc.DisableTOTP(r.Context(), userID, input.Code); err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		if errors.IsTemporary(err) {
+			status = http.StatusTooManyRequests
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
+
+// requireIfMatch reads the version a write is based on from If-Match, zero
+// for `If-Match: *`. It answers the request itself when the header is missing
+// or malformed.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, ok, err := pkghttp.IfMatch(r)
+	if !ok {
+		pkghttp.JSON(w, http.StatusPreconditionRequired, ErrorResponse{Error: "If-Match header is required"})
+		return 0, false
+	}
+	if err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return 0, false
+	}
+	return version, true
+}
+
+// writeVersioned answers with the user and its version as the ETag.
+func writeVersioned(w http.ResponseWriter, status int, u *model.User, res any) {
+	w.Header().Set("ETag", pkghttp.ETag(u.Version))
+	pkghttp.JSON(w, status, res)
 }
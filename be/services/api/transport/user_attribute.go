@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"api/service"
+	"net/http"
+
+	"be/pkg/errors"
+	pkghttp "be/pkg/http"
+	"be/pkg/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type UserAttributeController struct {
+	r    chi.Router
+	svc  service.UserAttributeService
+	auth func(http.Handler) http.Handler
+}
+
+func NewUserAttributeController(r chi.Router, svc service.UserAttributeService, auth func(http.Handler) http.Handler) *UserAttributeController {
+	return &UserAttributeController{r: r, svc: svc, auth: auth}
+}
+
+func (ac *UserAttributeController) RegisterRoutes() {
+	ac.r.Group(func(r chi.Router) {
+		r.Use(ac.auth)
+
+		r.With(pkghttp.RequireAccess(model.ScopeUsersRead, model.RoleAdmin, model.RoleAuditor)).
+			Get("/admin/user-attributes", ac.list)
+
+		// the schema is shared by every organization, so it is managed by the
+		// human admins of the default one only
+		r.Group(func(r chi.Router) {
+			r.Use(pkghttp.RequireRole(model.RoleAdmin), pkghttp.RequireOrg(model.DefaultOrgID))
+			r.Put("/admin/user-attributes/{name}", ac.save)
+			r.With(pkghttp.DenyImpersonation).Delete("/admin/user-attributes/{name}", ac.delete)
+		})
+	})
+}
+
+func (ac *UserAttributeController) list(w http.ResponseWriter, r *http.Request) {
+	defs, err := ac.svc.List(r.Context())
+	if err != nil {
+		pkghttp.JSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ListUserAttributesResponse{}
+	res.Bind(defs)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
+
+func (ac *UserAttributeController) save(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	var input SaveUserAttributeInput
+	if err := input.Bind(r); err != nil {
+		pkghttp.JSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	d, err := ac.svc.Save(r.Context(), actor, model.AttributeDefinition{
+		Name:      chi.URLParam(r, "name"),
+		Type:      input.Type,
+		Required:  input.Required,
+		Enum:      input.Enum,
+		MaxLength: input.MaxLength,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusOK, newUserAttributeResponse(d))
+}
+
+func (ac *UserAttributeController) delete(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	err := ac.svc.Delete(r.Context(), actor, chi.URLParam(r, "name"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pkghttp.JSON(w, http.StatusNoContent, "")
+}
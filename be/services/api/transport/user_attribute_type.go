@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"be/pkg/model"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type UserAttributeResponse struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Required  bool      `json:"required"`
+	Enum      []string  `json:"enum"`
+	MaxLength int       `json:"max_length"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newUserAttributeResponse(d *model.AttributeDefinition) UserAttributeResponse {
+	return UserAttributeResponse{
+		Name:      d.Name,
+		Type:      d.Type,
+		Required:  d.Required,
+		Enum:      d.Enum,
+		MaxLength: d.MaxLength,
+		UpdatedAt: d.UpdatedAt,
+		CreatedAt: d.CreatedAt,
+	}
+}
+
+type ListUserAttributesResponse struct {
+	Attributes []UserAttributeResponse `json:"attributes"`
+}
+
+func (res *ListUserAttributesResponse) Bind(defs []model.AttributeDefinition) {
+	res.Attributes = make([]UserAttributeResponse, 0, len(defs))
+	for i := range defs {
+		res.Attributes = append(res.Attributes, newUserAttributeResponse(&defs[i]))
+	}
+}
+
+// SaveUserAttributeInput is the definition of the attribute named in the path.
+type SaveUserAttributeInput struct {
+	Type      string   `json:"type"`
+	Required  bool     `json:"required"`
+	Enum      []string `json:"enum"`
+	MaxLength int      `json:"max_length"`
+}
+
+func (req *SaveUserAttributeInput) Bind(r *http.Request) error {
+	return json.NewDecoder(r.Body).Decode(req)
+}
@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"api/service"
+	"io"
+	"net/http"
+
+	"be/pkg/errors"
+	pkghttp "be/pkg/http"
+	"be/pkg/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxImportSize bounds the uploaded file, a few tens of thousands of rows.
+const maxImportSize = 10 << 20
+
+type UserImportController struct {
+	r    chi.Router
+	svc  service.UserImportService
+	auth func(http.Handler) http.Handler
+}
+
+func NewUserImportController(r chi.Router, svc service.UserImportService, auth func(http.Handler) http.Handler) *UserImportController {
+	return &UserImportController{r: r, svc: svc, auth: auth}
+}
+
+func (ic *UserImportController) RegisterRoutes() {
+	ic.r.Group(func(r chi.Router) {
+		r.Use(ic.auth)
+		// rows may carry roles, and granting roles stays with human admins
+		r.With(pkghttp.RequireRole(model.RoleAdmin), pkghttp.DenyImpersonation).
+			Post("/admin/users/import", ic.start)
+		r.With(pkghttp.RequireAccess(model.ScopeUsersRead, model.RoleAdmin, model.RoleAuditor)).
+			Get("/admin/users/import/{id}", ic.get)
+	})
+}
+
+func (ic *UserImportController) start(w http.ResponseWriter, r *http.Request) {
+	actor, ok := pkghttp.GetActor(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		pkghttp.JSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	j, err := ic.svc.Start(r.Context(), actor, importFormat(r), data)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsInvalid(err) {
+			status = http.StatusBadRequest
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ImportJobResponse{}
+	res.Bind(j, nil)
+	pkghttp.JSON(w, http.StatusAccepted, res)
+}
+
+func (ic *UserImportController) get(w http.ResponseWriter, r *http.Request) {
+	j, rowErrors, err := ic.svc.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		pkghttp.JSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	res := ImportJobResponse{}
+	res.Bind(j, rowErrors)
+	pkghttp.JSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"be/pkg/model"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// importFormat picks the format of an uploaded import from the "format" query
+// parameter, or else the Content-Type.
+func importFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return model.ImportCSV
+	case "application/x-ndjson", "application/ndjson":
+		return model.ImportNDJSON
+	}
+	return mediaType
+}
+
+type ImportRowErrorResponse struct {
+	Row     int    `json:"row"`
+	Email   string `json:"email"`
+	Message string `json:"message"`
+}
+
+type ImportJobResponse struct {
+	ID            string                   `json:"id"`
+	Format        string                   `json:"format"`
+	Status        string                   `json:"status"`
+	TotalRows     int                      `json:"total_rows"`
+	ProcessedRows int                      `json:"processed_rows"`
+	CreatedRows   int                      `json:"created_rows"`
+	FailedRows    int                      `json:"failed_rows"`
+	Error         string                   `json:"error,omitempty"`
+	Errors        []ImportRowErrorResponse `json:"errors"`
+	CreatedAt     time.Time                `json:"created_at"`
+	StartedAt     *time.Time               `json:"started_at"`
+	CompletedAt   *time.Time               `json:"completed_at"`
+}
+
+func (res *ImportJobResponse) Bind(j *model.ImportJob, rowErrors []model.ImportRowError) {
+	*res = ImportJobResponse{
+		ID:            j.ID,
+		Format:        j.Format,
+		Status:        j.Status,
+		TotalRows:     j.TotalRows,
+		ProcessedRows: j.ProcessedRows,
+		CreatedRows:   j.CreatedRows,
+		FailedRows:    j.FailedRows,
+		Error:         j.Error.String,
+		Errors:        make([]ImportRowErrorResponse, 0, len(rowErrors)),
+		CreatedAt:     j.CreatedAt,
+	}
+	for _, e := range rowErrors {
+		res.Errors = append(res.Errors, ImportRowErrorResponse(e))
+	}
+	if j.StartedAt.Valid {
+		res.StartedAt = &j.StartedAt.Time
+	}
+	if j.CompletedAt.Valid {
+		res.CompletedAt = &j.CompletedAt.Time
+	}
+}
@@ -1,15 +1,50 @@
 package transport
 
 import (
+	"api/service"
+	"be/pkg/model"
 	"encoding/json"
 	"errors"
+	"maps"
 	"net/http"
 	"net/mail"
+	"slices"
+	"strings"
+	"time"
 )
 
+// UserResponse is the user record as shown to the user themselves. Admin
+// endpoints extend it in AdminUserResponse.
+type UserResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+
+	EmailVerified   bool       `json:"email_verified"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+}
+
+func newUserResponse(u *model.User) UserResponse {
+	res := UserResponse{
+		ID:            u.ID,
+		Email:         u.Email,
+		Name:          u.Name.String,
+		CreatedAt:     u.CreatedAt,
+		EmailVerified: u.EmailVerified(),
+	}
+	if u.EmailVerified() {
+		res.EmailVerifiedAt = &u.EmailVerifiedAt.Time
+	}
+	return res
+}
+
 type SignUpInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// InvitationToken comes from the invitation link, required when sign-up
+	// is by invitation only.
+	InvitationToken string `json:"invitation_token"`
 }
 
 func (req *SignUpInput) Bind(r *http.Request) error {
@@ -17,7 +52,8 @@ func (req *SignUpInput) Bind(r *http.Request) error {
 		return err
 	}
 
-	if _, err := mail.ParseAddress(req.Email); err != nil {
+	// the bulk import applies the same rule to each row
+	if !model.IsValidEmail(req.Email) {
 		return errors.New("invalid email format")
 	}
 
@@ -46,9 +82,237 @@ func (req *SignInInput) Bind(r *http.Request) error {
 }
 
 type SignInResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	OrgID        string `json:"org_id,omitempty"`
+
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+func (res *SignInResponse) Bind(tokens *service.Tokens) {
+	res.Token = tokens.AccessToken
+	res.RefreshToken = tokens.RefreshToken
+	res.ExpiresIn = tokens.ExpiresIn
+	res.OrgID = tokens.OrgID
+	res.MFARequired = tokens.MFAToken != ""
+	res.MFAToken = tokens.MFAToken
+}
+
+type SignInMFAInput struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+func (req *SignInMFAInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.MFAToken) == 0 {
+		return errors.New("missing mfa token")
+	}
+
+	if len(req.Code) == 0 {
+		return errors.New("missing code")
+	}
+
+	return nil
+}
+
+type TOTPCodeInput struct {
+	Code string `json:"code"`
+}
+
+func (req *TOTPCodeInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Code) == 0 {
+		return errors.New("missing code")
+	}
+
+	return nil
+}
+
+type TOTPEnrollResponse struct {
+	Secret        string   `json:"secret"`
+	OTPAuthURI    string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (res *TOTPEnrollResponse) Bind(e *service.TOTPEnrollment) {
+	res.Secret = e.Secret
+	res.OTPAuthURI = e.URI
+	res.RecoveryCodes = e.RecoveryCodes
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (req *RefreshTokenInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.RefreshToken) == 0 {
+		return errors.New("missing refresh token")
+	}
+
+	return nil
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email"`
+}
+
+func (req *ForgotPasswordInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return errors.New("invalid email format")
+	}
+
+	return nil
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (req *ResetPasswordInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.Token) == 0 {
+		return errors.New("missing token")
+	}
+
+	if len(req.Password) == 0 {
+		return errors.New("missing password")
+	}
+
+	return nil
+}
+
+type VerifyEmailResponse struct {
+	UserID string `json:"user_id"`
+}
+
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+type OIDCLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OIDCCallbackInput carries the query parameters the provider redirects back
+// with.
+type OIDCCallbackInput struct {
+	Code  string
+	State string
+}
+
+func (req *OIDCCallbackInput) Bind(r *http.Request) error {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		return errors.New("authorization failed: " + e)
+	}
+
+	req.Code = q.Get("code")
+	req.State = q.Get("state")
+	if len(req.Code) == 0 {
+		return errors.New("missing code")
+	}
+
+	if len(req.State) == 0 {
+		return errors.New("missing state")
+	}
+
+	return nil
+}
+
+type UpdateProfileInput struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+func (req *UpdateProfileInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if req.Email != nil {
+		if !model.IsValidEmail(*req.Email) {
+			return errors.New("invalid email format")
+		}
+	}
+
+	return nil
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (req *ChangePasswordInput) Bind(r *http.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+
+	if len(req.CurrentPassword) == 0 {
+		return errors.New("missing current password")
+	}
+
+	if len(req.NewPassword) == 0 {
+		return errors.New("missing new password")
+	}
+
+	return nil
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Fields says what is wrong with each invalid field of the body.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// fieldErrors maps the invalid fields of a request body to what is wrong
+// with them.
+type fieldErrors map[string]string
+
+func (e fieldErrors) Error() string {
+	fields := slices.Sorted(maps.Keys(e))
+	msgs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		msgs = append(msgs, f+": "+e[f])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// newErrorResponse includes the invalid fields when err has them. Invalid
+// attributes are reported as attributes.<name>.
+func newErrorResponse(err error) ErrorResponse {
+	res := ErrorResponse{Error: err.Error()}
+	var fe fieldErrors
+	if errors.As(err, &fe) {
+		res.Fields = fe
+	}
+	var ae model.AttributeErrors
+	if errors.As(err, &ae) {
+		res.Fields = map[string]string{}
+		for name, msg := range ae {
+			res.Fields["attributes."+name] = msg
+		}
+	}
+	return res
 }
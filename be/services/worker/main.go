@@ -2,21 +2,38 @@ package main
 
 import (
 	"be/pkg/config"
+	"be/pkg/events"
 	"be/pkg/log"
+	"be/pkg/mail"
 	"be/pkg/transport/sqs"
 	"context"
+	"time"
 	"worker/service"
 	"worker/store"
 	"worker/transport"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type envConfig struct {
 	Stage string `mapstructure:"STAGE"`
 	Port  int    `mapstructure:"PORT"`
 
+	PgApiConnURI string `mapstructure:"PG_API_CONN_URI"`
+
+	AppURL    string `mapstructure:"APP_URL"`
+	InviteTTL string `mapstructure:"INVITE_TTL"`
+
+	MailDriver   string `mapstructure:"MAIL_DRIVER"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	MailDir      string `mapstructure:"MAIL_DIR"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+
 	AwsRegion           string `mapstructure:"AWS_REGION"`
 	AWSEndpoint         string `mapstructure:"AWS_ENDPOINT"`
 	AwsAccessKey        string `mapstructure:"AWS_ACCESS_KEY"`
@@ -53,8 +70,43 @@ func main() {
 
 	userLoggersHandler := transport.NewHandler(svc)
 
+	pgConfig, err := pgxpool.ParseConfig(env.PgApiConnURI)
+	if err != nil {
+		panic(err)
+	}
+	store.ScopeToTenant(pgConfig)
+	pgPool, err := pgxpool.NewWithConfig(ctx, pgConfig)
+	if err != nil {
+		panic(err)
+	}
+	defer pgPool.Close()
+
+	mailer, err := mail.New(mail.Config{
+		Driver:       env.MailDriver,
+		From:         env.MailFrom,
+		Dir:          env.MailDir,
+		SMTPHost:     env.SMTPHost,
+		SMTPPort:     env.SMTPPort,
+		SMTPUsername: env.SMTPUsername,
+		SMTPPassword: env.SMTPPassword,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	userRepo := store.NewUserRepo(pgPool)
+	privacySvc := service.NewPrivacyService(userRepo, r, store.NewDataExportRepo(pgPool))
+	importSvc := service.NewUserImportService(
+		store.NewImportJobRepo(pgPool), store.NewUserAttributeRepo(pgPool), r, mailer,
+		service.UserImportConfig{AppURL: env.AppURL, InviteTTL: parseDuration(env.InviteTTL, 7*24*time.Hour)},
+	)
+
 	router := sqs.NewSQSRouter(sqs.RouteFromAttributeFn)
-	router.AddHandler("userloggers", userLoggersHandler)
+	router.AddHandler(events.RouteUserLogs, userLoggersHandler)
+	router.AddHandler(events.RouteDataExports, transport.NewDataExportHandler(privacySvc))
+	router.AddHandler(events.RouteUserPurges, transport.NewUserPurgeHandler(privacySvc))
+	router.AddHandler(events.RouteUserImports, transport.NewUserImportHandler(importSvc))
+	router.AddHandler(events.RouteAdminExports, transport.NewAdminExportHandler(service.NewAdminExportService(userRepo, r, store.NewAdminExportRepo(pgPool))))
 
 	sqsAWSConfig, err := awsconfig.LoadDefaultConfig(ctx,
 		awsconfig.WithRegion(env.AwsRegion),
@@ -78,3 +130,15 @@ func main() {
 		panic(err)
 	}
 }
+
+func parseDuration(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
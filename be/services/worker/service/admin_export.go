@@ -0,0 +1,81 @@
+package service
+
+import (
+	"be/pkg/errors"
+	"be/pkg/events"
+	"be/pkg/export"
+	"be/pkg/model"
+	"be/pkg/tenant"
+	"bytes"
+	"context"
+	"worker/store"
+)
+
+type AdminExportService interface {
+	// Build writes a background admin export and stores the file on it.
+	Build(ctx context.Context, ev events.AdminExportRequested) error
+}
+
+type adminExportService struct {
+	users   store.UserRepository
+	logs    store.LogRepository
+	exports store.AdminExportRepository
+}
+
+func NewAdminExportService(u store.UserRepository, l store.LogRepository, e store.AdminExportRepository) AdminExportService {
+	return &adminExportService{users: u, logs: l, exports: e}
+}
+
+func (s *adminExportService) Build(ctx context.Context, ev events.AdminExportRequested) error {
+	e, err := s.exports.Find(ctx, ev.ExportID)
+	if errors.IsNotFound(err) {
+		// expired and dropped before the worker got to it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if e.Status != model.DataExportPending {
+		return nil
+	}
+
+	// the users and logs exported are those of the export's organization
+	ctx = tenant.WithOrg(ctx, e.OrgID)
+
+	var buf bytes.Buffer
+	switch e.Kind {
+	case model.AdminExportUsers:
+		err = writeExport(&buf, e.Format, func(write func(export.UserRecord) error) error {
+			return s.users.Stream(ctx, e.Filter, func(u *model.User) error {
+				return write(export.NewUserRecord(u))
+			})
+		})
+	case model.AdminExportUserLogs:
+		err = writeExport(&buf, e.Format, func(write func(export.UserLogRecord) error) error {
+			return s.logs.Stream(ctx, func(l *model.UserLogs) error {
+				return write(export.NewUserLogRecord(l))
+			})
+		})
+	default:
+		err = errors.Errorf("Unknown export kind %q", e.Kind)
+	}
+	if err != nil {
+		if failErr := s.exports.Fail(ctx, e.ID, err.Error()); failErr != nil {
+			return failErr
+		}
+		return err
+	}
+
+	return s.exports.Complete(ctx, e.ID, buf.Bytes())
+}
+
+func writeExport[T export.Record](buf *bytes.Buffer, format string, stream func(write func(T) error) error) error {
+	w, err := export.NewWriter[T](buf, format)
+	if err != nil {
+		return err
+	}
+	if err := stream(w.Write); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -21,9 +21,12 @@ func NewLogService(r store.LogRepository) LogService {
 
 func (s *logService) Write(ctx context.Context, ev events.UserLogsEvent) error {
 	return s.logRepo.Write(ctx, model.UserLogs{
-		UserID:    ev.UserID,
-		EventType: ev.EventType,
-		Details:   ev.Details,
-		CreatedAt: ev.EventTime,
+		UserID:       ev.UserID,
+		EventType:    ev.EventType,
+		Details:      ev.Details,
+		Actor:        ev.Actor,
+		Impersonator: ev.Impersonator,
+		OrgID:        ev.OrgID,
+		CreatedAt:    ev.EventTime,
 	})
 }
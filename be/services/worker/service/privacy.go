@@ -0,0 +1,164 @@
+package service
+
+import (
+	"archive/zip"
+	"be/pkg/errors"
+	"be/pkg/events"
+	"be/pkg/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+	"worker/store"
+)
+
+type PrivacyService interface {
+	// Export builds the personal data archive of a user and stores it on the
+	// export.
+	Export(ctx context.Context, ev events.DataExportRequested) error
+	// Purge anonymises the logs of a deleted user, keeping the record of the
+	// deletion itself.
+	Purge(ctx context.Context, ev events.UserPurgeRequested) error
+}
+
+type privacyService struct {
+	users   store.UserRepository
+	logs    store.LogRepository
+	exports store.DataExportRepository
+}
+
+func NewPrivacyService(u store.UserRepository, l store.LogRepository, e store.DataExportRepository) PrivacyService {
+	return &privacyService{users: u, logs: l, exports: e}
+}
+
+// exportedUser is the users row as written to the archive. The password hash
+// is left out.
+type exportedUser struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	// Organizations replaces the role the users row held before roles
+	// became per organization.
+	Organizations []exportedMembership `json:"organizations"`
+}
+
+type exportedMembership struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"joined_at"`
+}
+
+type exportedLog struct {
+	EventType    string    `json:"event_type"`
+	Details      string    `json:"details"`
+	Actor        string    `json:"actor,omitempty"`
+	Impersonator string    `json:"impersonator,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (s *privacyService) Export(ctx context.Context, ev events.DataExportRequested) error {
+	u, err := s.users.FindByID(ctx, ev.UserID)
+	if errors.IsNotFound(err) {
+		// the account was deleted meanwhile, along with its exports
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	memberships, err := s.users.ListMemberships(ctx, ev.UserID)
+	if err != nil {
+		return err
+	}
+
+	logs, err := s.logs.ListByUser(ctx, ev.UserID)
+	if err != nil {
+		return err
+	}
+
+	archive, err := buildArchive(u, memberships, logs)
+	if err != nil {
+		if failErr := s.exports.Fail(ctx, ev.ExportID, err.Error()); failErr != nil {
+			return failErr
+		}
+		return err
+	}
+
+	return s.exports.Complete(ctx, ev.ExportID, archive)
+}
+
+func buildArchive(u *model.User, memberships []model.Membership, logs []model.UserLogs) ([]byte, error) {
+	user := exportedUser{
+		ID:            u.ID,
+		Email:         u.Email,
+		Name:          u.Name.String,
+		CreatedAt:     u.CreatedAt,
+		Organizations: make([]exportedMembership, 0, len(memberships)),
+	}
+	for _, m := range memberships {
+		user.Organizations = append(user.Organizations, exportedMembership{
+			ID:        m.OrgID,
+			Name:      m.OrgName,
+			Role:      m.Role,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	if u.EmailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &u.EmailVerifiedAt.Time
+	}
+
+	entries := make([]exportedLog, 0, len(logs))
+	for _, l := range logs {
+		entries = append(entries, exportedLog{
+			EventType:    l.EventType,
+			Details:      l.Details,
+			Actor:        l.Actor,
+			Impersonator: l.Impersonator,
+			CreatedAt:    l.CreatedAt,
+		})
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]any{"user.json": user, "logs.json": entries}
+	// in a fixed order, so the same data always makes the same archive
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(files[name]); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *privacyService) Purge(ctx context.Context, ev events.UserPurgeRequested) error {
+	logs, err := s.logs.ListByUser(ctx, ev.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range logs {
+		if l.EventType == events.AccountDeletedEventType {
+			continue
+		}
+		if err := s.logs.Anonymise(ctx, &l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"be/pkg/errors"
+	"be/pkg/events"
+	"be/pkg/mail"
+	"be/pkg/model"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"worker/store"
+)
+
+type UserImportService interface {
+	// Import creates the users of an import job and mails each an invite. A
+	// job redelivered after a crash resumes after the last recorded row; a
+	// user is created in the same transaction as their row is recorded.
+	Import(ctx context.Context, ev events.UserImportRequested) error
+}
+
+type UserImportConfig struct {
+	// AppURL is the frontend base URL used to build invite links.
+	AppURL string
+	// InviteTTL is how long the invite link stays valid.
+	InviteTTL time.Duration
+}
+
+type userImportService struct {
+	jobs       store.ImportJobRepository
+	attributes store.UserAttributeRepository
+	logs       store.LogRepository
+	mailer     mail.Mailer
+	cfg        UserImportConfig
+}
+
+func NewUserImportService(
+	j store.ImportJobRepository, a store.UserAttributeRepository, l store.LogRepository, m mail.Mailer, cfg UserImportConfig,
+) UserImportService {
+	return &userImportService{jobs: j, attributes: a, logs: l, mailer: m, cfg: cfg}
+}
+
+// importRow is a row of an import file. err is set when the row could not be
+// read at all.
+type importRow struct {
+	Email      string           `json:"email"`
+	Name       string           `json:"name"`
+	Role       string           `json:"role"`
+	Attributes model.Attributes `json:"attributes"`
+	// csvAttributes are the attr.<name> cells of a CSV row, read as the
+	// types of their attributes once the schema is known.
+	csvAttributes map[string]string
+	err           string
+}
+
+func (s *userImportService) Import(ctx context.Context, ev events.UserImportRequested) error {
+	j, err := s.jobs.Find(ctx, ev.JobID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if j.Status == model.ImportCompleted || j.Status == model.ImportFailed {
+		return nil
+	}
+
+	rows, err := parseImport(j.Format, j.Data)
+	if err != nil {
+		return s.jobs.Fail(ctx, j.ID, err.Error())
+	}
+	if err := s.jobs.Start(ctx, j.ID, len(rows)); err != nil {
+		return err
+	}
+	schema, err := s.attributes.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := j.ProcessedRows; i < len(rows); i++ {
+		msg, err := s.importRow(ctx, j, schema, rows[i])
+		if err != nil {
+			return err
+		}
+		// created rows are recorded along with their user
+		if msg == "" {
+			continue
+		}
+
+		rowErr := &model.ImportRowError{Row: i + 1, Email: rows[i].Email, Message: msg}
+		if err := s.jobs.RecordRow(ctx, j.ID, rowErr); err != nil {
+			return err
+		}
+	}
+
+	return s.jobs.Complete(ctx, j.ID)
+}
+
+// importRow creates the user of a row. A row that cannot be created returns
+// the reason; the error is kept for failures worth retrying the job for.
+func (s *userImportService) importRow(ctx context.Context, j *model.ImportJob, schema []model.AttributeDefinition, row importRow) (string, error) {
+	if row.err != "" {
+		return row.err, nil
+	}
+	if msg := validateImportRow(schema, &row); msg != "" {
+		return msg, nil
+	}
+
+	token, hash, err := newInviteToken()
+	if err != nil {
+		return "", err
+	}
+	id, err := s.jobs.CreateUser(ctx, j, store.ImportedUser{
+		Email:           row.Email,
+		Name:            row.Name,
+		Role:            row.Role,
+		Attributes:      row.Attributes,
+		InviteHash:      hash,
+		InviteExpiresAt: time.Now().UTC().Add(s.cfg.InviteTTL),
+	}, func(string) error { return s.invite(ctx, row.Email, token) })
+	if errors.IsInvalid(err) {
+		return err.Error(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	details := fmt.Sprintf("New user: id=%s email=%s import=%s", id, row.Email, j.ID)
+	if len(row.Attributes) > 0 {
+		b, _ := json.Marshal(row.Attributes)
+		details += " attributes=" + string(b)
+	}
+	return "", s.logs.Write(ctx, model.UserLogs{
+		UserID:    id,
+		EventType: "users.signUp",
+		Details:   details,
+		Actor:     j.CreatedBy,
+		OrgID:     j.OrgID,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// validateImportRow applies the sign-up rules to a row, defaults its role and
+// checks its attributes against the schema.
+func validateImportRow(schema []model.AttributeDefinition, row *importRow) string {
+	if !model.IsValidEmail(row.Email) {
+		return "invalid email format"
+	}
+	if len(row.Name) > model.MaxNameLength {
+		return fmt.Sprintf("name longer than %d characters", model.MaxNameLength)
+	}
+	if row.Role == "" {
+		row.Role = model.RoleUser
+	}
+	if !model.IsValidRole(row.Role) {
+		return fmt.Sprintf("invalid role %q", row.Role)
+	}
+
+	if row.csvAttributes != nil {
+		row.Attributes = model.Attributes{}
+		invalid := model.AttributeErrors{}
+		for name, text := range row.csvAttributes {
+			i := slices.IndexFunc(schema, func(d model.AttributeDefinition) bool { return d.Name == name })
+			if i < 0 {
+				invalid[name] = "unknown attribute"
+				continue
+			}
+			v, err := schema[i].ParseValue(text)
+			if err != nil {
+				invalid[name] = err.Error()
+				continue
+			}
+			row.Attributes[name] = v
+		}
+		if len(invalid) > 0 {
+			return invalid.Error()
+		}
+	}
+	if err := model.CheckAttributes(schema, row.Attributes); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// newInviteToken returns a random invite token and its hash. It is a password
+// reset token, so the API needs nothing new to redeem it.
+func newInviteToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	sum := sha256.Sum256([]byte(token))
+	return token, hex.EncodeToString(sum[:]), nil
+}
+
+// invite mails the user the link to choose their password with token.
+func (s *userImportService) invite(ctx context.Context, email, token string) error {
+	return s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "You have been invited",
+		Body: fmt.Sprintf(
+			"An account has been created for you.\n\n"+
+				"Open the link below to choose your password. It expires in %s.\n\n%s/reset-password?token=%s",
+			s.cfg.InviteTTL, s.cfg.AppURL, token,
+		),
+	})
+}
+
+// parseImport reads the rows of an import file. A CSV file starts with a
+// header naming its columns; email is required, name and role are optional
+// and attr.<name> columns hold attributes. An empty attribute cell leaves
+// the attribute unset.
+func parseImport(format string, data []byte) ([]importRow, error) {
+	switch format {
+	case model.ImportCSV:
+		return parseImportCSV(data)
+	case model.ImportNDJSON:
+		return parseImportNDJSON(data)
+	default:
+		return nil, errors.Errorf("Unsupported import format %q", format)
+	}
+}
+
+func parseImportCSV(data []byte) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("Empty import file")
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("Missing email column")
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := []importRow{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		row := importRow{
+			Email:         field(record, "email"),
+			Name:          field(record, "name"),
+			Role:          field(record, "role"),
+			csvAttributes: map[string]string{},
+		}
+		for column := range columns {
+			name, ok := strings.CutPrefix(column, "attr.")
+			if v := field(record, column); ok && v != "" {
+				row.csvAttributes[name] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseImportNDJSON reads one JSON object per line, skipping blank lines. A
+// line that is not valid JSON fails on its own.
+func parseImportNDJSON(data []byte) ([]importRow, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	rows := []importRow{}
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		row := importRow{}
+		if err := json.Unmarshal(line, &row); err != nil {
+			row = importRow{err: "invalid JSON"}
+		}
+		row.Email, row.Name, row.Role = strings.TrimSpace(row.Email), strings.TrimSpace(row.Name), strings.TrimSpace(row.Role)
+		rows = append(rows, row)
+	}
+	return rows, errors.WithStack(sc.Err())
+}
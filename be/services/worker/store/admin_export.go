@@ -0,0 +1,57 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AdminExportRepository interface {
+	Find(ctx context.Context, id string) (*model.AdminExport, error)
+	// Complete and Fail only touch pending exports.
+	Complete(ctx context.Context, id string, file []byte) error
+	Fail(ctx context.Context, id, reason string) error
+}
+
+type adminExportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAdminExportRepo(pool *pgxpool.Pool) AdminExportRepository {
+	return &adminExportRepo{db: pool}
+}
+
+func (r *adminExportRepo) Find(ctx context.Context, id string) (*model.AdminExport, error) {
+	var e model.AdminExport
+	err := r.db.QueryRow(ctx, `
+        SELECT id, kind, format, status, created_by, org_id, filter, created_at
+        FROM admin_exports
+        WHERE id = $1`, id).
+		Scan(&e.ID, &e.Kind, &e.Format, &e.Status, &e.CreatedBy, &e.OrgID, &e.Filter, &e.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Export not found"), "")
+	}
+	return &e, errors.WithStack(err)
+}
+
+func (r *adminExportRepo) Complete(ctx context.Context, id string, file []byte) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE admin_exports
+        SET status = $1, file = $2, completed_at = $3
+        WHERE id = $4 AND status = $5`,
+		model.DataExportReady, file, time.Now().UTC(), id, model.DataExportPending)
+	return errors.WithStack(err)
+}
+
+func (r *adminExportRepo) Fail(ctx context.Context, id, reason string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE admin_exports
+        SET status = $1, error = $2, completed_at = $3
+        WHERE id = $4 AND status = $5`,
+		model.DataExportFailed, reason, time.Now().UTC(), id, model.DataExportPending)
+	return errors.WithStack(err)
+}
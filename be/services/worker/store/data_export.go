@@ -0,0 +1,43 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DataExportRepository interface {
+	// Complete and Fail only touch pending exports; one the API has replaced
+	// meanwhile is left alone.
+	Complete(ctx context.Context, id string, archive []byte) error
+	Fail(ctx context.Context, id, reason string) error
+}
+
+type dataExportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDataExportRepo(pool *pgxpool.Pool) DataExportRepository {
+	return &dataExportRepo{db: pool}
+}
+
+func (r *dataExportRepo) Complete(ctx context.Context, id string, archive []byte) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE data_exports
+        SET status = $1, archive = $2, completed_at = $3
+        WHERE id = $4 AND status = $5`,
+		model.DataExportReady, archive, time.Now().UTC(), id, model.DataExportPending)
+	return errors.WithStack(err)
+}
+
+func (r *dataExportRepo) Fail(ctx context.Context, id, reason string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE data_exports
+        SET status = $1, error = $2, completed_at = $3
+        WHERE id = $4 AND status = $5`,
+		model.DataExportFailed, reason, time.Now().UTC(), id, model.DataExportPending)
+	return errors.WithStack(err)
+}
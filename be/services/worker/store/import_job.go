@@ -0,0 +1,178 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ImportJobRepository interface {
+	// Find returns the job with its data.
+	Find(ctx context.Context, id string) (*model.ImportJob, error)
+	// Start marks the job running with the number of rows to process. It is
+	// called again when a job is resumed.
+	Start(ctx context.Context, id string, totalRows int) error
+	// RecordRow advances the progress by one row, storing rowErr when the row
+	// failed.
+	RecordRow(ctx context.Context, id string, rowErr *model.ImportRowError) error
+	// CreateUser creates the user of the next row in the organization of the
+	// job, with their invite token, and advances the progress by one created
+	// row, in one transaction. invite is called with the new id before it
+	// commits, so a crash either leaves the row to be imported again or has
+	// it imported and the user invited. A taken email is returned as invalid,
+	// with nothing written.
+	CreateUser(ctx context.Context, j *model.ImportJob, u ImportedUser, invite func(userID string) error) (string, error)
+	Complete(ctx context.Context, id string) error
+	Fail(ctx context.Context, id, reason string) error
+}
+
+// ImportedUser is the user of an import row, without a usable password: they
+// set one through the invite, a password reset token.
+type ImportedUser struct {
+	Email      string
+	Name       string
+	Role       string
+	Attributes model.Attributes
+	// InviteHash is the hash of the invite token, which expires at
+	// InviteExpiresAt.
+	InviteHash      string
+	InviteExpiresAt time.Time
+}
+
+// unusablePassword never matches a bcrypt comparison.
+const unusablePassword = "!"
+
+type importJobRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewImportJobRepo(pool *pgxpool.Pool) ImportJobRepository {
+	return &importJobRepo{db: pool}
+}
+
+func (r *importJobRepo) Find(ctx context.Context, id string) (*model.ImportJob, error) {
+	var j model.ImportJob
+	err := r.db.QueryRow(ctx, `
+        SELECT id, format, status, created_by, org_id, data, total_rows, processed_rows, created_rows, failed_rows, created_at
+        FROM import_jobs
+        WHERE id = $1`, id).
+		Scan(&j.ID, &j.Format, &j.Status, &j.CreatedBy, &j.OrgID, &j.Data, &j.TotalRows, &j.ProcessedRows, &j.CreatedRows, &j.FailedRows, &j.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("Import job not found"), "")
+	}
+	return &j, errors.WithStack(err)
+}
+
+func (r *importJobRepo) Start(ctx context.Context, id string, totalRows int) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE import_jobs
+        SET status = $1, total_rows = $2, started_at = COALESCE(started_at, $3)
+        WHERE id = $4`,
+		model.ImportRunning, totalRows, time.Now().UTC(), id)
+	return errors.WithStack(err)
+}
+
+func (r *importJobRepo) RecordRow(ctx context.Context, id string, rowErr *model.ImportRowError) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	created, failed := 1, 0
+	if rowErr != nil {
+		created, failed = 0, 1
+		_, err = tx.Exec(ctx, `
+            INSERT INTO import_job_errors (job_id,row_number,email,message)
+            VALUES ($1,$2,$3,$4)
+            ON CONFLICT (job_id, row_number) DO NOTHING`,
+			id, rowErr.Row, rowErr.Email, rowErr.Message)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE import_jobs
+        SET processed_rows = processed_rows + 1, created_rows = created_rows + $1, failed_rows = failed_rows + $2
+        WHERE id = $3`,
+		created, failed, id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *importJobRepo) CreateUser(ctx context.Context, j *model.ImportJob, u ImportedUser, invite func(userID string) error) (string, error) {
+	if u.Attributes == nil {
+		u.Attributes = model.Attributes{}
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	id := uuid.NewString()
+	now := time.Now().UTC()
+	_, err = tx.Exec(ctx, `
+        WITH u AS (
+            INSERT INTO users (id,email,password,name,attributes,created_at) VALUES ($1,$2,$3,$4,$5,$6)
+        )
+        INSERT INTO organization_members (org_id,user_id,role,created_at) VALUES ($7,$1,$8,$6)`,
+		id, u.Email, unusablePassword, u.Name, u.Attributes, now, j.OrgID, u.Role,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return "", errors.WithInvalid(errors.New("Email existed"), "")
+	}
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO user_tokens (token_hash,kind,user_id,expires_at,created_at) VALUES ($1,$2,$3,$4,$5)`,
+		u.InviteHash, model.TokenPasswordReset, id, u.InviteExpiresAt, now,
+	)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE import_jobs
+        SET processed_rows = processed_rows + 1, created_rows = created_rows + 1
+        WHERE id = $1`, j.ID)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if err := invite(id); err != nil {
+		return "", err
+	}
+	return id, errors.WithStack(tx.Commit(ctx))
+}
+
+func (r *importJobRepo) Complete(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE import_jobs
+        SET status = $1, data = ''::bytea, completed_at = $2
+        WHERE id = $3`,
+		model.ImportCompleted, time.Now().UTC(), id)
+	return errors.WithStack(err)
+}
+
+func (r *importJobRepo) Fail(ctx context.Context, id, reason string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE import_jobs
+        SET status = $1, error = $2, data = ''::bytea, completed_at = $3
+        WHERE id = $4`,
+		model.ImportFailed, reason, time.Now().UTC(), id)
+	return errors.WithStack(err)
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"be/pkg/errors"
 	"be/pkg/model"
+	"be/pkg/tenant"
 	"context"
 	"time"
 
@@ -13,9 +14,22 @@ import (
 )
 
 type LogRepository interface {
+	// Write stores the entry in the partition of its organization.
 	Write(ctx context.Context, l model.UserLogs) error
+	// ListByUser returns every log entry of the user, in all organizations,
+	// oldest first.
+	ListByUser(ctx context.Context, userID string) ([]model.UserLogs, error)
+	// Stream calls fn with every log entry of the organization the context
+	// acts in, newest first, a page at a time.
+	Stream(ctx context.Context, fn func(*model.UserLogs) error) error
+	// Anonymise detaches the entry from its user and drops its details, along
+	// with who acted and impersonated in it.
+	Anonymise(ctx context.Context, l *model.UserLogs) error
 }
 
+// userIDIndex is the global secondary index keyed by user_id and SK.
+const userIDIndex = "user_id-index"
+
 type logRepo struct {
 	client *dynamodb.Client
 	table  string
@@ -35,16 +49,132 @@ func (r *logRepo) Write(ctx context.Context, l model.UserLogs) error {
 	}
 
 	item := map[string]ddbtypes.AttributeValue{
-		"PK":         &ddbtypes.AttributeValueMemberS{Value: "logs"},
+		"PK":         &ddbtypes.AttributeValueMemberS{Value: model.LogPartition(l.OrgID)},
 		"SK":         &ddbtypes.AttributeValueMemberS{Value: id.String()},
-		"user_id":    &ddbtypes.AttributeValueMemberS{Value: l.UserID},
 		"event_type": &ddbtypes.AttributeValueMemberS{Value: l.EventType},
 		"details":    &ddbtypes.AttributeValueMemberS{Value: l.Details},
 		"created_at": &ddbtypes.AttributeValueMemberS{Value: l.CreatedAt.Format(time.RFC3339Nano)},
 	}
+	// user_id keys the user index, which refuses empty values, so entries
+	// without a user, such as sign-in failures of unknown emails, leave it out
+	if l.UserID != "" {
+		item["user_id"] = &ddbtypes.AttributeValueMemberS{Value: l.UserID}
+	}
+	if l.Actor != "" {
+		item["actor"] = &ddbtypes.AttributeValueMemberS{Value: l.Actor}
+	}
+	if l.Impersonator != "" {
+		item["impersonator"] = &ddbtypes.AttributeValueMemberS{Value: l.Impersonator}
+	}
 	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName: &r.table,
 		Item:      item,
 	})
 	return errors.WithStack(err)
 }
+
+func (r *logRepo) ListByUser(ctx context.Context, userID string) ([]model.UserLogs, error) {
+	params := &dynamodb.QueryInput{
+		TableName:              &r.table,
+		IndexName:              aws.String(userIDIndex),
+		KeyConditionExpression: aws.String("user_id = :uid"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":uid": &ddbtypes.AttributeValueMemberS{Value: userID},
+		},
+	}
+
+	logs := []model.UserLogs{}
+	paginator := dynamodb.NewQueryPaginator(r.client, params)
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, it := range out.Items {
+			l, err := userLogOf(it)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, *l)
+		}
+	}
+
+	return logs, nil
+}
+
+func (r *logRepo) Stream(ctx context.Context, fn func(*model.UserLogs) error) error {
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              &r.table,
+		KeyConditionExpression: aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":pk": &ddbtypes.AttributeValueMemberS{Value: model.LogPartition(tenant.OrgID(ctx))},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, it := range out.Items {
+			l, err := userLogOf(it)
+			if err != nil {
+				return err
+			}
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func userLogOf(it map[string]ddbtypes.AttributeValue) (*model.UserLogs, error) {
+	id := stringAttr(it, "SK")
+	if id == "" {
+		return nil, errors.New("Log entry has no SK")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, stringAttr(it, "created_at"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "log entry %s", id)
+	}
+
+	// user_id is left out of entries without a user, see Write
+	return &model.UserLogs{
+		ID:           id,
+		UserID:       stringAttr(it, "user_id"),
+		EventType:    stringAttr(it, "event_type"),
+		Details:      stringAttr(it, "details"),
+		OrgID:        model.LogPartitionOrg(stringAttr(it, "PK")),
+		Actor:        stringAttr(it, "actor"),
+		Impersonator: stringAttr(it, "impersonator"),
+		CreatedAt:    createdAt,
+	}, nil
+}
+
+// stringAttr returns the string attribute of the item, empty when it is
+// missing or of another type.
+func stringAttr(it map[string]ddbtypes.AttributeValue, name string) string {
+	if v, ok := it[name].(*ddbtypes.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+func (r *logRepo) Anonymise(ctx context.Context, l *model.UserLogs) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.table,
+		Key: map[string]ddbtypes.AttributeValue{
+			"PK": &ddbtypes.AttributeValueMemberS{Value: model.LogPartition(l.OrgID)},
+			"SK": &ddbtypes.AttributeValueMemberS{Value: l.ID},
+		},
+		UpdateExpression: aws.String("SET user_id = :uid, details = :details REMOVE actor, impersonator"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":uid":     &ddbtypes.AttributeValueMemberS{Value: model.AnonymisedUserID},
+			":details": &ddbtypes.AttributeValueMemberS{Value: ""},
+		},
+	})
+	return errors.WithStack(err)
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserRepository reads the users of the organization the context acts in,
+// or every user outside of any. Role is the user's role in that organization.
+type UserRepository interface {
+	FindByID(ctx context.Context, id string) (*model.User, error)
+	// ListMemberships returns the organizations of the user, oldest first.
+	ListMemberships(ctx context.Context, id string) ([]model.Membership, error)
+	// Stream calls fn with every user matching the filter, in order, reading
+	// them through a server-side cursor.
+	Stream(ctx context.Context, f model.UserFilter, fn func(*model.User) error) error
+}
+
+type userRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserRepo(pool *pgxpool.Pool) UserRepository {
+	return &userRepo{db: pool}
+}
+
+func (r *userRepo) FindByID(ctx context.Context, id string) (*model.User, error) {
+	var u model.User
+	err := r.db.QueryRow(ctx, `
+        SELECT id, email, password, name, `+userRoleColumn+`, created_at, email_verified_at
+        FROM users
+        WHERE id = $1 AND `+userInTenant, id).
+		Scan(&u.ID, &u.Email, &u.Password, &u.Name, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.WithNotFound(errors.New("User not found"), "")
+	}
+	return &u, errors.WithStack(err)
+}
+
+func (r *userRepo) ListMemberships(ctx context.Context, id string) ([]model.Membership, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT m.org_id, o.name, m.user_id, m.role, m.created_at
+        FROM organization_members m JOIN organizations o ON o.id = m.org_id
+        WHERE m.user_id = $1
+        ORDER BY m.created_at, m.org_id`, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	memberships := []model.Membership{}
+	for rows.Next() {
+		var m model.Membership
+		if err := rows.Scan(&m.OrgID, &m.OrgName, &m.UserID, &m.Role, &m.CreatedAt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, errors.WithStack(rows.Err())
+}
+
+// userSortColumns and the filter below match the admin user list of the API.
+var userSortColumns = map[string]string{
+	"created_at": "created_at",
+	"email":      "email",
+	"name":       "COALESCE(name, '')",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// streamBatchSize is how many rows Stream fetches from its cursor at a time.
+const streamBatchSize = 500
+
+func (r *userRepo) Stream(ctx context.Context, f model.UserFilter, fn func(*model.User) error) error {
+	column, ok := userSortColumns[f.SortBy]
+	if !ok {
+		return errors.WithInvalid(errors.Errorf("Invalid sort %q", f.SortBy), "")
+	}
+	dir := "ASC"
+	if f.Desc {
+		dir = "DESC"
+	}
+
+	// DECLARE takes no bind parameters, so pgx inlines them
+	args := []any{pgx.QueryExecModeSimpleProtocol}
+	param := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args)-1)
+	}
+
+	conds := []string{userInTenant}
+	if f.Status != "" {
+		conds = append(conds, userStatus+" = "+param(f.Status))
+	} else {
+		conds = append(conds, userStatus+" <> "+param(model.StatusDeleted))
+	}
+	if f.Search != "" {
+		p := param("%" + likeEscaper.Replace(f.Search) + "%")
+		conds = append(conds, fmt.Sprintf("(email ILIKE %s OR name ILIKE %s)", p, p))
+	}
+	if !f.CreatedFrom.IsZero() {
+		conds = append(conds, "created_at >= "+param(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		conds = append(conds, "created_at < "+param(f.CreatedTo))
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+        DECLARE users_stream NO SCROLL CURSOR FOR
+        SELECT id, email, password, name, `+userRoleColumn+`, created_at, email_verified_at, `+userStatusColumns+`
+        FROM users
+        WHERE %s
+        ORDER BY %s %s, id %s`, strings.Join(conds, " AND "), column, dir, dir), args...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH %d FROM users_stream`, streamBatchSize))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		n := 0
+		for rows.Next() {
+			n++
+			var u model.User
+			err := rows.Scan(&u.ID, &u.Email, &u.Password, &u.Name, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt,
+				&u.Status, &u.SuspendedAt, &u.DeletedAt)
+			if err != nil {
+				rows.Close()
+				return errors.WithStack(err)
+			}
+			if err := fn(&u); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		if n < streamBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"be/pkg/errors"
+	"be/pkg/model"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserAttributeRepository reads the schema of the custom user attributes the
+// API manages.
+type UserAttributeRepository interface {
+	List(ctx context.Context) ([]model.AttributeDefinition, error)
+}
+
+type userAttributeRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewUserAttributeRepo(pool *pgxpool.Pool) UserAttributeRepository {
+	return &userAttributeRepo{db: pool}
+}
+
+func (r *userAttributeRepo) List(ctx context.Context) ([]model.AttributeDefinition, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT name, type, required, enum, max_length, updated_at, created_at
+        FROM user_attribute_definitions
+        ORDER BY name`)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	defs := []model.AttributeDefinition{}
+	for rows.Next() {
+		var d model.AttributeDefinition
+		if err := rows.Scan(&d.Name, &d.Type, &d.Required, &d.Enum, &d.MaxLength, &d.UpdatedAt, &d.CreatedAt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defs = append(defs, d)
+	}
+	return defs, errors.WithStack(rows.Err())
+}
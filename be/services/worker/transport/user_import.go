@@ -0,0 +1,24 @@
+package transport
+
+import (
+	"be/pkg/errors"
+	"be/pkg/events"
+	"be/pkg/transport/sqs"
+	"context"
+	"encoding/json"
+	"worker/service"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+func NewUserImportHandler(svc service.UserImportService) sqs.HandlerFunc {
+	return func(ctx context.Context, msg types.Message) error {
+		ev := events.UserImportRequested{}
+		if err := json.Unmarshal([]byte(aws.ToString(msg.Body)), &ev); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return svc.Import(ctx, ev)
+	}
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAPIKeys(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	res, err := api.Post("/admin/apikeys").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name":"reporting","scopes":["users:read"]}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		Send()
+	require.NoError(t, err)
+
+	var created createAPIKeyResponse
+	require.NoError(t, res.JSON(&created))
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, []string{"users:read"}, created.Scopes)
+
+	// both header forms are accepted
+	err = api.Get("/admin/users").
+		SetHeader("X-API-Key", created.Key).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/admin/users").
+		SetHeader("Authorization", "ApiKey "+created.Key).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	// scopes are enforced
+	err = api.Get("/admin/userlogs").
+		SetHeader("X-API-Key", created.Key).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	assert.NoError(t, err)
+
+	// keys cannot manage keys
+	err = api.Get("/admin/apikeys").
+		SetHeader("X-API-Key", created.Key).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	assert.NoError(t, err)
+
+	res, err = api.Get("/admin/apikeys").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var list struct {
+		APIKeys []createAPIKeyResponse `json:"api_keys"`
+	}
+	require.NoError(t, res.JSON(&list))
+	found := false
+	for _, k := range list.APIKeys {
+		if k.ID == created.ID {
+			found = true
+			assert.Empty(t, k.Key)
+			assert.NotNil(t, k.LastUsedAt)
+		}
+	}
+	assert.True(t, found)
+
+	err = api.Delete("/admin/apikeys").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s"}`, created.ID)).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/admin/users").
+		SetHeader("X-API-Key", created.Key).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+}
+
+func TestAdminAPIKeyActsOnUsers(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, _, _ := generateUser(t)
+
+	res, err := api.Post("/admin/apikeys").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name":"cleanup","scopes":["users:write"]}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		Send()
+	require.NoError(t, err)
+
+	var created createAPIKeyResponse
+	require.NoError(t, res.JSON(&created))
+
+	// granting roles is left to human admins
+	err = api.Put("/admin/users/role").
+		SetHeader("X-API-Key", created.Key).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s","role":"admin"}`, userID)).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Delete("/admin/users").
+		SetHeader("X-API-Key", created.Key).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s"}`, userID)).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+}
+
+func TestAdminAPIKeyExpiry(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	err := api.Post("/admin/apikeys").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"name":"expired","scopes":["users:read"],"expires_at":"%s"}`,
+			time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/admin/apikeys").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name":"bad","scopes":["users:admin"]}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+}
+
+type createAPIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Key        string     `json:"key"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
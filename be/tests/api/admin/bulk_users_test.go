@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkUsersResp struct {
+	DryRun  bool `json:"dry_run"`
+	Matched int  `json:"matched"`
+	Changed int  `json:"changed"`
+	Users   []struct {
+		ID      string `json:"id"`
+		Changes map[string]struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"changes"`
+		Skipped string `json:"skipped"`
+	} `json:"users"`
+}
+
+func bulkUsers(t *testing.T, method, token string, body any) bulkUsersResp {
+	api := tester.NewAPITester()
+	bts, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req := api.Put("/admin/users/bulk")
+	if method == http.MethodDelete {
+		req = api.Delete("/admin/users/bulk")
+	}
+	res, err := req.
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(string(bts)).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var out bulkUsersResp
+	require.NoError(t, res.JSON(&out))
+	return out
+}
+
+func TestAdminBulkSuspendUsers(t *testing.T) {
+	adminToken := signInAdmin(t)
+	id1, email1, _ := generateUser(t)
+	id2, _, _ := generateUser(t)
+	ids := []string{id1, id2}
+
+	res := bulkUsers(t, http.MethodPut, adminToken, map[string]any{"ids": ids, "status": "suspended", "dry_run": true})
+	assert.True(t, res.DryRun)
+	assert.Equal(t, 2, res.Matched)
+	assert.Equal(t, 2, res.Changed)
+	require.Len(t, res.Users, 2)
+	assert.Equal(t, "active", res.Users[0].Changes["status"].From)
+	assert.Equal(t, "suspended", res.Users[0].Changes["status"].To)
+	assert.False(t, listsUser(t, adminToken, "suspended", email1, id1))
+
+	res = bulkUsers(t, http.MethodPut, adminToken, map[string]any{"ids": ids, "status": "suspended", "name": "Bulk"})
+	assert.False(t, res.DryRun)
+	assert.Equal(t, 2, res.Changed)
+	assert.True(t, listsUser(t, adminToken, "suspended", email1, id1))
+
+	// suspending again changes nothing but the name, which is already set
+	res = bulkUsers(t, http.MethodPut, adminToken, map[string]any{"ids": ids, "status": "suspended", "name": "Bulk"})
+	assert.Equal(t, 0, res.Changed)
+	assert.Equal(t, "Nothing to change", res.Users[0].Skipped)
+}
+
+func TestAdminBulkDeleteUsers(t *testing.T) {
+	adminToken := signInAdmin(t)
+	userID, email, _ := generateUser(t)
+	unknown := "00000000-0000-4000-8000-000000000000"
+
+	res := bulkUsers(t, http.MethodDelete, adminToken, map[string]any{"ids": []string{userID, unknown}})
+	assert.Equal(t, 1, res.Matched)
+	assert.Equal(t, 1, res.Changed)
+	require.Len(t, res.Users, 2)
+	assert.Equal(t, unknown, res.Users[1].ID)
+	assert.Equal(t, "User not found", res.Users[1].Skipped)
+
+	assert.True(t, listsUser(t, adminToken, "deleted", email, userID))
+
+	// deleted users are only restored one at a time
+	for _, status := range []string{"active", "suspended"} {
+		res = bulkUsers(t, http.MethodPut, adminToken, map[string]any{"ids": []string{userID}, "status": status})
+		assert.Equal(t, 0, res.Changed)
+		require.Len(t, res.Users, 1)
+		assert.Equal(t, "Could not change a deleted user, restore it first", res.Users[0].Skipped)
+	}
+	assert.True(t, listsUser(t, adminToken, "deleted", email, userID))
+}
+
+func TestAdminBulkUsersValidation(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	tooMany := make([]string, 501)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+	}
+	idsJSON, err := json.Marshal(tooMany)
+	require.NoError(t, err)
+
+	for _, body := range []string{
+		`{"status":"suspended"}`,
+		`{"ids":["x"],"filter":{},"status":"suspended"}`,
+		`{"ids":["x"]}`,
+		`{"ids":["not-a-uuid"],"status":"suspended"}`,
+		`{"ids":["x"],"status":"deleted"}`,
+		fmt.Sprintf(`{"ids":%s,"status":"suspended"}`, idsJSON),
+	} {
+		err := api.Put("/admin/users/bulk").
+			SetHeader("Authorization", "Bearer "+adminToken).
+			SetHeader("Content-Type", "application/json").
+			BodyString(body).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Done()
+		assert.NoError(t, err, body)
+	}
+}
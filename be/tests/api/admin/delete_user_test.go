@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminDeleteUserRevokesSessions(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, _, userToken := generateUser(t)
+
+	err := api.Delete("/admin/users").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s"}`, userID)).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/admin/users").
+		SetHeader("Authorization", "Bearer "+userToken).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+}
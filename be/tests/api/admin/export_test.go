@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type adminExportResp struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	DownloadURL string `json:"download_url"`
+}
+
+func TestAdminExportUsersCSV(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, email, _ := generateUser(t)
+
+	res, err := api.Get("/admin/users/export").
+		AddQuery("q", email).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Accept", "text/csv").
+		Expect(t).
+		Status(http.StatusOK).
+		Header("Content-Type", "text/csv").
+		Send()
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(strings.NewReader(res.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "id", records[0][0])
+	assert.NotContains(t, records[0], "password")
+	assert.Equal(t, userID, records[1][0])
+	assert.Equal(t, email, records[1][1])
+}
+
+func TestAdminExportUsersNDJSON(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, email, _ := generateUser(t)
+
+	res, err := api.Get("/admin/users/export").
+		AddQuery("q", email).
+		AddQuery("format", "ndjson").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Header("Content-Type", "application/x-ndjson").
+		Send()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(res.String()), "\n")
+	require.Len(t, lines, 1)
+	var user struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &user))
+	assert.Equal(t, userID, user.ID)
+	assert.Equal(t, email, user.Email)
+}
+
+func TestAdminExportRejectsInvalidFilters(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	for _, query := range [][2]string{{"format", "xml"}, {"sort", "password"}, {"status", "unknown"}} {
+		err := api.Get("/admin/users/export").
+			AddQuery(query[0], query[1]).
+			SetHeader("Authorization", "Bearer "+adminToken).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Done()
+		assert.NoError(t, err, query[0])
+	}
+}
+
+func TestAdminExportUserLogsInBackground(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	res, err := api.Get("/admin/userlogs/export").
+		AddQuery("async", "true").
+		AddQuery("format", "ndjson").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusAccepted).
+		Send()
+	require.NoError(t, err)
+
+	var job adminExportResp
+	require.NoError(t, res.JSON(&job))
+	require.NotEmpty(t, job.ID)
+	assert.Equal(t, "pending", job.Status)
+
+	require.Eventually(t, func() bool {
+		res, err := api.Get("/admin/userlogs/export/"+job.ID).
+			SetHeader("Authorization", "Bearer "+adminToken).
+			Send()
+		if err != nil || res.StatusCode != http.StatusOK {
+			return false
+		}
+		return res.JSON(&job) == nil && job.Status == "ready"
+	}, 20*time.Second, 500*time.Millisecond)
+
+	res, err = api.Get(job.DownloadURL).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Header("Content-Type", "application/x-ndjson").
+		Send()
+	require.NoError(t, err)
+
+	// signing in as admin above left at least one entry
+	lines := strings.Split(strings.TrimSpace(res.String()), "\n")
+	require.NotEmpty(t, lines)
+	var entry struct {
+		EventType string `json:"event_type"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.NotEmpty(t, entry.EventType)
+
+	// the export is only reachable under its own kind
+	err = api.Get("/admin/users/export/"+job.ID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusNotFound).
+		Done()
+	assert.NoError(t, err)
+}
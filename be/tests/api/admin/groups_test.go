@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type groupResp struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MemberCount int    `json:"member_count"`
+}
+
+func createGroup(t *testing.T, token, name string) groupResp {
+	api := tester.NewAPITester()
+
+	res, err := api.Post("/admin/groups").
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"name":"%s","description":"Testers"}`, name)).
+		Expect(t).
+		Status(http.StatusCreated).
+		Send()
+	require.NoError(t, err)
+
+	var g groupResp
+	require.NoError(t, res.JSON(&g))
+	return g
+}
+
+func addGroupMembers(t *testing.T, token, groupID string, userIDs []string, status int) []string {
+	api := tester.NewAPITester()
+	bts, err := json.Marshal(map[string]any{"user_ids": userIDs})
+	require.NoError(t, err)
+
+	res, err := api.Post("/admin/groups/"+groupID+"/members").
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(string(bts)).
+		Expect(t).
+		Status(status).
+		Send()
+	require.NoError(t, err)
+
+	var out struct {
+		Added []string `json:"added"`
+	}
+	if status == http.StatusOK {
+		require.NoError(t, res.JSON(&out))
+	}
+	return out.Added
+}
+
+func TestAdminGroups(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	name := fmt.Sprintf("Beta %d", time.Now().UnixNano())
+
+	g := createGroup(t, adminToken, name)
+	require.NotEmpty(t, g.ID)
+	assert.Equal(t, name, g.Name)
+	assert.Equal(t, 0, g.MemberCount)
+
+	// names are unique within the organization, whatever their case
+	err := api.Post("/admin/groups").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"name":"%s"}`, name)).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	require.NoError(t, err)
+
+	res, err := api.Put("/admin/groups/"+g.ID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"name":"%s renamed","description":"Early access"}`, name)).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var updated groupResp
+	require.NoError(t, res.JSON(&updated))
+	assert.Equal(t, name+" renamed", updated.Name)
+	assert.Equal(t, "Early access", updated.Description)
+
+	id1, _, _ := generateUser(t)
+	id2, _, _ := generateUser(t)
+	added := addGroupMembers(t, adminToken, g.ID, []string{id1, id2}, http.StatusOK)
+	assert.ElementsMatch(t, []string{id1, id2}, added)
+	assert.Empty(t, addGroupMembers(t, adminToken, g.ID, []string{id1}, http.StatusOK))
+	addGroupMembers(t, adminToken, g.ID, []string{"00000000-0000-4000-8000-000000000000"}, http.StatusNotFound)
+
+	res, err = api.Get("/admin/groups/"+g.ID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+	require.NoError(t, res.JSON(&updated))
+	assert.Equal(t, 2, updated.MemberCount)
+
+	// members are listed a page at a time, like any users list
+	res, err = api.Get("/admin/groups/"+g.ID+"/members").
+		AddQuery("limit", "1").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var page adminUsersResp
+	require.NoError(t, res.JSON(&page))
+	require.Len(t, page.Users, 1)
+	assert.NotEmpty(t, page.NextCursor)
+
+	res, err = api.Get("/admin/users").
+		AddQuery("group", g.ID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+	require.NoError(t, res.JSON(&page))
+	assert.Len(t, page.Users, 2)
+
+	res, err = api.Get("/admin/users/"+id1+"/groups").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var groups struct {
+		Groups []groupResp `json:"groups"`
+	}
+	require.NoError(t, res.JSON(&groups))
+	require.Len(t, groups.Groups, 1)
+	assert.Equal(t, g.ID, groups.Groups[0].ID)
+
+	// bulk operations can target the members of a group
+	bulk := bulkUsers(t, http.MethodPut, adminToken, map[string]any{
+		"filter": map[string]any{"group": g.ID}, "status": "suspended", "dry_run": true,
+	})
+	assert.Equal(t, 2, bulk.Matched)
+
+	for i := 0; i < 2; i++ {
+		status := http.StatusNoContent
+		if i == 1 {
+			status = http.StatusNotFound
+		}
+		err = api.Delete("/admin/groups/"+g.ID+"/members/"+id1).
+			SetHeader("Authorization", "Bearer "+adminToken).
+			Expect(t).
+			Status(status).
+			Done()
+		require.NoError(t, err)
+	}
+
+	err = api.Delete("/admin/groups/"+g.ID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	require.NoError(t, err)
+
+	err = api.Get("/admin/groups/"+g.ID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusNotFound).
+		Done()
+	require.NoError(t, err)
+}
+
+func TestAdminGroupsForbiddenForUser(t *testing.T) {
+	api := tester.NewAPITester()
+	_, _, userToken := generateUser(t)
+
+	err := api.Post("/admin/groups").
+		SetHeader("Authorization", "Bearer "+userToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name":"Mine"}`).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	require.NoError(t, err)
+}
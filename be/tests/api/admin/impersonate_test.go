@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func impersonate(t *testing.T, token, userID string, status int) string {
+	api := tester.NewAPITester()
+
+	res, err := api.Post("/admin/users/"+userID+"/impersonate").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(status).
+		Send()
+	require.NoError(t, err)
+
+	var out struct {
+		UserID    string `json:"user_id"`
+		Token     string `json:"token"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	if status == http.StatusOK {
+		require.NoError(t, res.JSON(&out))
+		assert.Equal(t, userID, out.UserID)
+		assert.Positive(t, out.ExpiresIn)
+	}
+	return out.Token
+}
+
+func TestAdminImpersonateUser(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, email, _ := generateUser(t)
+
+	token := impersonate(t, adminToken, userID, http.StatusOK)
+
+	res, err := api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var me struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	}
+	require.NoError(t, res.JSON(&me))
+	assert.Equal(t, userID, me.ID)
+	assert.Equal(t, email, me.Email)
+
+	err = api.Patch("/users/me").
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name":"Seen by support"}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	require.NoError(t, err)
+
+	// destructive actions stay with the user
+	err = api.Delete("/users/me").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	require.NoError(t, err)
+
+	err = api.Put("/users/me/password").
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"current_password":"test","new_password":"changed"}`).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	require.NoError(t, err)
+
+	// an impersonation cannot be chained
+	impersonate(t, token, userID, http.StatusForbidden)
+}
+
+func TestAdminImpersonateUserValidation(t *testing.T) {
+	adminToken := signInAdmin(t)
+	userID, _, userToken := generateUser(t)
+
+	impersonate(t, adminToken, "00000000-0000-4000-8000-000000000000", http.StatusNotFound)
+	impersonate(t, userToken, userID, http.StatusForbidden)
+
+	api := tester.NewAPITester()
+	res, err := api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var me struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, res.JSON(&me))
+	impersonate(t, adminToken, me.ID, http.StatusBadRequest)
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type importJobResp struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	TotalRows     int    `json:"total_rows"`
+	ProcessedRows int    `json:"processed_rows"`
+	CreatedRows   int    `json:"created_rows"`
+	FailedRows    int    `json:"failed_rows"`
+	Errors        []struct {
+		Row     int    `json:"row"`
+		Email   string `json:"email"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func TestAdminImportUsersCSV(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	_, existing, _ := generateUser(t)
+	email := fmt.Sprintf("import+%d@example.com", time.Now().UnixNano())
+
+	csv := "email,name,role\n" +
+		email + ",Imported User,auditor\n" +
+		"not-an-email,Broken,\n" +
+		existing + ",Duplicate,\n"
+
+	res, err := api.Post("/admin/users/import").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "text/csv").
+		BodyString(csv).
+		Expect(t).
+		Status(http.StatusAccepted).
+		Send()
+	require.NoError(t, err)
+
+	var job importJobResp
+	require.NoError(t, res.JSON(&job))
+	require.NotEmpty(t, job.ID)
+	assert.Equal(t, "pending", job.Status)
+
+	require.Eventually(t, func() bool {
+		res, err := api.Get("/admin/users/import/"+job.ID).
+			SetHeader("Authorization", "Bearer "+adminToken).
+			Send()
+		if err != nil || res.StatusCode != http.StatusOK {
+			return false
+		}
+		return res.JSON(&job) == nil && job.Status == "completed"
+	}, 20*time.Second, 500*time.Millisecond)
+
+	assert.Equal(t, 3, job.TotalRows)
+	assert.Equal(t, 3, job.ProcessedRows)
+	assert.Equal(t, 1, job.CreatedRows)
+	assert.Equal(t, 2, job.FailedRows)
+	require.Len(t, job.Errors, 2)
+	assert.Equal(t, 2, job.Errors[0].Row)
+	assert.Equal(t, "not-an-email", job.Errors[0].Email)
+	assert.Equal(t, 3, job.Errors[1].Row)
+	assert.Equal(t, existing, job.Errors[1].Email)
+
+	res, err = api.Get("/admin/users").
+		AddQuery("q", email).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var users adminUsersResp
+	require.NoError(t, res.JSON(&users))
+	require.Len(t, users.Users, 1)
+	assert.Equal(t, "Imported User", users.Users[0].Name)
+	assert.Equal(t, "auditor", users.Users[0].Role)
+}
+
+func TestAdminImportUsersRejectsUnknownFormat(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	err := api.Post("/admin/users/import").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/xml").
+		BodyString("<users/>").
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+}
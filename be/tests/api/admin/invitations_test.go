@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type invitationResp struct {
+	ID     string `json:"id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	Status string `json:"status"`
+}
+
+func createInvitation(t *testing.T, token, body string, status int) invitationResp {
+	api := tester.NewAPITester()
+
+	res, err := api.Post("/admin/invitations").
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(body).
+		Expect(t).
+		Status(status).
+		Send()
+	require.NoError(t, err)
+
+	var inv invitationResp
+	if status == http.StatusCreated {
+		require.NoError(t, res.JSON(&inv))
+	}
+	return inv
+}
+
+func TestAdminInvitations(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	email := fmt.Sprintf("invited%d@example.com", time.Now().UnixNano())
+
+	inv := createInvitation(t, adminToken, fmt.Sprintf(`{"email":"%s"}`, email), http.StatusCreated)
+	require.NotEmpty(t, inv.ID)
+	assert.Equal(t, email, inv.Email)
+	assert.Equal(t, "user", inv.Role)
+	assert.Equal(t, "pending", inv.Status)
+
+	// one pending invitation per email
+	createInvitation(t, adminToken, fmt.Sprintf(`{"email":"%s","role":"auditor"}`, email), http.StatusBadRequest)
+
+	res, err := api.Get("/admin/invitations").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var list struct {
+		Invitations []invitationResp `json:"invitations"`
+	}
+	require.NoError(t, res.JSON(&list))
+	assert.Contains(t, list.Invitations, inv)
+
+	for _, status := range []int{http.StatusNoContent, http.StatusNotFound} {
+		err = api.Delete("/admin/invitations/"+inv.ID).
+			SetHeader("Authorization", "Bearer "+adminToken).
+			Expect(t).
+			Status(status).
+			Done()
+		require.NoError(t, err)
+	}
+
+	// once revoked, the email can be invited again
+	createInvitation(t, adminToken, fmt.Sprintf(`{"email":"%s","role":"auditor"}`, email), http.StatusCreated)
+}
+
+func TestAdminInvitationsValidation(t *testing.T) {
+	adminToken := signInAdmin(t)
+	_, email, userToken := generateUser(t)
+
+	createInvitation(t, adminToken, fmt.Sprintf(`{"email":"%s"}`, email), http.StatusBadRequest)
+	createInvitation(t, adminToken, `{"email":"not-an-email"}`, http.StatusBadRequest)
+	createInvitation(t, adminToken, `{"email":"role@example.com","role":"root"}`, http.StatusBadRequest)
+	createInvitation(t, adminToken, `{"email":"past@example.com","expires_at":"2000-01-01T00:00:00Z"}`, http.StatusBadRequest)
+	createInvitation(t, userToken, `{"email":"mine@example.com"}`, http.StatusForbidden)
+}
+
+func TestSignUpWithInvalidInvitation(t *testing.T) {
+	api := tester.NewAPITester()
+
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email":"bogus%d@example.com","password":"test","invitation_token":"bogus"}`, time.Now().UnixNano())).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	require.NoError(t, err)
+}
@@ -4,18 +4,20 @@ import (
 	"be/tests/tester"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAdminListUsers(t *testing.T) {
 	api := tester.NewAPITester()
-	_, _, token := generateUser(t)
+	token := signInAdmin(t)
 
 	res, err := api.Get("/admin/users").
-		AddQuery("limit", "1000").
+		AddQuery("limit", "100").
 		SetHeader("Authorization", "Bearer "+token).
 		Expect(t).
 		Status(http.StatusOK).
@@ -28,6 +30,159 @@ func TestAdminListUsers(t *testing.T) {
 	assert.GreaterOrEqual(t, len(adminResp.Users), 1)
 }
 
+func TestAdminListUsersSearchAndSort(t *testing.T) {
+	api := tester.NewAPITester()
+	token := signInAdmin(t)
+
+	marker := fmt.Sprintf("srch%d", time.Now().UnixNano())
+	for _, prefix := range []string{"b", "a"} {
+		err := api.Post("/users/signup").
+			SetHeader("Content-Type", "application/json").
+			BodyString(fmt.Sprintf(`{"email":"%s+%s@example.com","password":"test"}`, prefix, marker)).
+			Expect(t).
+			Status(http.StatusCreated).
+			Done()
+		require.NoError(t, err)
+	}
+
+	list := func(query map[string]string) adminUsersResp {
+		req := api.Get("/admin/users").SetHeader("Authorization", "Bearer "+token)
+		for k, v := range query {
+			req = req.AddQuery(k, v)
+		}
+		res, err := req.Expect(t).Status(http.StatusOK).Send()
+		require.NoError(t, err)
+
+		var page adminUsersResp
+		require.NoError(t, res.JSON(&page))
+		return page
+	}
+
+	page := list(map[string]string{"q": strings.ToUpper(marker), "sort": "email", "order": "asc", "total": "true"})
+	require.Len(t, page.Users, 2)
+	assert.Equal(t, "a+"+marker+"@example.com", page.Users[0].Email)
+	assert.Equal(t, "b+"+marker+"@example.com", page.Users[1].Email)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, 2, *page.Total)
+
+	page = list(map[string]string{"q": marker, "sort": "email", "order": "asc", "limit": "1"})
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, "a+"+marker+"@example.com", page.Users[0].Email)
+	assert.Nil(t, page.Total)
+
+	cursor := page.NextCursor
+	page = list(map[string]string{"q": marker, "sort": "email", "order": "asc", "limit": "1", "cursor": cursor})
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, "b+"+marker+"@example.com", page.Users[0].Email)
+
+	// a cursor only continues the ordering it was issued for
+	err := api.Get("/admin/users").
+		AddQuery("q", marker).
+		AddQuery("sort", "name").
+		AddQuery("cursor", cursor).
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	page = list(map[string]string{"q": marker, "created_from": tomorrow})
+	assert.Empty(t, page.Users)
+
+	err = api.Get("/admin/users").
+		AddQuery("sort", "password").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+}
+
+func TestAdminListUsersPaging(t *testing.T) {
+	api := tester.NewAPITester()
+	token := signInAdmin(t)
+
+	marker := fmt.Sprintf("page%d", time.Now().UnixNano())
+	for i := range 3 {
+		err := api.Post("/users/signup").
+			SetHeader("Content-Type", "application/json").
+			BodyString(fmt.Sprintf(`{"email":"%d+%s@example.com","password":"test"}`, i, marker)).
+			Expect(t).
+			Status(http.StatusCreated).
+			Done()
+		require.NoError(t, err)
+	}
+
+	list := func(cursor string) adminUsersResp {
+		res, err := api.Get("/admin/users").
+			AddQuery("q", marker).
+			AddQuery("order", "asc").
+			AddQuery("limit", "2").
+			AddQuery("cursor", cursor).
+			SetHeader("Authorization", "Bearer "+token).
+			Expect(t).
+			Status(http.StatusOK).
+			Send()
+		require.NoError(t, err)
+
+		var page adminUsersResp
+		require.NoError(t, res.JSON(&page))
+		return page
+	}
+
+	// users are listed by creation time, so in sign-up order
+	first := list("")
+	require.Len(t, first.Users, 2)
+	assert.Equal(t, "0+"+marker+"@example.com", first.Users[0].Email)
+	assert.NotEmpty(t, first.NextCursor)
+	assert.Empty(t, first.PrevCursor)
+
+	last := list(first.NextCursor)
+	require.Len(t, last.Users, 1)
+	assert.Equal(t, "2+"+marker+"@example.com", last.Users[0].Email)
+	assert.Empty(t, last.NextCursor)
+	assert.NotEmpty(t, last.PrevCursor)
+
+	back := list(last.PrevCursor)
+	require.Len(t, back.Users, 2)
+	assert.Equal(t, first.Users[0].ID, back.Users[0].ID)
+	assert.Equal(t, first.Users[1].ID, back.Users[1].ID)
+	assert.NotEmpty(t, back.NextCursor)
+	assert.Empty(t, back.PrevCursor)
+
+	// cursors are signed, an edited one is refused
+	payload, sig, _ := strings.Cut(first.NextCursor, ".")
+	err := api.Get("/admin/users").
+		AddQuery("order", "asc").
+		AddQuery("cursor", payload+"x."+sig).
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/admin/users").
+		AddQuery("limit", "101").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+}
+
+func TestAdminListUsersForbiddenForUser(t *testing.T) {
+	api := tester.NewAPITester()
+	_, _, token := generateUser(t)
+
+	err := api.Get("/admin/users").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	assert.NoError(t, err)
+}
+
 func generateUser(t *testing.T) (userID, email, token string) {
 	api := tester.NewAPITester()
 
@@ -46,7 +201,13 @@ func generateUser(t *testing.T) (userID, email, token string) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, signUpResp.UserID)
 
-	res, err = api.Post("/users/signin").
+	return signUpResp.UserID, email, signIn(t, email, password)
+}
+
+func signIn(t *testing.T, email, password string) string {
+	api := tester.NewAPITester()
+
+	res, err := api.Post("/users/signin").
 		SetHeader("Content-Type", "application/json").
 		BodyString(fmt.Sprintf(`{"email":"%s","password":"%s"}`, email, password)).
 		Expect(t).
@@ -59,7 +220,12 @@ func generateUser(t *testing.T) (userID, email, token string) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, tokenResp.Token)
 
-	return signUpResp.UserID, email, tokenResp.Token
+	return tokenResp.Token
+}
+
+func signInAdmin(t *testing.T) string {
+	email, password := tester.AdminCredentials()
+	return signIn(t, email, password)
 }
 
 type signUpResponse struct {
@@ -75,7 +241,11 @@ type adminUsersResp struct {
 		ID        string    `json:"id"`
 		Email     string    `json:"email"`
 		Name      string    `json:"name"`
+		Role      string    `json:"role"`
+		Status    string    `json:"status"`
 		CreatedAt time.Time `json:"created_at"`
 	} `json:"users"`
 	NextCursor string `json:"next_cursor"`
+	PrevCursor string `json:"prev_cursor"`
+	Total      *int   `json:"total"`
 }
@@ -11,7 +11,7 @@ import (
 
 func TestAdminUserLogs(t *testing.T) {
 	api := tester.NewAPITester()
-	_, _, token := generateUser(t)
+	token := signInAdmin(t)
 
 	res, err := api.Get("/admin/userlogs").
 		SetHeader("Authorization", "Bearer "+token).
@@ -0,0 +1,308 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type membershipResponse struct {
+	OrgID   string `json:"org_id"`
+	OrgName string `json:"org_name"`
+	UserID  string `json:"user_id"`
+	Role    string `json:"role"`
+}
+
+// switchOrg moves the session of token to the organization and returns the
+// access token acting there.
+func switchOrg(t *testing.T, token, orgID string) string {
+	api := tester.NewAPITester()
+
+	res, err := api.Post("/users/me/orgs/"+orgID+"/switch").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var tokens struct {
+		Token string `json:"token"`
+		OrgID string `json:"org_id"`
+	}
+	require.NoError(t, res.JSON(&tokens))
+	assert.Equal(t, orgID, tokens.OrgID)
+	return tokens.Token
+}
+
+func listUserIDs(t *testing.T, token string) []string {
+	api := tester.NewAPITester()
+
+	res, err := api.Get("/admin/users").
+		AddQuery("limit", "100").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var page adminUsersResp
+	require.NoError(t, res.JSON(&page))
+	ids := []string{}
+	for _, u := range page.Users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestOrganizations(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	res, err := api.Post("/admin/orgs").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"name":"Acme %d"}`, time.Now().UnixNano())).
+		Expect(t).
+		Status(http.StatusCreated).
+		Send()
+	require.NoError(t, err)
+
+	var org struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, res.JSON(&org))
+	require.NotEmpty(t, org.ID)
+
+	// the creator is the only member of the new organization
+	orgToken := switchOrg(t, adminToken, org.ID)
+	userID, email, userToken := generateUser(t)
+	assert.NotContains(t, listUserIDs(t, orgToken), userID)
+
+	// only the default organization onboards tenants
+	err = api.Post("/admin/orgs").
+		SetHeader("Authorization", "Bearer "+orgToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name":"Nested"}`).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	require.NoError(t, err)
+
+	// users join through an invitation they accept
+	since := time.Now()
+	createInvitation(t, orgToken, fmt.Sprintf(`{"email":"%s","role":"auditor"}`, email), http.StatusCreated)
+	assert.NotContains(t, listUserIDs(t, orgToken), userID)
+
+	mail, err := tester.LastMail(email, since)
+	require.NoError(t, err)
+	match := regexp.MustCompile(`invitations/accept\?invitation=([A-Za-z0-9_-]+)`).FindStringSubmatch(mail)
+	require.NotNil(t, match, mail)
+
+	res, err = api.Post("/users/me/invitations/accept").
+		SetHeader("Authorization", "Bearer "+userToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"token":"%s"}`, match[1])).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var inv invitationResp
+	require.NoError(t, res.JSON(&inv))
+	assert.Equal(t, "accepted", inv.Status)
+	assert.Equal(t, "auditor", inv.Role)
+	assert.Contains(t, listUserIDs(t, orgToken), userID)
+
+	// an invitation is accepted once
+	err = api.Post("/users/me/invitations/accept").
+		SetHeader("Authorization", "Bearer "+userToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"token":"%s"}`, match[1])).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	require.NoError(t, err)
+
+	// the user keeps a plain role in the default organization
+	res, err = api.Get("/users/me/orgs").
+		SetHeader("Authorization", "Bearer "+userToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var orgs struct {
+		Orgs []membershipResponse `json:"orgs"`
+	}
+	require.NoError(t, res.JSON(&orgs))
+	require.Len(t, orgs.Orgs, 2)
+	assert.Equal(t, "user", orgs.Orgs[0].Role)
+	assert.Equal(t, "auditor", orgs.Orgs[1].Role)
+
+	// suspending the user in the organization leaves the default one alone
+	err = api.Post(fmt.Sprintf("/admin/users/%s/suspend", userID)).
+		SetHeader("Authorization", "Bearer "+orgToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	require.NoError(t, err)
+
+	err = api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+userToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	require.NoError(t, err)
+
+	err = api.Post("/users/me/orgs/"+org.ID+"/switch").
+		SetHeader("Authorization", "Bearer "+userToken).
+		Expect(t).
+		Status(http.StatusNotFound).
+		Done()
+	require.NoError(t, err)
+
+	err = api.Post(fmt.Sprintf("/admin/users/%s/restore", userID)).
+		SetHeader("Authorization", "Bearer "+orgToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	require.NoError(t, err)
+
+	// acting in the organization, the auditor can read its users
+	auditorToken := switchOrg(t, userToken, org.ID)
+	assert.Contains(t, listUserIDs(t, auditorToken), userID)
+
+	err = api.Delete("/admin/members/"+userID).
+		SetHeader("Authorization", "Bearer "+orgToken).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	require.NoError(t, err)
+	assert.NotContains(t, listUserIDs(t, orgToken), userID)
+
+	err = api.Post("/users/me/orgs/"+org.ID+"/switch").
+		SetHeader("Authorization", "Bearer "+userToken).
+		Expect(t).
+		Status(http.StatusNotFound).
+		Done()
+	require.NoError(t, err)
+}
+
+// joinOrg has the admin acting with orgToken invite the user, who accepts.
+func joinOrg(t *testing.T, orgToken, userToken, email string) {
+	api := tester.NewAPITester()
+
+	since := time.Now()
+	createInvitation(t, orgToken, fmt.Sprintf(`{"email":"%s","role":"user"}`, email), http.StatusCreated)
+	mail, err := tester.LastMail(email, since)
+	require.NoError(t, err)
+	match := regexp.MustCompile(`invitations/accept\?invitation=([A-Za-z0-9_-]+)`).FindStringSubmatch(mail)
+	require.NotNil(t, match, mail)
+
+	err = api.Post("/users/me/invitations/accept").
+		SetHeader("Authorization", "Bearer "+userToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"token":"%s"}`, match[1])).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	require.NoError(t, err)
+}
+
+func TestOrganizationAdminCannotChangeSharedUsers(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	res, err := api.Post("/admin/orgs").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"name":"Acme %d"}`, time.Now().UnixNano())).
+		Expect(t).
+		Status(http.StatusCreated).
+		Send()
+	require.NoError(t, err)
+
+	var org struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, res.JSON(&org))
+	orgToken := switchOrg(t, adminToken, org.ID)
+
+	userID, email, userToken := generateUser(t)
+	joinOrg(t, orgToken, userToken, email)
+
+	// the admin of the second organization cannot point the email of a user
+	// of the default one at their own mailbox
+	attacker := fmt.Sprintf(`{"email": "attacker+%d@example.com"}`, time.Now().UnixNano())
+	patchUser(t, orgToken, userID, attacker, http.StatusForbidden)
+	patchUser(t, orgToken, userID, `{"name": "Mallory"}`, http.StatusForbidden)
+
+	err = api.Put("/admin/users").
+		SetHeader("Authorization", "Bearer "+orgToken).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("If-Match", userETag(t, orgToken, userID)).
+		BodyString(fmt.Sprintf(`{"id": "%s", "email": "attacker+%d@example.com"}`, userID, time.Now().UnixNano())).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	require.NoError(t, err)
+
+	report := bulkUsers(t, http.MethodPut, orgToken, map[string]any{"ids": []string{userID}, "name": "Mallory"})
+	assert.Equal(t, 0, report.Changed)
+	require.Len(t, report.Users, 1)
+	assert.Equal(t, "Could not rename a user of other organizations", report.Users[0].Skipped)
+
+	// nor can the default organization's admin, now the user is shared
+	patchUser(t, adminToken, userID, attacker, http.StatusForbidden)
+
+	var me struct {
+		Email string `json:"email"`
+		Name  string `json:"name"`
+	}
+	res, err = api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+userToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+	require.NoError(t, res.JSON(&me))
+	assert.Equal(t, email, me.Email)
+	assert.Empty(t, me.Name)
+
+	// per-organization data stays open to the organization's admin
+	err = api.Post(fmt.Sprintf("/admin/users/%s/suspend", userID)).
+		SetHeader("Authorization", "Bearer "+orgToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	require.NoError(t, err)
+}
+
+func TestOrganizationMembersValidation(t *testing.T) {
+	api := tester.NewAPITester()
+	_, _, userToken := generateUser(t)
+
+	err := api.Post("/users/me/invitations/accept").
+		SetHeader("Authorization", "Bearer "+userToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"token":"bogus"}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	require.NoError(t, err)
+
+	err = api.Post("/admin/orgs").
+		SetHeader("Authorization", "Bearer "+userToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name":"Mine"}`).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	require.NoError(t, err)
+}
@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type patchUserErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+func patchUser(t *testing.T, token, userID, body string, status int) adminUpdateUserResponse {
+	api := tester.NewAPITester()
+
+	res, err := api.Patch("/admin/users/"+userID).
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/merge-patch+json").
+		SetHeader("If-Match", userETag(t, token, userID)).
+		BodyString(body).
+		Expect(t).
+		Status(status).
+		Send()
+	require.NoError(t, err)
+
+	var user adminUpdateUserResponse
+	if status == http.StatusOK {
+		require.NoError(t, res.JSON(&user))
+		assert.Equal(t, fmt.Sprintf(`"%d"`, user.Version), res.Header.Get("ETag"))
+	}
+	return user
+}
+
+func TestAdminPatchUser(t *testing.T) {
+	adminToken := signInAdmin(t)
+	userID, email, _ := generateUser(t)
+
+	user := patchUser(t, adminToken, userID, `{"name": "Jane"}`, http.StatusOK)
+	assert.Equal(t, "Jane", user.Name)
+	assert.Equal(t, email, user.Email)
+
+	// absent fields are kept
+	newEmail := fmt.Sprintf("patched+%d@example.com", time.Now().UnixNano())
+	user = patchUser(t, adminToken, userID, fmt.Sprintf(`{"email": "%s"}`, newEmail), http.StatusOK)
+	assert.Equal(t, "Jane", user.Name)
+	assert.Equal(t, newEmail, user.Email)
+
+	// null clears
+	user = patchUser(t, adminToken, userID, `{"name": null}`, http.StatusOK)
+	assert.Empty(t, user.Name)
+	assert.Equal(t, newEmail, user.Email)
+
+	// nothing to change keeps the version
+	before := user.Version
+	user = patchUser(t, adminToken, userID, `{}`, http.StatusOK)
+	assert.Equal(t, before, user.Version)
+}
+
+func TestAdminPatchUserEmailVerification(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	since := time.Now()
+	userID, email, _ := generateUser(t)
+
+	verifyLink := regexp.MustCompile(`verify-email\?token=([A-Za-z0-9_-]+)`)
+	mail, err := tester.LastMail(email, since)
+	require.NoError(t, err)
+	match := verifyLink.FindStringSubmatch(mail)
+	require.NotNil(t, match, mail)
+
+	err = api.Get("/users/verify").
+		AddQuery("token", match[1]).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	require.NoError(t, err)
+
+	user := patchUser(t, adminToken, userID, `{"name": "Jane"}`, http.StatusOK)
+	assert.True(t, user.Verified)
+
+	// a new email must be verified again
+	since = time.Now()
+	newEmail := fmt.Sprintf("patched+%d@example.com", time.Now().UnixNano())
+	user = patchUser(t, adminToken, userID, fmt.Sprintf(`{"email": "%s"}`, newEmail), http.StatusOK)
+	assert.False(t, user.Verified)
+
+	mail, err = tester.LastMail(newEmail, since)
+	require.NoError(t, err)
+	assert.Regexp(t, verifyLink, mail)
+}
+
+func TestAdminPatchUserValidation(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, _, _ := generateUser(t)
+
+	res, err := api.Patch("/admin/users/"+userID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/merge-patch+json").
+		SetHeader("If-Match", userETag(t, adminToken, userID)).
+		BodyString(`{"email": null, "name": 42, "role": "admin"}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Send()
+	require.NoError(t, err)
+
+	var errRes patchUserErrorResponse
+	require.NoError(t, res.JSON(&errRes))
+	assert.Equal(t, map[string]string{
+		"email": "cannot be removed",
+		"name":  "must be a string",
+		"role":  "unknown field",
+	}, errRes.Fields)
+
+	patchUser(t, adminToken, userID, `{"email": "invalid"}`, http.StatusBadRequest)
+	patchUser(t, adminToken, userID, `["name"]`, http.StatusBadRequest)
+
+	err = api.Patch("/admin/users/"+userID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "text/plain").
+		SetHeader("If-Match", userETag(t, adminToken, userID)).
+		BodyString(`{"name": "Jane"}`).
+		Expect(t).
+		Status(http.StatusUnsupportedMediaType).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Patch("/admin/users/"+userID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/merge-patch+json").
+		BodyString(`{"name": "Jane"}`).
+		Expect(t).
+		Status(http.StatusPreconditionRequired).
+		Done()
+	assert.NoError(t, err)
+}
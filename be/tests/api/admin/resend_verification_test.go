@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminResendVerification(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, _, _ := generateUser(t)
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{
+			name:           "unverified user",
+			id:             userID,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "unknown user",
+			id:             "00000000-0000-0000-0000-000000000000",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := api.Post("/admin/users/verification").
+				SetHeader("Authorization", "Bearer "+adminToken).
+				SetHeader("Content-Type", "application/json").
+				BodyString(fmt.Sprintf(`{"id":"%s"}`, tt.id)).
+				Expect(t).
+				Status(tt.expectedStatus).
+				Done()
+			assert.NoError(t, err)
+		})
+	}
+}
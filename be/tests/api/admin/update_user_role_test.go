@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminUpdateUserRole(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	tests := []struct {
+		name           string
+		role           string
+		expectedStatus int
+	}{
+		{
+			name:           "invalid role rejected",
+			role:           "root",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "valid role",
+			role:           "auditor",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, _, _ := generateUser(t)
+
+			res, err := api.Put("/admin/users/role").
+				SetHeader("Authorization", "Bearer "+adminToken).
+				SetHeader("Content-Type", "application/json").
+				BodyString(fmt.Sprintf(`{"id":"%s","role":"%s"}`, userID, tt.role)).
+				Expect(t).
+				Status(tt.expectedStatus).
+				Send()
+			assert.NoError(t, err)
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var updateRes adminUpdateUserResponse
+			err = res.JSON(&updateRes)
+			assert.NoError(t, err)
+			assert.Equal(t, userID, updateRes.ID)
+			assert.Equal(t, tt.role, updateRes.Role)
+		})
+	}
+}
+
+func TestAuditorIsReadOnly(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, email, _ := generateUser(t)
+
+	err := api.Put("/admin/users/role").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s","role":"auditor"}`, userID)).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	// the role is a token claim, so sign in again to pick it up
+	auditorToken := signIn(t, email, "test")
+
+	err = api.Get("/admin/userlogs").
+		SetHeader("Authorization", "Bearer "+auditorToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Put("/admin/users").
+		SetHeader("Authorization", "Bearer "+auditorToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s","name":"auditor"}`, userID)).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Delete("/admin/users").
+		SetHeader("Authorization", "Bearer "+auditorToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s"}`, userID)).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	assert.NoError(t, err)
+}
@@ -8,11 +8,12 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAdminUpdateUser(t *testing.T) {
 	api := tester.NewAPITester()
-	_, _, adminToken := generateUser(t)
+	adminToken := signInAdmin(t)
 
 	type testCase struct {
 		testCase string
@@ -53,6 +54,7 @@ func TestAdminUpdateUser(t *testing.T) {
 			res, err := api.Put("/admin/users").
 				SetHeader("Authorization", "Bearer "+adminToken).
 				SetHeader("Content-Type", "application/json").
+				SetHeader("If-Match", userETag(t, adminToken, userID)).
 				BodyString(updateBody).
 				Expect(t).
 				Status(tc.status).
@@ -70,13 +72,78 @@ func TestAdminUpdateUser(t *testing.T) {
 			assert.Equal(t, adminUpdateUserRes.ID, userID)
 			assert.Equal(t, adminUpdateUserRes.Email, tc.email)
 			assert.Equal(t, adminUpdateUserRes.Name, tc.name)
+			assert.Equal(t, fmt.Sprintf(`"%d"`, adminUpdateUserRes.Version), res.Header.Get("ETag"))
 		})
 	}
 }
 
+func TestAdminUpdateUserConcurrently(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, _, _ := generateUser(t)
+	etag := userETag(t, adminToken, userID)
+
+	err := api.Put("/admin/users").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s","name":"first"}`, userID)).
+		Expect(t).
+		Status(http.StatusPreconditionRequired).
+		Done()
+	assert.NoError(t, err)
+
+	// the first admin saves
+	err = api.Put("/admin/users").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("If-Match", etag).
+		BodyString(fmt.Sprintf(`{"id":"%s","name":"first"}`, userID)).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	// the second admin edited the same version and is told what changed
+	res, err := api.Put("/admin/users").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("If-Match", etag).
+		BodyString(fmt.Sprintf(`{"id":"%s","name":"second"}`, userID)).
+		Expect(t).
+		Status(http.StatusPreconditionFailed).
+		Send()
+	require.NoError(t, err)
+
+	var current adminUpdateUserResponse
+	require.NoError(t, res.JSON(&current))
+	assert.Equal(t, "first", current.Name)
+	assert.NotEqual(t, etag, res.Header.Get("ETag"))
+	assert.Equal(t, userETag(t, adminToken, userID), res.Header.Get("ETag"))
+}
+
+// userETag reads the current ETag of a user.
+func userETag(t *testing.T, token, userID string) string {
+	api := tester.NewAPITester()
+
+	res, err := api.Get("/admin/users/"+userID).
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	etag := res.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	return etag
+}
+
 type adminUpdateUserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string         `json:"id"`
+	Email      string         `json:"email"`
+	Name       string         `json:"name"`
+	Role       string         `json:"role"`
+	Verified   bool           `json:"email_verified"`
+	Version    int            `json:"version"`
+	Attributes map[string]any `json:"attributes"`
+	CreatedAt  time.Time      `json:"created_at"`
 }
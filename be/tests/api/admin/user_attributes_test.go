@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveUserAttribute(t *testing.T, token, name, body string, status int) {
+	api := tester.NewAPITester()
+
+	err := api.Put("/admin/user-attributes/"+name).
+		SetHeader("Authorization", "Bearer "+token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(body).
+		Expect(t).
+		Status(status).
+		Done()
+	require.NoError(t, err)
+}
+
+func TestAdminUserAttributes(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+
+	// unique names keep the schema of parallel runs apart
+	suffix := time.Now().UnixNano()
+	dept := fmt.Sprintf("dept_%d", suffix)
+	level := fmt.Sprintf("level_%d", suffix)
+	saveUserAttribute(t, adminToken, dept, `{"type":"string","enum":["eng","sales"]}`, http.StatusOK)
+	saveUserAttribute(t, adminToken, level, `{"type":"number"}`, http.StatusOK)
+	saveUserAttribute(t, adminToken, "Bad-Name", `{"type":"string"}`, http.StatusBadRequest)
+	saveUserAttribute(t, adminToken, level, `{"type":"number","enum":["1"]}`, http.StatusBadRequest)
+
+	userID, _, _ := generateUser(t)
+	user := patchUser(t, adminToken, userID, fmt.Sprintf(`{"attributes":{"%s":"eng","%s":3}}`, dept, level), http.StatusOK)
+	assert.Equal(t, map[string]any{dept: "eng", level: float64(3)}, user.Attributes)
+
+	// values are checked against the schema
+	res, err := api.Patch("/admin/users/"+userID).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/merge-patch+json").
+		SetHeader("If-Match", userETag(t, adminToken, userID)).
+		BodyString(fmt.Sprintf(`{"attributes":{"%s":"hr","unknown_%d":1}}`, dept, suffix)).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Send()
+	require.NoError(t, err)
+
+	var errRes patchUserErrorResponse
+	require.NoError(t, res.JSON(&errRes))
+	assert.Contains(t, errRes.Fields, "attributes."+dept)
+	assert.Contains(t, errRes.Fields, fmt.Sprintf("attributes.unknown_%d", suffix))
+
+	// filter by attribute, typed by the schema
+	res, err = api.Get("/admin/users").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		AddQuery("attr."+level, "3").
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var page adminUsersResp
+	require.NoError(t, res.JSON(&page))
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, userID, page.Users[0].ID)
+
+	err = api.Get("/admin/users").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		AddQuery("attr."+level, "three").
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	// null removes a single attribute
+	user = patchUser(t, adminToken, userID, fmt.Sprintf(`{"attributes":{"%s":null}}`, level), http.StatusOK)
+	assert.Equal(t, map[string]any{dept: "eng"}, user.Attributes)
+
+	// deleting the definition removes it from users
+	err = api.Delete("/admin/user-attributes/"+dept).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	require.NoError(t, err)
+
+	user = patchUser(t, adminToken, userID, `{}`, http.StatusOK)
+	assert.Empty(t, user.Attributes)
+
+	err = api.Delete("/admin/user-attributes/"+level).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	require.NoError(t, err)
+}
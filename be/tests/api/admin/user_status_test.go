@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminSuspendAndRestoreUser(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, email, userToken := generateUser(t)
+
+	res, err := api.Post(fmt.Sprintf("/admin/users/%s/suspend", userID)).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var user adminUserResp
+	require.NoError(t, res.JSON(&user))
+	assert.Equal(t, "suspended", user.Status)
+	assert.NotNil(t, user.SuspendedAt)
+
+	err = api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+userToken).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email":"%s","password":"test"}`, email)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post(fmt.Sprintf("/admin/users/%s/suspend", userID)).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	res, err = api.Post(fmt.Sprintf("/admin/users/%s/restore", userID)).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+	require.NoError(t, res.JSON(&user))
+	assert.Equal(t, "active", user.Status)
+	assert.Nil(t, user.SuspendedAt)
+
+	signIn(t, email, "test")
+}
+
+func TestAdminDeleteUserIsRestorable(t *testing.T) {
+	api := tester.NewAPITester()
+	adminToken := signInAdmin(t)
+	userID, email, _ := generateUser(t)
+
+	err := api.Delete("/admin/users").
+		SetHeader("Authorization", "Bearer "+adminToken).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"id":"%s"}`, userID)).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+
+	assert.False(t, listsUser(t, adminToken, "", email, userID))
+	assert.True(t, listsUser(t, adminToken, "deleted", email, userID))
+
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email":"%s","password":"test"}`, email)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post(fmt.Sprintf("/admin/users/%s/restore", userID)).
+		SetHeader("Authorization", "Bearer "+adminToken).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	assert.True(t, listsUser(t, adminToken, "active", email, userID))
+	signIn(t, email, "test")
+}
+
+func TestAdminListUsersInvalidStatus(t *testing.T) {
+	api := tester.NewAPITester()
+	token := signInAdmin(t)
+
+	err := api.Get("/admin/users").
+		AddQuery("status", "banned").
+		SetHeader("Authorization", "Bearer "+token).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+}
+
+func listsUser(t *testing.T, token, status, email, userID string) bool {
+	api := tester.NewAPITester()
+
+	req := api.Get("/admin/users").
+		AddQuery("q", email).
+		SetHeader("Authorization", "Bearer "+token)
+	if status != "" {
+		req = req.AddQuery("status", status)
+	}
+	res, err := req.Expect(t).Status(http.StatusOK).Send()
+	require.NoError(t, err)
+
+	var users adminUsersResp
+	require.NoError(t, res.JSON(&users))
+	for _, u := range users.Users {
+		if u.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+type adminUserResp struct {
+	ID          string  `json:"id"`
+	Status      string  `json:"status"`
+	SuspendedAt *string `json:"suspended_at"`
+	DeletedAt   *string `json:"deleted_at"`
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"be/tests/tester"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSVerifiesAccessTokenAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	credentials := fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(credentials).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	require.NoError(t, err)
+
+	res, err := api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(credentials).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var tokens tokenResponse
+	require.NoError(t, res.JSON(&tokens))
+
+	res, err = api.Get("/.well-known/jwks.json").
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	require.NoError(t, res.JSON(&jwks))
+	require.NotEmpty(t, jwks.Keys)
+
+	// verify the token the way another service would, knowing only the JWKS
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokens.Token, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		for _, k := range jwks.Keys {
+			if k.Kid != kid {
+				continue
+			}
+
+			switch k.Kty {
+			case "OKP":
+				x, err := base64.RawURLEncoding.DecodeString(k.X)
+				return ed25519.PublicKey(x), err
+			case "RSA":
+				n, err := base64.RawURLEncoding.DecodeString(k.N)
+				if err != nil {
+					return nil, err
+				}
+				e, err := base64.RawURLEncoding.DecodeString(k.E)
+				if err != nil {
+					return nil, err
+				}
+				return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+			}
+		}
+		return nil, fmt.Errorf("key %q not in JWKS", kid)
+	}, jwt.WithValidMethods([]string{"EdDSA", "RS256"}))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims["user_id"])
+}
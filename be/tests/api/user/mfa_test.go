@@ -0,0 +1,152 @@
+package api
+
+import (
+	"be/pkg/totp"
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	password := "test"
+	credentials := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(credentials).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	assert.NoError(t, err)
+
+	res, err := api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(credentials).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	assert.NoError(t, err)
+
+	var signIn mfaSignInResponse
+	assert.NoError(t, res.JSON(&signIn))
+	assert.False(t, signIn.MFARequired)
+
+	res, err = api.Post("/users/mfa/totp").
+		SetHeader("Authorization", "Bearer "+signIn.Token).
+		Expect(t).
+		Status(http.StatusCreated).
+		Send()
+	assert.NoError(t, err)
+
+	var enrollment totpEnrollResponse
+	assert.NoError(t, res.JSON(&enrollment))
+	assert.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+	assert.Len(t, enrollment.RecoveryCodes, 10)
+
+	err = api.Post("/users/mfa/totp/confirm").
+		SetHeader("Authorization", "Bearer "+signIn.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"code": "000000"}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+	err = api.Post("/users/mfa/totp/confirm").
+		SetHeader("Authorization", "Bearer "+signIn.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"code": "%s"}`, code)).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+
+	// the password alone now only returns a challenge
+	mfaSignIn := func() mfaSignInResponse {
+		res, err := api.Post("/users/signin").
+			SetHeader("Content-Type", "application/json").
+			BodyString(credentials).
+			Expect(t).
+			Status(http.StatusOK).
+			Send()
+		assert.NoError(t, err)
+
+		var challenge mfaSignInResponse
+		assert.NoError(t, res.JSON(&challenge))
+		assert.True(t, challenge.MFARequired)
+		assert.NotEmpty(t, challenge.MFAToken)
+		assert.Empty(t, challenge.Token)
+		return challenge
+	}
+
+	err = api.Post("/users/signin/mfa").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"mfa_token": "%s", "code": "000000"}`, mfaSignIn().MFAToken)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	res, err = api.Post("/users/signin/mfa").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaSignIn().MFAToken, enrollment.RecoveryCodes[0])).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	assert.NoError(t, err)
+
+	var mfaTokens mfaSignInResponse
+	assert.NoError(t, res.JSON(&mfaTokens))
+	assert.NotEmpty(t, mfaTokens.Token)
+
+	// recovery codes are single use
+	err = api.Post("/users/signin/mfa").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaSignIn().MFAToken, enrollment.RecoveryCodes[0])).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	// a TOTP code is accepted once, the one confirming the enrollment is spent
+	err = api.Delete("/users/mfa/totp").
+		SetHeader("Authorization", "Bearer "+mfaTokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"code": "%s"}`, code)).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	// the code of the next period is still within the accepted drift
+	code, err = totp.Code(enrollment.Secret, time.Now().Add(totp.Period*time.Second))
+	assert.NoError(t, err)
+	err = api.Delete("/users/mfa/totp").
+		SetHeader("Authorization", "Bearer "+mfaTokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"code": "%s"}`, code)).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+}
+
+type mfaSignInResponse struct {
+	Token       string `json:"token"`
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type totpEnrollResponse struct {
+	Secret        string   `json:"secret"`
+	OTPAuthURI    string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCSignInAPI(t *testing.T) {
+	tester.StartOIDCStub()
+	api := tester.NewAPITester()
+
+	res, err := api.Get("/users/oidc/providers").
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	assert.NoError(t, err)
+
+	var providers struct {
+		Providers []string `json:"providers"`
+	}
+	assert.NoError(t, res.JSON(&providers))
+	assert.Contains(t, providers.Providers, "stub")
+
+	err = api.Get("/users/oidc/unknown/login").
+		Expect(t).
+		Status(http.StatusNotFound).
+		Done()
+	assert.NoError(t, err)
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+
+	// first login signs the user up
+	query := oidcAuthorize(t, email, true)
+	res, err = api.Get("/users/oidc/stub/callback?" + query.Encode()).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	assert.NoError(t, err)
+
+	var tokens tokenResponse
+	assert.NoError(t, res.JSON(&tokens))
+	assert.NotEmpty(t, tokens.Token)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// the state is single use
+	err = api.Get("/users/oidc/stub/callback?" + query.Encode()).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	// later logins resolve to the linked identity
+	err = api.Get("/users/oidc/stub/callback?" + oidcAuthorize(t, email, true).Encode()).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	// users created through the provider have no password
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "!"}`, email)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+}
+
+func TestOIDCSignInAPI_LinksExistingUser(t *testing.T) {
+	tester.StartOIDCStub()
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	assert.NoError(t, err)
+
+	// an unverified email at the provider is not enough to link an account
+	err = api.Get("/users/oidc/stub/callback?" + oidcAuthorize(t, email, false).Encode()).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/users/oidc/stub/callback?" + oidcAuthorize(t, email, true).Encode()).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	// the password was chosen before anyone proved owning the email
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+}
+
+// oidcAuthorize starts a login with the stub provider as email and returns the
+// query the provider redirects back to the frontend with.
+func oidcAuthorize(t *testing.T, email string, emailVerified bool) url.Values {
+	api := tester.NewAPITester()
+	res, err := api.Get("/users/oidc/stub/login").
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var login struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	require.NoError(t, res.JSON(&login))
+
+	authURL, err := url.Parse(login.AuthorizationURL)
+	require.NoError(t, err)
+	q := authURL.Query()
+	q.Set("login_hint", email)
+	q.Set("email_verified", fmt.Sprint(emailVerified))
+	authURL.RawQuery = q.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	authRes, err := client.Get(authURL.String())
+	require.NoError(t, err)
+	defer authRes.Body.Close()
+	require.Equal(t, http.StatusFound, authRes.StatusCode)
+
+	callback, err := url.Parse(authRes.Header.Get("Location"))
+	require.NoError(t, err)
+	return callback.Query()
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForgotPasswordAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "invalid email format",
+			body:           `{"email": "test"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "existing email",
+			body:           fmt.Sprintf(`{"email": "%s"}`, email),
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "unknown email is not revealed",
+			body:           fmt.Sprintf(`{"email": "unknown+%d@example.com"}`, time.Now().UnixNano()),
+			expectedStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := api.Post("/users/password/forgot").
+				SetHeader("Content-Type", "application/json").
+				BodyString(tt.body).
+				Expect(t).
+				Status(tt.expectedStatus).
+				Done()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "missing token",
+			body:           `{"password": "new"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing password",
+			body:           `{"token": "abc"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown token",
+			body:           `{"token": "abc", "password": "new"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := api.Post("/users/password/reset").
+				SetHeader("Content-Type", "application/json").
+				BodyString(tt.body).
+				Expect(t).
+				Status(tt.expectedStatus).
+				Done()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResetPasswordSuccessAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	require.NoError(t, err)
+
+	res, err := api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var signIn tokenResponse
+	require.NoError(t, res.JSON(&signIn))
+
+	since := time.Now()
+	err = api.Post("/users/password/forgot").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s"}`, email)).
+		Expect(t).
+		Status(http.StatusAccepted).
+		Done()
+	require.NoError(t, err)
+
+	mail, err := tester.LastMail(email, since)
+	require.NoError(t, err)
+	match := regexp.MustCompile(`reset-password\?token=([A-Za-z0-9_-]+)`).FindStringSubmatch(mail)
+	require.NotNil(t, match, mail)
+
+	err = api.Post("/users/password/reset").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"token": "%s", "password": "new"}`, match[1])).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	require.NoError(t, err)
+
+	// the new password replaces the old one
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "new"}`, email)).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	// a reset token is used once
+	err = api.Post("/users/password/reset").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"token": "%s", "password": "other"}`, match[1])).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	// sessions started before the reset are signed out
+	err = api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+signIn.Token).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/users/refresh").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"refresh_token": "%s"}`, signIn.RefreshToken)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+}
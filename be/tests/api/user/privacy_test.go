@@ -0,0 +1,124 @@
+package api
+
+import (
+	"archive/zip"
+	"be/tests/tester"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExportAPI(t *testing.T) {
+	api := tester.NewAPITester()
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	tokens := signUpAndSignIn(t, email, "test")
+
+	err := api.Get("/users/me/export").
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	res, err := api.Get("/users/me/export").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		Expect(t).
+		Status(http.StatusAccepted).
+		Send()
+	require.NoError(t, err)
+
+	var pending dataExportResponse
+	require.NoError(t, res.JSON(&pending))
+	assert.NotEmpty(t, pending.ID)
+	assert.Equal(t, "pending", pending.Status)
+
+	// the worker builds the archive in the background
+	var archive []byte
+	require.Eventually(t, func() bool {
+		res, err := api.Get("/users/me/export").
+			SetHeader("Authorization", "Bearer "+tokens.Token).
+			Send()
+		if err != nil || res.StatusCode != http.StatusOK {
+			return false
+		}
+		archive = res.Bytes()
+		return true
+	}, 20*time.Second, 500*time.Millisecond)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+
+	var user struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	require.Contains(t, files, "user.json")
+	require.NoError(t, json.Unmarshal(files["user.json"], &user))
+	assert.Equal(t, email, user.Email)
+	assert.Empty(t, user.Password)
+
+	var logs []struct {
+		EventType string `json:"event_type"`
+	}
+	require.Contains(t, files, "logs.json")
+	require.NoError(t, json.Unmarshal(files["logs.json"], &logs))
+}
+
+func TestDeleteAccountAPI(t *testing.T) {
+	api := tester.NewAPITester()
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	tokens := signUpAndSignIn(t, email, "test")
+
+	err := api.Delete("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"password": "wrong"}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Delete("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"password": "test"}`).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+}
+
+type dataExportResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signUpAndSignIn(t *testing.T, email, password string) tokenResponse {
+	api := tester.NewAPITester()
+	credentials := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)
+
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(credentials).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	require.NoError(t, err)
+
+	res, err := api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(credentials).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var tokens tokenResponse
+	require.NoError(t, res.JSON(&tokens))
+	return tokens
+}
+
+func TestProfileAPI(t *testing.T) {
+	api := tester.NewAPITester()
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	tokens := signUpAndSignIn(t, email, "test")
+
+	err := api.Get("/users/me").
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	res, err := api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+
+	var me profileResponse
+	require.NoError(t, res.JSON(&me))
+	assert.Equal(t, email, me.Email)
+	assert.Empty(t, me.Role)
+	etag := res.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	err = api.Patch("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"name": "Jane"}`).
+		Expect(t).
+		Status(http.StatusPreconditionRequired).
+		Done()
+	assert.NoError(t, err)
+
+	res, err = api.Patch("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("If-Match", etag).
+		BodyString(`{"name": "Jane"}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+	require.NoError(t, res.JSON(&me))
+	assert.Equal(t, "Jane", me.Name)
+	assert.Equal(t, email, me.Email)
+
+	// the name change made the first ETag stale
+	res, err = api.Patch("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("If-Match", etag).
+		BodyString(`{"name": "John"}`).
+		Expect(t).
+		Status(http.StatusPreconditionFailed).
+		Send()
+	require.NoError(t, err)
+	require.NoError(t, res.JSON(&me))
+	assert.Equal(t, "Jane", me.Name)
+	etag = res.Header.Get("ETag")
+
+	err = api.Patch("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"email": "not an email"}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	newEmail := fmt.Sprintf("new+%d@example.com", time.Now().UnixNano())
+	res, err = api.Patch("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("If-Match", etag).
+		BodyString(fmt.Sprintf(`{"email": "%s"}`, newEmail)).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+	require.NoError(t, res.JSON(&me))
+	assert.Equal(t, newEmail, me.Email)
+	assert.Equal(t, "Jane", me.Name)
+	assert.False(t, me.EmailVerified)
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	api := tester.NewAPITester()
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	tokens := signUpAndSignIn(t, email, "test")
+
+	// a second session, which the change signs out
+	res, err := api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	require.NoError(t, err)
+	var other tokenResponse
+	require.NoError(t, res.JSON(&other))
+
+	err = api.Put("/users/me/password").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"current_password": "wrong", "new_password": "changed"}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Put("/users/me/password").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		SetHeader("Content-Type", "application/json").
+		BodyString(`{"current_password": "test", "new_password": "changed"}`).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+tokens.Token).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/users/me").
+		SetHeader("Authorization", "Bearer "+other.Token).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "changed"}`, email)).
+		Expect(t).
+		Status(http.StatusOK).
+		Done()
+	assert.NoError(t, err)
+}
+
+type profileResponse struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"be/tests/tester"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshAndSignOutAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	password := "test"
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	assert.NoError(t, err)
+
+	res, err := api.Post("/users/signin").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	assert.NoError(t, err)
+
+	var signIn tokenResponse
+	err = res.JSON(&signIn)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, signIn.Token)
+	assert.NotEmpty(t, signIn.RefreshToken)
+
+	res, err = api.Post("/users/refresh").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"refresh_token": "%s"}`, signIn.RefreshToken)).
+		Expect(t).
+		Status(http.StatusOK).
+		Send()
+	assert.NoError(t, err)
+
+	var refreshed tokenResponse
+	err = res.JSON(&refreshed)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, refreshed.Token)
+	assert.NotEqual(t, signIn.RefreshToken, refreshed.RefreshToken)
+
+	// refresh tokens are single use
+	err = api.Post("/users/refresh").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"refresh_token": "%s"}`, signIn.RefreshToken)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	// a plain user is authenticated but not allowed on admin routes
+	err = api.Get("/admin/users").
+		SetHeader("Authorization", "Bearer "+refreshed.Token).
+		Expect(t).
+		Status(http.StatusForbidden).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/users/signout").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"refresh_token": "%s"}`, refreshed.RefreshToken)).
+		Expect(t).
+		Status(http.StatusNoContent).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Get("/admin/users").
+		SetHeader("Authorization", "Bearer "+refreshed.Token).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+
+	err = api.Post("/users/refresh").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"refresh_token": "%s"}`, refreshed.RefreshToken)).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		Done()
+	assert.NoError(t, err)
+}
+
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
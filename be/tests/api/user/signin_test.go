@@ -57,3 +57,70 @@ func TestSignInAPI(t *testing.T) {
 		})
 	}
 }
+
+func TestSignInUniformErrorAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "test"}`, email)).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	assert.NoError(t, err)
+
+	unknown := fmt.Sprintf("unknown+%d@example.com", time.Now().UnixNano())
+	for _, body := range []string{
+		fmt.Sprintf(`{"email": "%s", "password": "wrong"}`, email),
+		fmt.Sprintf(`{"email": "%s", "password": "test"}`, unknown),
+	} {
+		err = api.Post("/users/signin").
+			SetHeader("Content-Type", "application/json").
+			BodyString(body).
+			Expect(t).
+			Status(http.StatusUnauthorized).
+			JSON(map[string]string{"error": "Invalid credentials"}).
+			Done()
+		assert.NoError(t, err)
+	}
+}
+
+func TestSignInLockoutAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	email := fmt.Sprintf("test+%d@example.com", time.Now().UnixNano())
+	password := "test"
+	err := api.Post("/users/signup").
+		SetHeader("Content-Type", "application/json").
+		BodyString(fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)).
+		Expect(t).
+		Status(http.StatusCreated).
+		Done()
+	assert.NoError(t, err)
+
+	signIn := func(password string, expectedStatus int) {
+		err := api.Post("/users/signin").
+			SetHeader("Content-Type", "application/json").
+			BodyString(fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)).
+			Expect(t).
+			Status(expectedStatus).
+			Done()
+		assert.NoError(t, err)
+	}
+
+	for range 3 {
+		signIn("wrong", http.StatusUnauthorized)
+	}
+
+	// from the third failure on, attempts are delayed, even with the right password
+	signIn(password, http.StatusTooManyRequests)
+
+	time.Sleep(1100 * time.Millisecond)
+	signIn("wrong", http.StatusUnauthorized)
+
+	// the fifth failure locks the account
+	time.Sleep(2100 * time.Millisecond)
+	signIn("wrong", http.StatusTooManyRequests)
+	signIn(password, http.StatusTooManyRequests)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"be/tests/tester"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyEmailAPI(t *testing.T) {
+	api := tester.NewAPITester()
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "missing token",
+			token:          "",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown token",
+			token:          "abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := api.Get("/users/verify").
+				AddQuery("token", tt.token).
+				Expect(t).
+				Status(tt.expectedStatus).
+				Done()
+			assert.NoError(t, err)
+		})
+	}
+}
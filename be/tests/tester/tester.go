@@ -1,10 +1,15 @@
 package tester
 
 import (
+	"fmt"
+	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"gopkg.in/h2non/baloo.v3"
@@ -15,6 +20,45 @@ func NewAPITester() *baloo.Client {
 	return baloo.New(viper.GetString("API_URL"))
 }
 
+// AdminCredentials returns the admin account the API bootstraps on startup.
+func AdminCredentials() (email, password string) {
+	loadEnvConfig()
+	return viper.GetString("ADMIN_EMAIL"), viper.GetString("ADMIN_PASSWORD")
+}
+
+// LastMail returns the latest mail the API sent to the address since the given
+// time, as written by its file mail driver to MAIL_DIR. It waits a few seconds
+// for the mail to show up.
+func LastMail(to string, since time.Time) (string, error) {
+	loadEnvConfig()
+	dir := viper.GetString("MAIL_DIR")
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(100 * time.Millisecond) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+
+		// files are named <unix nanos>-<to>.eml
+		names := []string{}
+		for _, e := range entries {
+			sent, addr, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".eml"), "-")
+			nanos, err := strconv.ParseInt(sent, 10, 64)
+			if ok && err == nil && addr == to && nanos >= since.UnixNano() {
+				names = append(names, e.Name())
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			b, err := os.ReadFile(filepath.Join(dir, names[len(names)-1]))
+			return string(b), err
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("no mail to %s in %s", to, dir)
+		}
+	}
+}
+
 func loadEnvConfig() {
 	rootDir := rootDir()
 	file := path.Join(rootDir, "/.env")